@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Flags for audit images command
+var (
+	auditAllNamespaces bool
+	auditNamespace     string
+	auditPolicyFile    string
+	auditFailOn        string
+	auditOutputFormat  string
+	auditTimeoutStr    string
+)
+
+// Flags for audit policies command
+var (
+	policiesAllNamespaces bool
+	policiesNamespace     string
+	policiesFiles         []string
+	policiesFailOn        string
+	policiesOutputFormat  string
+	policiesTimeoutStr    string
+)
+
+// newAuditCmd creates the audit command
+func newAuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Audit workloads against organization policies",
+	}
+	cmd.AddCommand(newAuditImagesCmd())
+	cmd.AddCommand(newAuditPoliciesCmd())
+	return cmd
+}
+
+// newAuditImagesCmd creates the audit images command
+func newAuditImagesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "Check pod images against the image hygiene policy",
+		Long: `Check the images of every pod against the image hygiene policy, to catch
+specs prone to ImagePullBackOff before they fail:
+
+  INVALID_IMAGE_REFERENCE        the reference cannot be pulled as written
+  DISALLOWED_REGISTRY            registry not in allowedRegistries
+  LATEST_TAG                     :latest, explicitly or by omitting the tag
+  DIGEST_REQUIRED                tag reference in a digestNamespaces namespace
+  PULL_POLICY_MISMATCH           imagePullPolicy inconsistent with tag mutability
+  DOCKERHUB_WITHOUT_PULL_SECRET  anonymous Docker Hub pulls, subject to rate limits
+
+The policy is read from the imagePolicy key of --policy, or of
+$XDG_CONFIG_HOME/k8t/config.yaml. Without either, every check that needs no
+organization-specific list is enabled.
+
+With --fail-on, the command exits with status 1 when a finding has that
+severity or higher, for use in CI.`,
+		Args: cobra.NoArgs,
+		RunE: runAuditImages,
+	}
+
+	cmd.Flags().BoolVarP(&auditAllNamespaces, "all-namespaces", "A", false, "Audit all namespaces")
+	cmd.Flags().StringVarP(&auditNamespace, "namespace", "n", "default", "Namespace to audit")
+	cmd.Flags().StringVar(&auditPolicyFile, "policy", "", "Path to a YAML file with an imagePolicy key (default: imagePolicy from $XDG_CONFIG_HOME/k8t/config.yaml, if present)")
+	cmd.Flags().StringVar(&auditFailOn, "fail-on", "", "Exit with status 1 on findings of this severity or higher (HIGH, MEDIUM, LOW)")
+	cmd.Flags().StringVarP(&auditOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	cmd.Flags().StringVar(&auditTimeoutStr, "timeout", "1m", "Timeout for listing pods")
+
+	return cmd
+}
+
+// runAuditImages lists pods and checks their images against the policy
+func runAuditImages(cmd *cobra.Command, args []string) error {
+	timeout, err := time.ParseDuration(auditTimeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration '%s': %w", auditTimeoutStr, err)
+	}
+
+	format, err := output.ParseFormat(auditOutputFormat)
+	if err != nil {
+		return err
+	}
+
+	failOn, err := parseFailOn(auditFailOn)
+	if err != nil {
+		return err
+	}
+
+	// Load the policy before touching the cluster
+	policy, err := loadImagePolicy()
+	if err != nil {
+		return err
+	}
+
+	// Create Kubernetes client
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	// Validate cluster connectivity
+	if err := client.Validate(); err != nil {
+		return fmt.Errorf("failed to connect to Kubernetes cluster: %w", err)
+	}
+
+	// Create audit logger
+	auditLogger, err := output.NewAuditLogger(verbose)
+	if err != nil {
+		return fmt.Errorf("failed to create audit logger: %w", err)
+	}
+	defer auditLogger.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	mirrors, err := loadMirrors(ctx, client, auditLogger)
+	if err != nil {
+		return err
+	}
+
+	pods, err := listPods(ctx, client, auditLogger, auditNamespace, auditAllNamespaces)
+	if err != nil {
+		return err
+	}
+
+	az := analyzer.NewAnalyzer(client, auditLogger, timeout)
+	az.SetMirrors(mirrors)
+	findings := az.AuditImages(pods, policy)
+
+	if !quiet {
+		report := auditReport(findings, len(pods), auditNamespace, auditAllNamespaces)
+		if err := output.Format(report, format, noColor, os.Stdout); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	}
+
+	if failing := countAtOrAbove(findings, failOn); failing > 0 {
+		// Returned errors are not printed: the root command silences them
+		fmt.Fprintf(os.Stderr, "%d findings at or above %s\n", failing, failOn)
+		return fmt.Errorf("%d image policy findings at or above %s", failing, failOn)
+	}
+	return nil
+}
+
+// newAuditPoliciesCmd creates the audit policies command
+func newAuditPoliciesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policies",
+		Short: "Check pods against custom CEL policies",
+		Long: `Check every pod against policies written in CEL, read from the celPolicies
+key of one or more files. A policy reports a violation when its expression
+is true, for instance:
+
+  celPolicies:
+    - id: PROD_TAG_REFERENCE
+      description: Production images must be pinned by digest
+      expression: pod.metadata.namespace.startsWith('prod-') && !image.isDigest
+      messageExpression: "'container ' + image.containerName + ' uses ' + image.fullReference"
+      severity: HIGH
+
+Expressions see the pod (pod), its node (node), the container image being
+checked (image, or images for scope: pod) and the image pull findings of
+failing pods (findings). Violations are reported as findings of the policy's
+id, grouped by workload and container.
+
+With --fail-on, the command exits with status 1 when a finding has that
+severity or higher, for use in CI.`,
+		Args: cobra.NoArgs,
+		RunE: runAuditPolicies,
+	}
+
+	cmd.Flags().BoolVarP(&policiesAllNamespaces, "all-namespaces", "A", false, "Audit all namespaces")
+	cmd.Flags().StringVarP(&policiesNamespace, "namespace", "n", "default", "Namespace to audit")
+	cmd.Flags().StringArrayVarP(&policiesFiles, "file", "f", nil, "Path to a YAML file with a celPolicies key (repeatable)")
+	cmd.Flags().StringVar(&policiesFailOn, "fail-on", "", "Exit with status 1 on findings of this severity or higher (HIGH, MEDIUM, LOW)")
+	cmd.Flags().StringVarP(&policiesOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	cmd.Flags().StringVar(&policiesTimeoutStr, "timeout", "1m", "Timeout for listing and analyzing pods")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// runAuditPolicies lists pods and evaluates the CEL policies against them
+func runAuditPolicies(cmd *cobra.Command, args []string) error {
+	timeout, err := time.ParseDuration(policiesTimeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration '%s': %w", policiesTimeoutStr, err)
+	}
+
+	format, err := output.ParseFormat(policiesOutputFormat)
+	if err != nil {
+		return err
+	}
+
+	failOn, err := parseFailOn(policiesFailOn)
+	if err != nil {
+		return err
+	}
+
+	// Compile the policies before touching the cluster
+	policies, err := analyzer.LoadCELPolicies(policiesFiles...)
+	if err != nil {
+		return err
+	}
+
+	// Create Kubernetes client
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	// Validate cluster connectivity
+	if err := client.Validate(); err != nil {
+		return fmt.Errorf("failed to connect to Kubernetes cluster: %w", err)
+	}
+
+	// Create audit logger
+	auditLogger, err := output.NewAuditLogger(verbose)
+	if err != nil {
+		return fmt.Errorf("failed to create audit logger: %w", err)
+	}
+	defer auditLogger.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	mirrors, err := loadMirrors(ctx, client, auditLogger)
+	if err != nil {
+		return err
+	}
+
+	pods, err := listPods(ctx, client, auditLogger, policiesNamespace, policiesAllNamespaces)
+	if err != nil {
+		return err
+	}
+
+	az := analyzer.NewAnalyzer(client, auditLogger, timeout)
+	az.SetMirrors(mirrors)
+
+	// Failing pods are analyzed so policies can reason about their findings
+	var failing []corev1.Pod
+	for i := range pods {
+		if len(k8s.GetAffectedContainers(&pods[i])) > 0 {
+			failing = append(failing, pods[i])
+		}
+	}
+	var results []analyzer.PodResult
+	if len(failing) > 0 {
+		results, err = az.AnalyzePods(ctx, failing, analyzer.ScanOptions{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: timeout of %v reached; some pods were not analyzed\n", timeout)
+		}
+	}
+
+	findings, evalErrs := az.EvaluateCELPolicies(ctx, pods, results, policies)
+	for _, evalErr := range evalErrs {
+		auditLogger.LogWarning(evalErr.Error())
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", evalErr)
+		}
+	}
+
+	if !quiet {
+		report := auditReport(findings, len(pods), policiesNamespace, policiesAllNamespaces)
+		if err := output.Format(report, format, noColor, os.Stdout); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	}
+
+	if failing := countAtOrAbove(findings, failOn); failing > 0 {
+		fmt.Fprintf(os.Stderr, "%d policy violations at or above %s\n", failing, failOn)
+		return fmt.Errorf("%d policy violations at or above %s", failing, failOn)
+	}
+	return nil
+}
+
+// loadImagePolicy reads the image policy from --policy, or from the k8t config
+// file when the flag is not set. A missing config file yields the default policy.
+func loadImagePolicy() (*analyzer.ImagePolicy, error) {
+	if auditPolicyFile != "" {
+		return analyzer.LoadImagePolicy(auditPolicyFile)
+	}
+
+	configPath := configFilePath()
+	if configPath == "" {
+		return analyzer.DefaultImagePolicy(), nil
+	}
+	return analyzer.LoadImagePolicy(configPath)
+}
+
+// auditReport wraps the workload findings of an audit in a namespace report
+func auditReport(findings []types.DiagnosticFinding, pods int, namespace string, allNamespaces bool) *types.AnalysisReport {
+	targetName, targetNamespace := namespace, namespace
+	if allNamespaces {
+		targetName, targetNamespace = "all-namespaces", ""
+	}
+	report := analyzer.MergeReports(types.TargetTypeNamespace, targetName, targetNamespace, nil)
+	report.Summary.TotalPodsAnalyzed = pods
+	analyzer.AddWorkloadFindings(report, findings)
+	return report
+}
+
+// parseFailOn validates a --fail-on severity; empty means never fail on findings
+func parseFailOn(value string) (types.Severity, error) {
+	failOn := types.Severity(strings.ToUpper(value))
+	switch failOn {
+	case "", types.SeverityHigh, types.SeverityMedium, types.SeverityLow:
+		return failOn, nil
+	default:
+		return "", fmt.Errorf("invalid --fail-on '%s': must be one of: HIGH, MEDIUM, LOW", value)
+	}
+}
+
+// countAtOrAbove counts the findings with a severity at or above threshold; none without one
+func countAtOrAbove(findings []types.DiagnosticFinding, threshold types.Severity) int {
+	if threshold == "" {
+		return 0
+	}
+	rank := map[types.Severity]int{types.SeverityLow: 1, types.SeverityMedium: 2, types.SeverityHigh: 3}
+	count := 0
+	for _, finding := range findings {
+		if rank[finding.Severity] >= rank[threshold] {
+			count++
+		}
+	}
+	return count
+}
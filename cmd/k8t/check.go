@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Flags for check command
+var (
+	allNamespaces      bool
+	checkNamespace     string
+	checkConcurrency   int
+	checkTimeoutStr    string
+	checkPodTimeoutStr string
+	checkSample        bool
+	checkDetailed      bool
+	checkOutputFormat  string
+)
+
+// newCheckCmd creates the check command
+func newCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check cluster for potential issues",
+		Long: `Check the Kubernetes cluster for potential issues across all namespaces
+or a specific namespace. This command scans for common problems like
+ImagePullBackOff, CrashLoopBackOff, and other pod errors.
+
+Namespaces are listed and image pull failures are analyzed concurrently.
+Use --concurrency, --qps and --burst to tune the load put on the API server.
+
+With --sample, pods sharing an owner, image set and waiting reason are
+analyzed once through a representative pod; the other pods inherit its
+findings, which are marked as inferred in the report.
+
+When one registry accounts for most failures, a single cluster-level
+finding reports the outage and the workloads it affects; --detailed
+probes that registry from this machine.`,
+		RunE: runCheckAnalysis,
+	}
+
+	// Command-specific flags
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Check all namespaces")
+	cmd.Flags().StringVarP(&checkNamespace, "namespace", "n", "default", "Namespace to check")
+	cmd.Flags().IntVar(&checkConcurrency, "concurrency", analyzer.DefaultConcurrency, "Maximum number of namespaces or pods processed in parallel")
+	cmd.Flags().StringVar(&checkTimeoutStr, "timeout", "5m", "Timeout for the whole check")
+	cmd.Flags().StringVar(&checkPodTimeoutStr, "pod-timeout", "30s", "Timeout for analyzing a single pod")
+	cmd.Flags().BoolVar(&checkSample, "sample", false, "Analyze one representative pod per group of identical failures")
+	cmd.Flags().BoolVar(&checkDetailed, "detailed", false, "Probe registries detected as unavailable (DNS, TCP, TLS, HTTP), once per registry")
+	cmd.Flags().StringVarP(&checkOutputFormat, "output", "o", "text", "Output format (text, json, yaml); json and yaml emit the image pull analysis report")
+
+	cmd.AddCommand(newCheckImagesCmd())
+	cmd.AddCommand(newCheckDriftCmd())
+
+	return cmd
+}
+
+// podIssue records a problem detected on a pod during the check
+type podIssue struct {
+	pod       *corev1.Pod
+	issueType string
+}
+
+// runCheckAnalysis executes the cluster check
+func runCheckAnalysis(cmd *cobra.Command, args []string) error {
+	// Parse timeouts
+	timeout, err := time.ParseDuration(checkTimeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration '%s': %w", checkTimeoutStr, err)
+	}
+	podTimeout, err := time.ParseDuration(checkPodTimeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid pod timeout duration '%s': %w", checkPodTimeoutStr, err)
+	}
+	if checkConcurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d: must be at least 1", checkConcurrency)
+	}
+
+	// Parse output format
+	format, err := output.ParseFormat(checkOutputFormat)
+	if err != nil {
+		return err
+	}
+
+	// Load custom root cause rules before touching the cluster
+	rules, err := loadRules()
+	if err != nil {
+		return err
+	}
+
+	// Create Kubernetes client
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	// Validate cluster connectivity
+	if err := client.Validate(); err != nil {
+		return fmt.Errorf("failed to connect to Kubernetes cluster: %w", err)
+	}
+
+	// Create audit logger
+	auditLogger, err := output.NewAuditLogger(verbose)
+	if err != nil {
+		return fmt.Errorf("failed to create audit logger: %w", err)
+	}
+	defer auditLogger.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var namespacesToCheck []string
+
+	// Determine which namespaces to check
+	if allNamespaces {
+		namespaces, err := client.ListNamespaces(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list namespaces: %w", err)
+		}
+		namespacesToCheck = namespaces
+	} else {
+		namespacesToCheck = []string{checkNamespace}
+	}
+	sort.Strings(namespacesToCheck)
+
+	mirrors, err := loadMirrors(ctx, client, auditLogger)
+	if err != nil {
+		return err
+	}
+
+	az := analyzer.NewAnalyzer(client, auditLogger, podTimeout)
+	az.SetRules(rules)
+	az.SetMirrors(mirrors)
+	az.SetInsecureRegistries(insecureRegistries)
+
+	// List pods and policy rejections in every namespace concurrently
+	// Pods rejected at admission never exist; they only show up as
+	// FailedCreate events on their controller.
+	podsByNamespace := make([][]corev1.Pod, len(namespacesToCheck))
+	rejectionsByNamespace := make([][]types.DiagnosticFinding, len(namespacesToCheck))
+	listErrors := make([]error, len(namespacesToCheck))
+	listProgress := output.NewProgress(os.Stderr, "Listing namespaces", showProgress() && len(namespacesToCheck) > 1)
+	err = analyzer.RunConcurrently(ctx, checkConcurrency, len(namespacesToCheck), func(ctx context.Context, i int) {
+		auditLogger.LogPodList(namespacesToCheck[i])
+		podList, err := client.ListPods(ctx, namespacesToCheck[i])
+		if err != nil {
+			listErrors[i] = err
+			return
+		}
+		podsByNamespace[i] = podList.Items
+
+		rejections, err := az.AnalyzeAdmissionRejections(ctx, namespacesToCheck[i])
+		if err != nil {
+			if verbose {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to check policy rejections in namespace %s: %v\n", namespacesToCheck[i], err)
+			}
+			return
+		}
+		rejectionsByNamespace[i] = rejections
+	}, listProgress.Update)
+	listProgress.Finish()
+	if err != nil {
+		return fmt.Errorf("cluster check did not complete within %v: %w", timeout, err)
+	}
+
+	// Scan pod statuses for common issues
+	var issues []podIssue
+	var imagePullPods []corev1.Pod
+	var rejections []types.DiagnosticFinding
+	issuesByNamespace := make(map[string]int)
+
+	for i, ns := range namespacesToCheck {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Checking namespace: %s\n", ns)
+		}
+
+		if listErrors[i] != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to list pods in namespace %s: %v\n", ns, listErrors[i])
+			continue
+		}
+
+		rejections = append(rejections, rejectionsByNamespace[i]...)
+		issuesByNamespace[ns] += len(rejectionsByNamespace[i])
+
+		pods := podsByNamespace[i]
+		analyzer.SortPods(pods)
+
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Found %d pods in namespace %s\n", len(pods), ns)
+		}
+
+		for j := range pods {
+			hasIssue, issueType := checkPodIssues(&pods[j])
+			if !hasIssue {
+				continue
+			}
+			issues = append(issues, podIssue{pod: &pods[j], issueType: issueType})
+			issuesByNamespace[ns]++
+			// Every image-related waiting reason gets a root cause analysis
+			if len(k8s.GetAffectedContainers(&pods[j])) > 0 {
+				imagePullPods = append(imagePullPods, pods[j])
+			}
+		}
+	}
+
+	// Analyze image pull failures concurrently for root causes
+	var results []analyzer.PodResult
+	if len(imagePullPods) > 0 {
+		progress := output.NewProgress(os.Stderr, "Analyzing image pull failures", showProgress())
+		results, err = az.AnalyzePods(ctx, imagePullPods, analyzer.ScanOptions{
+			Concurrency: checkConcurrency,
+			Progress:    progress.Update,
+			Sample:      checkSample,
+		})
+		progress.Finish()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cluster check timeout of %v reached; some pods were not analyzed\n", timeout)
+		}
+	}
+
+	// Failures concentrated on a few nodes point at the nodes, not the images
+	var nodeFindings []types.DiagnosticFinding
+	if len(imagePullPods) > 0 {
+		var scannedPods []corev1.Pod
+		for _, pods := range podsByNamespace {
+			scannedPods = append(scannedPods, pods...)
+		}
+		nodeFindings = az.CorrelateNodes(ctx, scannedPods)
+	}
+
+	// One registry failing most pulls is an outage, not many unrelated issues
+	outages := az.AnalyzeRegistryOutages(ctx, results, checkDetailed)
+
+	findingsByPod := make(map[string][]types.DiagnosticFinding)
+	for _, result := range results {
+		if result.Err != nil {
+			if verbose {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to analyze pod %s/%s: %v\n", result.Pod.Namespace, result.Pod.Name, result.Err)
+			}
+			continue
+		}
+		if len(result.Report.Findings) > 0 {
+			findingsByPod[podKey(result.Pod)] = result.Report.Findings
+		}
+	}
+
+	totalIssues := len(issues) + len(rejections)
+
+	// Structured formats emit the merged image pull analysis report
+	if format != output.FormatTypeText {
+		report := analyzer.MergeReports(types.TargetTypeNamespace, checkTargetName(namespacesToCheck), checkTargetNamespace(), results)
+		analyzer.AddWorkloadFindings(report, rejections)
+		analyzer.AddWorkloadFindings(report, nodeFindings)
+		analyzer.AddWorkloadFindings(report, outages)
+		if !quiet {
+			if err := output.Format(report, format, noColor, os.Stdout); err != nil {
+				return fmt.Errorf("failed to format output: %w", err)
+			}
+		}
+		if totalIssues > 0 {
+			return fmt.Errorf("found %d issue(s) in cluster", totalIssues)
+		}
+		return nil
+	}
+
+	// Display issues in namespace/pod order, after any registry outage explaining them
+	if !quiet {
+		for _, finding := range outages {
+			outage := finding.RegistryOutage
+			line := fmt.Sprintf("[RegistryOutage] %s - %d pods in %d workloads", finding.Summary, outage.AffectedPods, len(outage.Workloads))
+			if diag := finding.NetworkDiagnostics; diag != nil {
+				line += " - Probe: " + probeSummary(diag)
+			}
+			fmt.Println(line)
+			for _, workload := range outage.Workloads {
+				fmt.Printf("    %s %s/%s (%d pods)\n", workload.Kind, workload.Namespace, workload.Name, workload.Pods)
+			}
+		}
+		for _, issue := range issues {
+			line := fmt.Sprintf("[%s] Pod: %s/%s - Status: %s",
+				issue.issueType, issue.pod.Namespace, issue.pod.Name, issue.pod.Status.Phase)
+			if findings, ok := findingsByPod[podKey(issue.pod)]; ok {
+				line += fmt.Sprintf(" - Root Cause: %s", formatRootCauses(findings))
+				if findings[0].Inferred {
+					line += fmt.Sprintf(" (inferred from %s)", findings[0].InferredFrom)
+				}
+			}
+			fmt.Println(line)
+		}
+		for _, finding := range rejections {
+			kind, name, _ := strings.Cut(finding.Subject, "/")
+			line := fmt.Sprintf("[PolicyRejection] %s: %s/%s - Root Cause: %s",
+				kind, finding.PodNamespace, name, finding.RootCause)
+			if finding.Policy != nil {
+				line += fmt.Sprintf(" - Blocked by: %s", finding.Policy.Controller)
+				if len(finding.Policy.Policies) > 0 {
+					line += fmt.Sprintf(" (%s)", strings.Join(finding.Policy.Policies, ", "))
+				}
+			}
+			fmt.Println(line)
+		}
+		for _, finding := range nodeFindings {
+			corr := finding.NodeCorrelation
+			line := fmt.Sprintf("[NodeCorrelation] Node: %s", corr.NodeName)
+			if corr.Zone != "" {
+				line += fmt.Sprintf(" (zone %s)", corr.Zone)
+			}
+			line += fmt.Sprintf(" - Root Cause: %s - %d pods failing to pull %s, pulled by %d pods on other nodes",
+				finding.RootCause, len(corr.FailedPods), strings.Join(corr.Images, ", "), corr.HealthyElsewhere)
+			fmt.Println(line)
+		}
+	}
+
+	// Display summary
+	if !quiet {
+		fmt.Println("\n--- Summary ---")
+		if totalIssues == 0 {
+			fmt.Println("No issues found!")
+		} else {
+			fmt.Printf("Total issues found: %d\n", totalIssues)
+			if checkSample && len(results) > 0 {
+				inferred := 0
+				for _, result := range results {
+					if result.Inferred {
+						inferred++
+					}
+				}
+				fmt.Printf("Image pull failures analyzed directly: %d, inferred by sampling: %d\n", len(results)-inferred, inferred)
+			}
+			for _, finding := range outages {
+				fmt.Printf("Registry outage: %s (%d pods)\n", finding.RegistryOutage.Registry, finding.RegistryOutage.AffectedPods)
+			}
+			if len(nodeFindings) > 0 {
+				fmt.Printf("Nodes with concentrated image pull failures: %d\n", len(nodeFindings))
+			}
+			fmt.Println("\nIssues by namespace:")
+			for _, ns := range namespacesToCheck {
+				if count := issuesByNamespace[ns]; count > 0 {
+					fmt.Printf("  %s: %d issue(s)\n", ns, count)
+				}
+			}
+		}
+	}
+
+	// Return error if issues found (cobra will handle exit code)
+	if totalIssues > 0 {
+		return fmt.Errorf("found %d issue(s) in cluster", totalIssues)
+	}
+
+	return nil
+}
+
+// probeSummary condenses registry probe results to the first failing check
+func probeSummary(diag *types.NetworkDiagnostics) string {
+	switch {
+	case diag.DNSResolution != nil && !diag.DNSResolution.Success:
+		return "DNS failed: " + diag.DNSResolution.ErrorMessage
+	case diag.TCPConnection != nil && !diag.TCPConnection.Success:
+		return "TCP failed: " + diag.TCPConnection.ErrorMessage
+	case diag.TLSCheck != nil && diag.TLSCheck.ErrorMessage != "":
+		return "TLS failed: " + diag.TLSCheck.ErrorMessage
+	case diag.HTTPCheck != nil && diag.HTTPCheck.ErrorMessage != "":
+		return "HTTP failed: " + diag.HTTPCheck.ErrorMessage
+	case diag.HTTPCheck != nil:
+		return fmt.Sprintf("HTTP %d", diag.HTTPCheck.StatusCode)
+	default:
+		return "inconclusive"
+	}
+}
+
+// formatRootCauses lists a pod's root causes, naming the containers when they differ
+func formatRootCauses(findings []types.DiagnosticFinding) string {
+	if len(findings) == 1 {
+		return string(findings[0].RootCause)
+	}
+	causes := make([]string, len(findings))
+	for i, finding := range findings {
+		causes[i] = fmt.Sprintf("%s (%s)", string(finding.RootCause), strings.Join(finding.AffectedContainers, ", "))
+	}
+	return strings.Join(causes, ", ")
+}
+
+// checkTargetName describes the scope of a check for report metadata
+func checkTargetName(namespaces []string) string {
+	if allNamespaces {
+		return "all-namespaces"
+	}
+	if len(namespaces) == 1 {
+		return namespaces[0]
+	}
+	return fmt.Sprintf("%d namespaces", len(namespaces))
+}
+
+// checkTargetNamespace returns the namespace recorded in the report ("" for all namespaces)
+func checkTargetNamespace() string {
+	if allNamespaces {
+		return ""
+	}
+	return checkNamespace
+}
+
+// podKey returns the namespace/name key of a pod
+func podKey(pod *corev1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+// checkPodIssues checks if a pod has common issues
+func checkPodIssues(pod *corev1.Pod) (bool, string) {
+	// Check both regular and init container statuses
+	statuses := append([]corev1.ContainerStatus{}, pod.Status.ContainerStatuses...)
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+
+	// Check for ImagePullBackOff or ErrImagePull
+	for _, containerStatus := range statuses {
+		if containerStatus.State.Waiting != nil {
+			reason := containerStatus.State.Waiting.Reason
+			switch reason {
+			case "ImagePullBackOff", "ErrImagePull":
+				return true, "ImagePullBackOff"
+			case "CrashLoopBackOff":
+				return true, "CrashLoopBackOff"
+			case "CreateContainerConfigError":
+				return true, "ConfigError"
+			case "InvalidImageName":
+				return true, "InvalidImage"
+			case "ErrImageNeverPull":
+				return true, "ImageNeverPull"
+			case "RegistryUnavailable":
+				return true, "RegistryUnavailable"
+			case "SignatureValidationFailed":
+				return true, "SignatureValidationFailed"
+			}
+		}
+
+		// Check if container is restarting frequently
+		if containerStatus.RestartCount > 5 {
+			return true, "HighRestarts"
+		}
+	}
+
+	// Check pod phase
+	if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodUnknown {
+		return true, "PodFailed"
+	}
+
+	return false, ""
+}
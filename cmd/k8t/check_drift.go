@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// Flags for check drift command
+var (
+	checkDriftAllNamespaces bool
+	checkDriftNamespace     string
+	checkDriftDetailed      bool
+	checkDriftOutputFormat  string
+	checkDriftTimeoutStr    string
+)
+
+// newCheckDriftCmd creates the check drift command
+func newCheckDriftCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Find replicas running different digests of the same image",
+		Long: `Compare the digest each replica runs (ContainerStatus.ImageID) across the
+pods of every workload. With mutable tags, replicas started on different
+nodes or at different times can run different images.
+
+Reported per container:
+  REPLICAS_DIFFER  replicas run different digests of the same reference
+  TAG_MOVED        the tag now resolves to a digest no replica runs (--detailed)
+  LATEST_TAG       the image uses :latest, explicitly or by default
+
+--detailed resolves each tag in its registry (anonymously, through the
+configured mirror). Each drift comes with the image pinned by digest, the
+one most replicas run, and the patch for its Deployment, StatefulSet or
+DaemonSet.
+
+Registry certificates are verified when resolving tags, since the digests
+end up in pin patches. --insecure-registry skips verification for a
+registry host, e.g. one serving a self-signed certificate.`,
+		Args: cobra.NoArgs,
+		RunE: runCheckDrift,
+	}
+
+	cmd.Flags().BoolVarP(&checkDriftAllNamespaces, "all-namespaces", "A", false, "Check all namespaces")
+	cmd.Flags().StringVarP(&checkDriftNamespace, "namespace", "n", "default", "Namespace to check")
+	cmd.Flags().BoolVar(&checkDriftDetailed, "detailed", false, "Resolve tags in their registry to detect digests that moved")
+	cmd.Flags().StringVarP(&checkDriftOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	cmd.Flags().StringVar(&checkDriftTimeoutStr, "timeout", "2m", "Timeout for the whole check")
+
+	return cmd
+}
+
+// runCheckDrift lists pods and reports image drift across the replicas of their workloads
+func runCheckDrift(cmd *cobra.Command, args []string) error {
+	timeout, err := time.ParseDuration(checkDriftTimeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration '%s': %w", checkDriftTimeoutStr, err)
+	}
+
+	format, err := output.ParseFormat(checkDriftOutputFormat)
+	if err != nil {
+		return err
+	}
+
+	// Create Kubernetes client
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	// Validate cluster connectivity
+	if err := client.Validate(); err != nil {
+		return fmt.Errorf("failed to connect to Kubernetes cluster: %w", err)
+	}
+
+	// Create audit logger
+	auditLogger, err := output.NewAuditLogger(verbose)
+	if err != nil {
+		return fmt.Errorf("failed to create audit logger: %w", err)
+	}
+	defer auditLogger.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	mirrors, err := loadMirrors(ctx, client, auditLogger)
+	if err != nil {
+		return err
+	}
+
+	pods, err := listPods(ctx, client, auditLogger, checkDriftNamespace, checkDriftAllNamespaces)
+	if err != nil {
+		return err
+	}
+
+	az := analyzer.NewAnalyzer(client, auditLogger, timeout)
+	az.SetMirrors(mirrors)
+	az.SetInsecureRegistries(insecureRegistries)
+	drifts := az.AnalyzeImageDrift(ctx, pods, checkDriftDetailed)
+
+	return output.FormatImageDrift(drifts, format, noColor, os.Stdout)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// Flags for check images command
+var (
+	checkImagesAllNamespaces bool
+	checkImagesNamespace     string
+	checkImagesCache         bool
+	checkImagesOutputFormat  string
+	checkImagesTimeoutStr    string
+)
+
+// newCheckImagesCmd creates the check images command
+func newCheckImagesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "Rate how exposed workloads are to a registry outage",
+		Long: `Rate, per workload, how exposed new pods are to a registry outage.
+
+Workloads pulling with imagePullPolicy Always need the registry for every
+new pod. With --cache, the image caches nodes report in status.images are
+read: a workload is HIGH when no schedulable node caches its images,
+MEDIUM when some do and LOW when all do. Without --cache, only the pull
+policy is rated.
+
+Nodes report at most 50 images each, so large caches are under-reported.`,
+		Args: cobra.NoArgs,
+		RunE: runCheckImages,
+	}
+
+	cmd.Flags().BoolVarP(&checkImagesAllNamespaces, "all-namespaces", "A", false, "Check all namespaces")
+	cmd.Flags().StringVarP(&checkImagesNamespace, "namespace", "n", "default", "Namespace to check")
+	cmd.Flags().BoolVar(&checkImagesCache, "cache", false, "Read node image caches (requires permission to list nodes)")
+	cmd.Flags().StringVarP(&checkImagesOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	cmd.Flags().StringVar(&checkImagesTimeoutStr, "timeout", "2m", "Timeout for the whole check")
+
+	return cmd
+}
+
+// runCheckImages lists pods and rates the registry outage exposure of their workloads
+func runCheckImages(cmd *cobra.Command, args []string) error {
+	timeout, err := time.ParseDuration(checkImagesTimeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration '%s': %w", checkImagesTimeoutStr, err)
+	}
+
+	format, err := output.ParseFormat(checkImagesOutputFormat)
+	if err != nil {
+		return err
+	}
+
+	// Create Kubernetes client
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	// Validate cluster connectivity
+	if err := client.Validate(); err != nil {
+		return fmt.Errorf("failed to connect to Kubernetes cluster: %w", err)
+	}
+
+	// Create audit logger
+	auditLogger, err := output.NewAuditLogger(verbose)
+	if err != nil {
+		return fmt.Errorf("failed to create audit logger: %w", err)
+	}
+	defer auditLogger.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pods, err := listPods(ctx, client, auditLogger, checkImagesNamespace, checkImagesAllNamespaces)
+	if err != nil {
+		return err
+	}
+
+	az := analyzer.NewAnalyzer(client, auditLogger, timeout)
+	exposures, err := az.ImageExposure(ctx, pods, checkImagesCache)
+	if err != nil {
+		return fmt.Errorf("failed to read node image caches: %w", err)
+	}
+
+	if !quiet {
+		if err := output.FormatImageExposure(exposures, checkImagesCache, format, noColor, os.Stdout); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	}
+	return nil
+}
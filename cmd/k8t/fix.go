@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	"github.com/spf13/cobra"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Flags for fix command
+var (
+	fixNamespace    string
+	fixApply        bool
+	fixPullSecret   string
+	fixSetImages    []string
+	fixOutputFormat string
+	fixTimeoutStr   string
+)
+
+// newFixCmd creates the fix command
+func newFixCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fix <pod-name>",
+		Short: "Apply remediation for an ImagePullBackOff pod",
+		Long: `Diagnose an ImagePullBackOff pod and turn the findings into patches:
+referencing a pull secret from the ServiceAccount, changing an image or
+setting imagePullPolicy on the pod's Deployment, StatefulSet or DaemonSet.
+
+Every patch is shown as a diff and validated with a server-side dry-run.
+Nothing is changed unless --apply is given. All reads and writes, including
+dry-runs, are recorded in the audit log.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runFix,
+	}
+
+	cmd.Flags().StringVarP(&fixNamespace, "namespace", "n", "default", "Kubernetes namespace")
+	cmd.Flags().BoolVar(&fixApply, "apply", false, "Apply the validated patches (default: dry-run only)")
+	cmd.Flags().StringVar(&fixPullSecret, "pull-secret", "", "Pull secret to reference for authentication failures (default: a secret with credentials for the image registry)")
+	cmd.Flags().StringArrayVar(&fixSetImages, "set-image", nil, "Replace a container image, as container=image (repeatable)")
+	cmd.Flags().StringVarP(&fixOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	cmd.Flags().StringVar(&fixTimeoutStr, "timeout", "30s", "Timeout for analysis and patching")
+
+	return cmd
+}
+
+// runFix plans, validates and optionally applies remediation for a pod
+func runFix(cmd *cobra.Command, args []string) error {
+	podName := args[0]
+
+	timeout, err := time.ParseDuration(fixTimeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration '%s': %w", fixTimeoutStr, err)
+	}
+
+	format, err := output.ParseFormat(fixOutputFormat)
+	if err != nil {
+		return err
+	}
+
+	images, err := parseSetImages(fixSetImages)
+	if err != nil {
+		return err
+	}
+
+	// Load custom root cause rules before touching the cluster
+	rules, err := loadRules()
+	if err != nil {
+		return err
+	}
+
+	// Create Kubernetes client
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	// Validate cluster connectivity
+	if err := client.Validate(); err != nil {
+		return fmt.Errorf("failed to connect to Kubernetes cluster: %w", err)
+	}
+
+	// Create audit logger
+	auditLogger, err := output.NewAuditLogger(verbose)
+	if err != nil {
+		return fmt.Errorf("failed to create audit logger: %w", err)
+	}
+	defer auditLogger.Close()
+
+	az := analyzer.NewAnalyzer(client, auditLogger, timeout)
+	az.SetRules(rules)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	mirrors, err := loadMirrors(ctx, client, auditLogger)
+	if err != nil {
+		return err
+	}
+	az.SetMirrors(mirrors)
+
+	auditLogger.LogPodGet(podName, fixNamespace)
+	pod, err := client.GetPod(ctx, fixNamespace, podName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return handleAnalysisError(analyzer.NewPodNotFoundError(fixNamespace, podName), auditLogger)
+		}
+		return handleAnalysisError(fmt.Errorf("failed to fetch pod: %w", err), auditLogger)
+	}
+
+	report, err := az.AnalyzePodObject(ctx, pod)
+	if err != nil {
+		return handleAnalysisError(err, auditLogger)
+	}
+
+	actions, err := az.PlanRemediation(ctx, pod, report.Findings, analyzer.FixOptions{
+		PullSecret: fixPullSecret,
+		Images:     images,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(actions) == 0 {
+		if !quiet {
+			fmt.Fprintf(os.Stdout, "No automatic fix available for pod %s/%s.\n", fixNamespace, podName)
+			if len(report.Findings) > 0 {
+				fmt.Fprintf(os.Stdout, "Follow the remediation steps of: k8t analyze imagepullbackoff %s -n %s\n", podName, fixNamespace)
+			}
+		}
+		return nil
+	}
+
+	results := make([]types.ActionResult, 0, len(actions))
+	failed := false
+	for _, action := range actions {
+		result := az.ExecuteAction(ctx, action, fixApply)
+		failed = failed || result.Error != ""
+		results = append(results, result)
+	}
+
+	if !quiet {
+		if err := output.FormatFixResults(results, fixApply, format, noColor, os.Stdout); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("one or more remediation actions failed")
+	}
+	return nil
+}
+
+// parseSetImages parses repeated --set-image container=image flags
+func parseSetImages(values []string) (map[string]string, error) {
+	images := make(map[string]string, len(values))
+	for _, value := range values {
+		container, image, ok := strings.Cut(value, "=")
+		if !ok || container == "" || image == "" {
+			return nil, fmt.Errorf("invalid --set-image '%s': must be container=image", value)
+		}
+		images[container] = image
+	}
+	return images, nil
+}
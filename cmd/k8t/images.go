@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// Flags for images command
+var (
+	imagesAllNamespaces bool
+	imagesNamespace     string
+	imagesOutputFormat  string
+	imagesTimeoutStr    string
+)
+
+// newImagesCmd creates the images command
+func newImagesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "List the images running in the cluster",
+		Long: `List every image reference used by pods in the selected namespaces,
+including init and ephemeral containers: registry, repository, tag or
+digest, the image ID the runtime resolved, the owning workloads, the pod
+count and the pull secrets the pods reference.
+
+Use -o csv or -o json for audits and registry migrations:
+
+  k8t images -A -o csv > images.csv`,
+		Args: cobra.NoArgs,
+		RunE: runImages,
+	}
+
+	cmd.Flags().BoolVarP(&imagesAllNamespaces, "all-namespaces", "A", false, "List images in all namespaces")
+	cmd.Flags().StringVarP(&imagesNamespace, "namespace", "n", "default", "Namespace to list images in")
+	cmd.Flags().StringVarP(&imagesOutputFormat, "output", "o", "table", "Output format (table, json, yaml, csv)")
+	cmd.Flags().StringVar(&imagesTimeoutStr, "timeout", "1m", "Timeout for listing pods")
+
+	return cmd
+}
+
+// runImages lists pods and prints the inventory of their images
+func runImages(cmd *cobra.Command, args []string) error {
+	timeout, err := time.ParseDuration(imagesTimeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration '%s': %w", imagesTimeoutStr, err)
+	}
+
+	format, err := output.ParseInventoryFormat(imagesOutputFormat)
+	if err != nil {
+		return err
+	}
+
+	// Create Kubernetes client
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	// Validate cluster connectivity
+	if err := client.Validate(); err != nil {
+		return fmt.Errorf("failed to connect to Kubernetes cluster: %w", err)
+	}
+
+	// Create audit logger
+	auditLogger, err := output.NewAuditLogger(verbose)
+	if err != nil {
+		return fmt.Errorf("failed to create audit logger: %w", err)
+	}
+	defer auditLogger.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pods, err := listPods(ctx, client, auditLogger, imagesNamespace, imagesAllNamespaces)
+	if err != nil {
+		return err
+	}
+
+	return output.FormatImageInventory(analyzer.BuildImageInventory(pods), format, os.Stdout)
+}
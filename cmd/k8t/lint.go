@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// Flags for lint command
+var (
+	lintFiles        []string
+	lintNamespace    string
+	lintProbe        bool
+	lintOffline      bool
+	lintOutputFormat string
+	lintTimeoutStr   string
+)
+
+// newLintCmd creates the lint command
+func newLintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Predict ImagePullBackOff from manifests before applying them",
+		Long: `Check the pod templates of manifests for image pull failures before they
+are applied: Pods, Deployments, StatefulSets, DaemonSets, ReplicaSets, Jobs
+and CronJobs, from files, directories or stdin (-f -), e.g. the output of
+helm template or kustomize build.
+
+  INVALID_IMAGE_NAME      the image reference cannot be parsed
+  AUTHENTICATION_FAILURE  a referenced pull secret does not exist in the target
+                          namespace, or (--probe) the registry requires
+                          credentials no pull secret of the pod or its
+                          ServiceAccount has
+  IMAGE_NOT_FOUND         (--probe) the registry does not have the tag
+
+Pull secrets and ServiceAccounts defined in the manifests count as existing;
+others are looked up in the cluster unless --offline. Registries are probed
+anonymously from this machine; k8t never reads pull secret values.
+
+A missing pull secret is MEDIUM: public images still pull without it. It is
+HIGH when --probe shows the registry requires credentials. With --offline,
+a pull secret not defined in the manifests is LOW: it was not checked.
+
+The command exits with status 1 when a HIGH finding predicts a failed deploy:
+
+  helm template my-app ./chart | k8t lint -f - -n my-app --probe`,
+		Args: cobra.NoArgs,
+		RunE: runLint,
+	}
+
+	cmd.Flags().StringArrayVarP(&lintFiles, "filename", "f", nil, "Manifest file or directory, or - for stdin (repeatable)")
+	cmd.Flags().StringVarP(&lintNamespace, "namespace", "n", "default", "Namespace of objects that do not set one")
+	cmd.Flags().BoolVar(&lintProbe, "probe", false, "Check that each image tag exists in its registry")
+	cmd.Flags().BoolVar(&lintOffline, "offline", false, "Do not contact the cluster; only pull secrets defined in the manifests are known")
+	cmd.Flags().StringVarP(&lintOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	cmd.Flags().StringVar(&lintTimeoutStr, "timeout", "1m", "Timeout for cluster lookups and registry probes")
+	_ = cmd.MarkFlagRequired("filename")
+
+	return cmd
+}
+
+// runLint reads the manifests and predicts the image pull failures of their pod templates
+func runLint(cmd *cobra.Command, args []string) error {
+	timeout, err := time.ParseDuration(lintTimeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration '%s': %w", lintTimeoutStr, err)
+	}
+
+	format, err := output.ParseFormat(lintOutputFormat)
+	if err != nil {
+		return err
+	}
+
+	if lintOffline && mirrorsConfigMap != "" {
+		return fmt.Errorf("--mirrors-configmap reads the cluster; use --mirrors with --offline")
+	}
+
+	// Read the manifests before touching the cluster
+	manifests, err := readManifests(lintFiles, lintNamespace)
+	if err != nil {
+		return err
+	}
+
+	var client *k8s.Client
+	if !lintOffline {
+		// Create Kubernetes client
+		client, err = newClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Kubernetes client: %w", err)
+		}
+
+		// Validate cluster connectivity
+		if err := client.Validate(); err != nil {
+			return fmt.Errorf("failed to connect to Kubernetes cluster: %w", err)
+		}
+	}
+
+	// Create audit logger
+	auditLogger, err := output.NewAuditLogger(verbose)
+	if err != nil {
+		return fmt.Errorf("failed to create audit logger: %w", err)
+	}
+	defer auditLogger.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	mirrors, err := loadMirrors(ctx, client, auditLogger)
+	if err != nil {
+		return err
+	}
+
+	az := analyzer.NewAnalyzer(client, auditLogger, timeout)
+	az.SetMirrors(mirrors)
+	az.SetInsecureRegistries(insecureRegistries)
+	findings := az.LintManifests(ctx, manifests, analyzer.LintOptions{
+		Probe:   lintProbe,
+		Offline: lintOffline,
+	})
+
+	if !quiet {
+		if err := output.FormatLintFindings(findings, len(manifests.Templates), format, noColor, os.Stdout); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	}
+
+	failing := 0
+	for _, finding := range findings {
+		if finding.Severity == types.SeverityHigh {
+			failing++
+		}
+	}
+	if failing > 0 {
+		return fmt.Errorf("%d findings predict image pull failures", failing)
+	}
+	return nil
+}
+
+// readManifests parses manifest files, the YAML and JSON files of directories,
+// and stdin for "-"
+func readManifests(paths []string, namespace string) (*k8s.Manifests, error) {
+	manifests := &k8s.Manifests{}
+	for _, path := range paths {
+		if path == "-" {
+			parsed, err := k8s.ParseManifests(os.Stdin, "stdin", namespace)
+			if err != nil {
+				return nil, err
+			}
+			manifests.Merge(parsed)
+			continue
+		}
+
+		files, err := manifestFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read manifest: %w", err)
+			}
+			parsed, err := k8s.ParseManifests(f, file, namespace)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			manifests.Merge(parsed)
+		}
+	}
+	return manifests, nil
+}
+
+// manifestFiles returns a file, or the .yaml, .yml and .json files of a directory
+// in name order; like kubectl apply -f, subdirectories are not read
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -2,14 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/aboigues/k8t/pkg/analyzer"
 	"github.com/aboigues/k8t/pkg/k8s"
 	"github.com/aboigues/k8t/pkg/output"
+	corev1 "k8s.io/api/core/v1"
 )
 
 // Global flags
@@ -18,6 +24,20 @@ var (
 	verbose    bool
 	quiet      bool
 	noColor    bool
+
+	// API client rate limiting
+	clientQPS   float32
+	clientBurst int
+
+	// User-defined root cause rules
+	rulesFile string
+
+	// Registry mirrors configured on the nodes
+	mirrorsFile      string
+	mirrorsConfigMap string
+
+	// Registry hosts whose certificate is not verified by manifest checks
+	insecureRegistries []string
 )
 
 func main() {
@@ -42,15 +62,109 @@ ImagePullBackOff errors in Kubernetes pods.`,
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
 	rootCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "Suppress all output except errors")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	rootCmd.PersistentFlags().Float32Var(&clientQPS, "qps", 0, "Maximum sustained Kubernetes API requests per second (default: client-go default)")
+	rootCmd.PersistentFlags().IntVar(&clientBurst, "burst", 0, "Maximum burst of Kubernetes API requests above --qps (default: client-go default)")
+	rootCmd.PersistentFlags().StringVar(&mirrorsFile, "mirrors", "", "Path to a YAML file of registry mirrors (default: mirrors from $XDG_CONFIG_HOME/k8t/config.yaml, if present)")
+	rootCmd.PersistentFlags().StringVar(&mirrorsConfigMap, "mirrors-configmap", "", "Read registry mirrors from a ConfigMap in the cluster (namespace/name)")
+	rootCmd.PersistentFlags().StringArrayVar(&insecureRegistries, "insecure-registry", nil, "Registry host (host[:port]) whose certificate is not verified when checking manifests and digests (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&rulesFile, "rules", "", "Path to a YAML file of custom root cause rules (default: rules from $XDG_CONFIG_HOME/k8t/config.yaml, if present)")
 
 	// Add subcommands
 	rootCmd.AddCommand(newVersionCmd())
 	rootCmd.AddCommand(newAnalyzeCmd())
 	rootCmd.AddCommand(newCheckCmd())
+	rootCmd.AddCommand(newFixCmd())
+	rootCmd.AddCommand(newImagesCmd())
+	rootCmd.AddCommand(newAuditCmd())
+	rootCmd.AddCommand(newLintCmd())
+	rootCmd.AddCommand(newWebhookCmd())
 
 	return rootCmd
 }
 
+// newClient creates a Kubernetes client honoring the global connection flags
+func newClient() (*k8s.Client, error) {
+	return k8s.NewClientWithOptions(kubeconfig, k8s.ClientOptions{
+		QPS:   clientQPS,
+		Burst: clientBurst,
+	})
+}
+
+// configFilePath returns the path of the k8t config file, or "" when there is none
+func configFilePath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	configPath := filepath.Join(configDir, "k8t", "config.yaml")
+	if _, err := os.Stat(configPath); errors.Is(err, fs.ErrNotExist) {
+		return ""
+	}
+	return configPath
+}
+
+// loadRules reads custom root cause rules from --rules, or from the k8t config
+// file when the flag is not set. A missing config file is not an error.
+func loadRules() (*analyzer.RuleSet, error) {
+	if rulesFile != "" {
+		return analyzer.LoadRules(rulesFile)
+	}
+
+	configPath := configFilePath()
+	if configPath == "" {
+		return nil, nil
+	}
+	return analyzer.LoadRules(configPath)
+}
+
+// mirrorsConfigMapKey is the ConfigMap key holding the mirror configuration
+const mirrorsConfigMapKey = "mirrors.yaml"
+
+// loadMirrors reads the registry mirror configuration from --mirrors-configmap,
+// --mirrors, or the k8t config file. A missing config file is not an error.
+func loadMirrors(ctx context.Context, client *k8s.Client, auditLogger *output.AuditLogger) (*analyzer.MirrorConfig, error) {
+	if mirrorsConfigMap != "" {
+		namespace, name, ok := strings.Cut(mirrorsConfigMap, "/")
+		if !ok || namespace == "" || name == "" {
+			return nil, fmt.Errorf("invalid --mirrors-configmap %q: expected namespace/name", mirrorsConfigMap)
+		}
+		auditLogger.LogConfigMapGet(name, namespace)
+		cm, err := client.GetConfigMap(ctx, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+
+		data, ok := cm.Data[mirrorsConfigMapKey]
+		if !ok && len(cm.Data) == 1 {
+			for _, value := range cm.Data {
+				data, ok = value, true
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("configmap %s has no %s key", mirrorsConfigMap, mirrorsConfigMapKey)
+		}
+		mirrors, err := analyzer.ParseMirrors([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid mirror configuration in configmap %s: %w", mirrorsConfigMap, err)
+		}
+		return mirrors, nil
+	}
+	if mirrorsFile != "" {
+		return analyzer.LoadMirrors(mirrorsFile)
+	}
+
+	configPath := configFilePath()
+	if configPath == "" {
+		return nil, nil
+	}
+	return analyzer.LoadMirrors(configPath)
+}
+
+// showProgress reports whether progress indicators should be written to stderr
+func showProgress() bool {
+	return !quiet && output.IsTerminal(os.Stderr)
+}
+
 // newAnalyzeCmd creates the analyze command
 func newAnalyzeCmd() *cobra.Command {
 	analyzeCmd := &cobra.Command{
@@ -70,6 +184,8 @@ var (
 	namespace     string
 	outputFormat  string
 	timeoutStr    string
+	detailed      bool
+	explain       bool
 )
 
 // newImagePullBackOffCmd creates the imagepullbackoff subcommand
@@ -87,6 +203,8 @@ root cause analysis with remediation steps.`,
 	cmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "Kubernetes namespace")
 	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text, json, yaml)")
 	cmd.Flags().StringVar(&timeoutStr, "timeout", "30s", "Analysis timeout duration")
+	cmd.Flags().BoolVar(&explain, "explain", false, "Show how the root cause was chosen: matched patterns, candidate scores and confidence")
+	cmd.Flags().BoolVar(&detailed, "detailed", false, "Probe the registry (DNS, TCP, TLS certificate, HTTP) for network, TLS and availability failures")
 
 	return cmd
 }
@@ -107,8 +225,14 @@ func runImagePullBackOffAnalysis(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Load custom root cause rules before touching the cluster
+	rules, err := loadRules()
+	if err != nil {
+		return err
+	}
+
 	// Create Kubernetes client
-	client, err := k8s.NewClient(kubeconfig)
+	client, err := newClient()
 	if err != nil {
 		return fmt.Errorf("failed to create Kubernetes client: %w", err)
 	}
@@ -127,9 +251,18 @@ func runImagePullBackOffAnalysis(cmd *cobra.Command, args []string) error {
 
 	// Create analyzer
 	az := analyzer.NewAnalyzer(client, auditLogger, timeout)
+	az.SetDetailed(detailed)
+	az.SetExplain(explain)
+	az.SetRules(rules)
 
 	// Run analysis
 	ctx := context.Background()
+	mirrors, err := loadMirrors(ctx, client, auditLogger)
+	if err != nil {
+		return err
+	}
+	az.SetMirrors(mirrors)
+	az.SetInsecureRegistries(insecureRegistries)
 	report, err := az.AnalyzePod(ctx, namespace, podName)
 	if err != nil {
 		return handleAnalysisError(err, auditLogger)
@@ -203,153 +336,27 @@ func handleAnalysisError(err error, logger *output.AuditLogger) error {
 	}
 }
 
-// Flags for check command
-var (
-	allNamespaces bool
-	checkNamespace string
-)
-
-// newCheckCmd creates the check command
-func newCheckCmd() *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   "check",
-		Short: "Check cluster for potential issues",
-		Long: `Check the Kubernetes cluster for potential issues across all namespaces
-or a specific namespace. This command scans for common problems like
-ImagePullBackOff, CrashLoopBackOff, and other pod errors.`,
-		RunE: runCheckAnalysis,
-	}
-
-	// Command-specific flags
-	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Check all namespaces")
-	cmd.Flags().StringVarP(&checkNamespace, "namespace", "n", "default", "Namespace to check")
-
-	return cmd
-}
-
-// runCheckAnalysis executes the cluster check
-func runCheckAnalysis(cmd *cobra.Command, args []string) error {
-	// Create Kubernetes client
-	client, err := k8s.NewClient(kubeconfig)
-	if err != nil {
-		return fmt.Errorf("failed to create Kubernetes client: %w", err)
-	}
-
-	// Validate cluster connectivity
-	if err := client.Validate(); err != nil {
-		return fmt.Errorf("failed to connect to Kubernetes cluster: %w", err)
-	}
-
-	// Create audit logger
-	auditLogger, err := output.NewAuditLogger(verbose)
-	if err != nil {
-		return fmt.Errorf("failed to create audit logger: %w", err)
-	}
-	defer auditLogger.Close()
-
-	ctx := context.Background()
-	var namespacesToCheck []string
-
-	// Determine which namespaces to check
-	if allNamespaces {
-		namespaces, err := client.ListNamespaces(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to list namespaces: %w", err)
+// listPods lists the pods of one namespace, or of all namespaces
+// Namespaces whose pods cannot be listed are reported and skipped.
+func listPods(ctx context.Context, client *k8s.Client, auditLogger *output.AuditLogger, namespace string, all bool) ([]corev1.Pod, error) {
+	namespaces := []string{namespace}
+	if all {
+		var err error
+		if namespaces, err = client.ListNamespaces(ctx); err != nil {
+			return nil, fmt.Errorf("failed to list namespaces: %w", err)
 		}
-		namespacesToCheck = namespaces
-	} else {
-		namespacesToCheck = []string{checkNamespace}
 	}
+	sort.Strings(namespaces)
 
-	// Track overall results
-	totalIssues := 0
-	issuesByNamespace := make(map[string]int)
-
-	// Check each namespace
-	for _, ns := range namespacesToCheck {
-		if verbose {
-			fmt.Fprintf(os.Stderr, "Checking namespace: %s\n", ns)
-		}
-
-		pods, err := client.ListPodsInNamespace(ctx, ns)
+	var pods []corev1.Pod
+	for _, ns := range namespaces {
+		auditLogger.LogPodList(ns)
+		podList, err := client.ListPods(ctx, ns)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: Failed to list pods in namespace %s: %v\n", ns, err)
 			continue
 		}
-
-		if verbose {
-			fmt.Fprintf(os.Stderr, "Found %d pods in namespace %s\n", len(pods), ns)
-		}
-
-		nsIssues := 0
-		for _, pod := range pods {
-			// Check pod status for common issues
-			hasIssue, issueType := checkPodIssues(pod)
-			if hasIssue {
-				if !quiet {
-					fmt.Printf("[%s] Pod: %s/%s - Status: %s\n",
-						issueType, ns, pod.Name, pod.Status.Phase)
-				}
-				nsIssues++
-				totalIssues++
-			}
-		}
-
-		if nsIssues > 0 {
-			issuesByNamespace[ns] = nsIssues
-		}
-	}
-
-	// Display summary
-	if !quiet {
-		fmt.Println("\n--- Summary ---")
-		if totalIssues == 0 {
-			fmt.Println("No issues found!")
-		} else {
-			fmt.Printf("Total issues found: %d\n", totalIssues)
-			fmt.Println("\nIssues by namespace:")
-			for ns, count := range issuesByNamespace {
-				fmt.Printf("  %s: %d issue(s)\n", ns, count)
-			}
-		}
-	}
-
-	// Return error if issues found (cobra will handle exit code)
-	if totalIssues > 0 {
-		return fmt.Errorf("found %d issue(s) in cluster", totalIssues)
+		pods = append(pods, podList.Items...)
 	}
-
-	return nil
-}
-
-// checkPodIssues checks if a pod has common issues
-func checkPodIssues(pod k8s.PodInfo) (bool, string) {
-	// Check for ImagePullBackOff or ErrImagePull
-	for _, containerStatus := range pod.ContainerStatuses {
-		if containerStatus.State.Waiting != nil {
-			reason := containerStatus.State.Waiting.Reason
-			switch reason {
-			case "ImagePullBackOff", "ErrImagePull":
-				return true, "ImagePullBackOff"
-			case "CrashLoopBackOff":
-				return true, "CrashLoopBackOff"
-			case "CreateContainerConfigError":
-				return true, "ConfigError"
-			case "InvalidImageName":
-				return true, "InvalidImage"
-			}
-		}
-
-		// Check if container is restarting frequently
-		if containerStatus.RestartCount > 5 {
-			return true, "HighRestarts"
-		}
-	}
-
-	// Check pod phase
-	if pod.Status.Phase == "Failed" || pod.Status.Phase == "Unknown" {
-		return true, "PodFailed"
-	}
-
-	return false, ""
+	return pods, nil
 }
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/webhook"
+	"github.com/spf13/cobra"
+)
+
+// Flags for webhook serve command
+var (
+	webhookAddr        string
+	webhookCertFile    string
+	webhookKeyFile     string
+	webhookMode        string
+	webhookTimeoutStr  string
+	webhookCacheTTLStr string
+	webhookProbe       bool
+)
+
+// newWebhookCmd creates the webhook command
+func newWebhookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Run k8t as an admission webhook",
+	}
+	cmd.AddCommand(newWebhookServeCmd())
+	return cmd
+}
+
+// newWebhookServeCmd creates the webhook serve command
+func newWebhookServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a validating admission webhook that catches unpullable images",
+		Long: `Serve a ValidatingAdmissionWebhook over TLS on /validate for Pods and for
+Deployments, StatefulSets, DaemonSets, ReplicaSets, Jobs and CronJobs. Each
+pod template gets the checks of k8t lint:
+
+  INVALID_IMAGE_NAME      the image reference cannot be parsed
+  AUTHENTICATION_FAILURE  a referenced pull secret does not exist, or the
+                          registry requires credentials no pull secret has
+  IMAGE_NOT_FOUND         the registry does not have the manifest
+
+With --mode warn the findings are returned as admission warnings, shown by
+kubectl; with --mode deny HIGH findings reject the request. Pods created by a
+controller are not checked again, and updates that keep the images, pull
+secrets and ServiceAccount are admitted unchecked.
+
+Pod templates passing every check are cached for --cache-ttl; templates
+with findings are checked again on each request, since a user may push the
+tag or create the secret right away. Requests not checked
+within --timeout are admitted with a warning, so keep --timeout below the
+webhook's timeoutSeconds. /healthz answers readiness probes.`,
+		Args: cobra.NoArgs,
+		RunE: runWebhookServe,
+	}
+
+	cmd.Flags().StringVar(&webhookAddr, "addr", ":8443", "Address to listen on")
+	cmd.Flags().StringVar(&webhookCertFile, "tls-cert-file", "", "Path to the TLS certificate")
+	cmd.Flags().StringVar(&webhookKeyFile, "tls-private-key-file", "", "Path to the TLS private key")
+	cmd.Flags().StringVar(&webhookMode, "mode", string(webhook.ModeWarn), "What to do with HIGH findings (warn, deny)")
+	cmd.Flags().StringVar(&webhookTimeoutStr, "timeout", webhook.DefaultTimeout.String(), "Admit requests not checked within this duration")
+	cmd.Flags().StringVar(&webhookCacheTTLStr, "cache-ttl", "5m", "How long a pod template that passed all checks is not checked again")
+	cmd.Flags().BoolVar(&webhookProbe, "probe", true, "Check that each image manifest exists in its registry")
+	_ = cmd.MarkFlagRequired("tls-cert-file")
+	_ = cmd.MarkFlagRequired("tls-private-key-file")
+
+	return cmd
+}
+
+// runWebhookServe serves admission reviews until interrupted
+func runWebhookServe(cmd *cobra.Command, args []string) error {
+	timeout, err := time.ParseDuration(webhookTimeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration '%s': %w", webhookTimeoutStr, err)
+	}
+	cacheTTL, err := time.ParseDuration(webhookCacheTTLStr)
+	if err != nil {
+		return fmt.Errorf("invalid cache TTL '%s': %w", webhookCacheTTLStr, err)
+	}
+
+	mode := webhook.Mode(webhookMode)
+	switch mode {
+	case webhook.ModeWarn, webhook.ModeDeny:
+	default:
+		return fmt.Errorf("invalid --mode '%s': must be one of: warn, deny", webhookMode)
+	}
+
+	// Create Kubernetes client
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	// Validate cluster connectivity
+	if err := client.Validate(); err != nil {
+		return fmt.Errorf("failed to connect to Kubernetes cluster: %w", err)
+	}
+
+	// Create audit logger; entries are logged but not kept by a long-running server
+	auditLogger, err := output.NewAuditLogger(verbose)
+	if err != nil {
+		return fmt.Errorf("failed to create audit logger: %w", err)
+	}
+	defer auditLogger.Close()
+	auditLogger.DiscardEntries()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mirrors, err := loadMirrors(ctx, client, auditLogger)
+	if err != nil {
+		return err
+	}
+
+	az := analyzer.NewAnalyzer(client, auditLogger, timeout)
+	az.SetMirrors(mirrors)
+	az.SetInsecureRegistries(insecureRegistries)
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate", webhook.NewHandler(az, auditLogger, webhook.Config{
+		Mode:     mode,
+		Timeout:  timeout,
+		CacheTTL: cacheTTL,
+		Probe:    webhookProbe,
+	}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	server := &http.Server{
+		Addr:              webhookAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServeTLS(webhookCertFile, webhookKeyFile)
+	}()
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Serving admission webhook on %s (mode %s)\n", webhookAddr, mode)
+	}
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("webhook server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("webhook server shutdown: %w", err)
+	}
+	return nil
+}
@@ -3,11 +3,13 @@ package analyzer
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/aboigues/k8t/pkg/k8s"
 	"github.com/aboigues/k8t/pkg/output"
 	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
 )
 
 // Analyzer coordinates diagnostic analysis for ImagePullBackOff issues
@@ -15,6 +17,18 @@ type Analyzer struct {
 	k8sClient   *k8s.Client
 	auditLogger *output.AuditLogger
 	timeout     time.Duration
+	detailed    bool // Probe the registry from this machine (DNS, TCP, TLS, HTTP)
+	explain     bool // Record the root cause decision trace in findings
+	rules       *RuleSet
+	mirrors     *MirrorConfig
+	insecure    map[string]bool // Registry hosts whose certificate is not verified when checking manifests and digests
+	runtimes    sync.Map // Node name -> containerRuntimeVersion, looked up once per node
+
+	// Node list shared by image cache, rollout and node correlation lookups
+	nodesMu     sync.Mutex
+	nodesListed bool
+	nodes       []corev1.Node
+	nodesErr    error // Denied node list, not retried
 }
 
 // NewAnalyzer creates a new analyzer instance
@@ -26,6 +40,44 @@ func NewAnalyzer(client *k8s.Client, logger *output.AuditLogger, timeout time.Du
 	}
 }
 
+// SetDetailed enables registry probes for network, TLS and availability failures
+func (a *Analyzer) SetDetailed(detailed bool) {
+	a.detailed = detailed
+}
+
+// SetExplain records the root cause decision trace in findings
+func (a *Analyzer) SetExplain(explain bool) {
+	a.explain = explain
+}
+
+// SetRules merges user-defined root cause rules with the built-in detection table
+func (a *Analyzer) SetRules(rules *RuleSet) {
+	a.rules = rules
+}
+
+// SetMirrors resolves images to the registry mirrors configured on the nodes
+func (a *Analyzer) SetMirrors(mirrors *MirrorConfig) {
+	a.mirrors = mirrors
+}
+
+// SetInsecureRegistries skips certificate verification when asking these
+// registry hosts (host[:port], as pulled from) for manifests and digests
+func (a *Analyzer) SetInsecureRegistries(hosts []string) {
+	a.insecure = make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		a.insecure[host] = true
+	}
+}
+
+// insecureRegistry reports whether the certificate of the host an image is pulled from is not verified
+func (a *Analyzer) insecureRegistry(ref types.ImageReference) bool {
+	host := ref.Registry
+	if endpoint := ref.EffectiveEndpoint(); endpoint != nil {
+		host = endpoint.Host
+	}
+	return a.insecure[host]
+}
+
 // AnalyzePod performs complete analysis on a single pod
 func (a *Analyzer) AnalyzePod(ctx context.Context, namespace, podName string) (*types.AnalysisReport, error) {
 	// Set timeout for the entire analysis operation
@@ -50,6 +102,24 @@ func (a *Analyzer) AnalyzePod(ctx context.Context, namespace, podName string) (*
 		return nil, fmt.Errorf("failed to fetch pod: %w", err)
 	}
 
+	return a.analyzePod(ctx, pod, startTime)
+}
+
+// AnalyzePodObject performs complete analysis on an already-fetched pod
+// Used by multi-pod scans that list pods up front instead of fetching each one
+func (a *Analyzer) AnalyzePodObject(ctx context.Context, pod *corev1.Pod) (*types.AnalysisReport, error) {
+	// Each pod gets its own timeout on top of any deadline carried by ctx
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	a.auditLogger.LogAnalysisStart(types.TargetTypePod, pod.Name, pod.Namespace)
+	return a.analyzePod(ctx, pod, time.Now())
+}
+
+// analyzePod runs event collection, root cause detection and remediation for a pod
+func (a *Analyzer) analyzePod(ctx context.Context, pod *corev1.Pod, startTime time.Time) (*types.AnalysisReport, error) {
+	namespace, podName := pod.Namespace, pod.Name
+
 	// Check if pod has ImagePullBackOff status
 	affectedContainers := k8s.GetAffectedContainers(pod)
 	if len(affectedContainers) == 0 {
@@ -89,51 +159,36 @@ func (a *Analyzer) AnalyzePod(ctx context.Context, namespace, podName string) (*
 	// Convert to EventSummary (with redaction)
 	eventSummaries := k8s.ConvertToEventSummary(imagePullEvents, true)
 
-	// Parse events for analysis
-	eventAnalysis := ParseEvents(eventSummaries)
-
-	// Detect root cause
-	rootCause := DetectRootCause(eventSummaries, pod, eventAnalysis)
+	// Collect evidence from events and container statuses (events may have expired)
+	evidence := CollectEvidence(eventSummaries, pod)
 
 	// Extract image references
 	imageRefs := k8s.GetContainerImages(pod)
+	a.resolveMirrors(imageRefs)
 
-	// Find the primary image reference (first affected container's image)
-	var primaryImageRef *types.ImageReference
-	if len(imageRefs) > 0 {
-		// Find the image ref for the first affected container
-		for _, imgRef := range imageRefs {
-			for _, affectedContainer := range affectedContainers {
-				if imgRef.ContainerName == affectedContainer {
-					primaryImageRef = &imgRef
-					break
-				}
-			}
-			if primaryImageRef != nil {
-				break
-			}
-		}
-		// Fall back to first image if not found
-		if primaryImageRef == nil {
-			primaryImageRef = &imageRefs[0]
-		}
-	}
+	// Pull errors are worded by the node's container runtime
+	runtime, runtimeVersion := a.nodeRuntime(ctx, pod.Spec.NodeName)
 
-	// Generate remediation steps
-	remediationSteps := GenerateRemediationSteps(rootCause, primaryImageRef)
-
-	// Build diagnostic finding
-	finding := a.buildFinding(pod, eventSummaries, rootCause, affectedContainers, imageRefs, remediationSteps, eventAnalysis)
+	// One finding per distinct root cause, grouping the containers that share it (clarification Q4)
+	groups := groupContainersByRootCause(pod, affectedContainers, imageRefs, eventSummaries, evidence, a.rules.ForRuntime(runtime), time.Now())
 
-	// Count severity
+	findings := make([]types.DiagnosticFinding, 0, len(groups))
+	rootCauseBreakdown := make(map[types.RootCause]int)
 	highCount, mediumCount, lowCount := 0, 0, 0
-	switch finding.Severity {
-	case types.SeverityHigh:
-		highCount = 1
-	case types.SeverityMedium:
-		mediumCount = 1
-	case types.SeverityLow:
-		lowCount = 1
+	for _, group := range groups {
+		finding := a.diagnoseContainerGroup(ctx, pod, group, runtime)
+		finding.ContainerRuntime = runtimeVersion
+		findings = append(findings, finding)
+
+		rootCauseBreakdown[finding.RootCause] = 1
+		switch finding.Severity {
+		case types.SeverityHigh:
+			highCount++
+		case types.SeverityMedium:
+			mediumCount++
+		case types.SeverityLow:
+			lowCount++
+		}
 	}
 
 	// Build analysis report
@@ -142,18 +197,16 @@ func (a *Analyzer) AnalyzePod(ctx context.Context, namespace, podName string) (*
 		TargetName:  podName,
 		Namespace:   namespace,
 		GeneratedAt: time.Now(),
-		Findings:    []types.DiagnosticFinding{finding},
+		Findings:    findings,
 		Summary: types.ReportSummary{
-			TotalPodsAnalyzed:   1,
-			PodsWithIssues:      1,
-			TotalContainers:     len(imageRefs),
+			TotalPodsAnalyzed:    1,
+			PodsWithIssues:       1,
+			TotalContainers:      len(imageRefs),
 			ContainersWithIssues: len(affectedContainers),
-			RootCauseBreakdown: map[types.RootCause]int{
-				rootCause: 1,
-			},
-			HighSeverityCount:   highCount,
-			MediumSeverityCount: mediumCount,
-			LowSeverityCount:    lowCount,
+			RootCauseBreakdown:   rootCauseBreakdown,
+			HighSeverityCount:    highCount,
+			MediumSeverityCount:  mediumCount,
+			LowSeverityCount:     lowCount,
 		},
 		AuditLog: []types.AuditEntry{},
 	}
@@ -168,6 +221,64 @@ func (a *Analyzer) AnalyzePod(ctx context.Context, namespace, podName string) (*
 	return report, nil
 }
 
+// diagnoseContainerGroup builds the finding for a group of containers sharing a root cause
+func (a *Analyzer) diagnoseContainerGroup(ctx context.Context, pod *corev1.Pod, group *containerGroup, runtime types.ContainerRuntime) types.DiagnosticFinding {
+	detection := group.detection
+	rootCause, supportingEvidence := detection.RootCause, detection.Evidence
+	primaryImageRef := group.primaryImage()
+
+	// Generate remediation steps
+	remediationSteps := GenerateRuntimeRemediationSteps(rootCause, primaryImageRef, runtime)
+	remediationSteps = append(mirrorRemediation(rootCause, primaryImageRef), remediationSteps...)
+
+	// Build diagnostic finding
+	finding := a.buildFinding(pod, group.events, rootCause, group.containers, group.imageRefs, remediationSteps, group.analysis, group.evidence, supportingEvidence)
+
+	finding.Confidence = detection.Confidence
+	finding.Matches = detection.Matches
+	finding.Alternatives = detection.Alternatives()
+	if a.explain {
+		finding.DecisionTrace = detection.Trace
+	}
+
+	// Name the blocking policy when the denial message identifies it
+	if rootCause == types.RootCausePolicyRejection && len(supportingEvidence) > 0 {
+		if policy := ParsePolicyRejection(supportingEvidence[0].Message); policy != nil {
+			finding.Policy = policy
+			finding.RemediationSteps = policyRejectionRemediation(policy)
+		}
+	}
+
+	// Disk exhaustion is a node problem: correlate with the node the pod is scheduled on
+	if rootCause == types.RootCauseNodeDiskPressure && pod.Spec.NodeName != "" {
+		finding.NodeDiagnostics = a.diagnoseNodeDisk(ctx, pod.Spec.NodeName)
+		finding.RemediationSteps = append(nodeDiskRemediation(finding.NodeDiagnostics), finding.RemediationSteps...)
+	}
+
+	// A bad image from a recent rollout is fixed fastest by rolling back
+	if imageChangeCauses[rootCause] {
+		if correlation := a.correlateRollout(ctx, pod, group.containers); correlation != nil {
+			finding.Rollout = correlation
+			finding.RemediationSteps = append(rolloutRemediation(pod.Namespace, correlation), finding.RemediationSteps...)
+		}
+	}
+
+	// Where a rescheduled pod would start: nodes already caching the image
+	if cacheCauses[rootCause] {
+		finding.ImageCache = a.imageCache(ctx, pod, group.containers)
+	}
+
+	// Detailed mode: check the registry endpoint when the failure is on the connection path
+	if a.detailed && primaryImageRef != nil && primaryImageRef.ParseError == "" && needsRegistryProbe(rootCause) {
+		finding.NetworkDiagnostics = probeEndpoint(ctx, primaryImageRef)
+	}
+
+	// User-defined rules take precedence over built-in severity and remediation
+	a.rules.applyTo(&finding, primaryImageRef)
+
+	return finding
+}
+
 // buildFinding creates DiagnosticFinding from analysis data
 func (a *Analyzer) buildFinding(
 	pod interface{},
@@ -177,6 +288,8 @@ func (a *Analyzer) buildFinding(
 	imageRefs []types.ImageReference,
 	remediationSteps []string,
 	analysis *EventAnalysis,
+	evidence []types.Evidence,
+	supportingEvidence []types.Evidence,
 ) types.DiagnosticFinding {
 	// Get pod metadata
 	podName, namespace := getPodMetadata(pod)
@@ -191,6 +304,9 @@ func (a *Analyzer) buildFinding(
 		if len(analysis.ErrorMessages) > 1 {
 			details += fmt.Sprintf(" (and %d more events)", len(analysis.ErrorMessages)-1)
 		}
+	} else if len(evidence) > 0 {
+		// Events expired: fall back to the message kept in the container status
+		details = fmt.Sprintf("%s (from %s)", evidence[0].Message, evidence[0].Source)
 	}
 
 	// Calculate failure duration
@@ -211,6 +327,7 @@ func (a *Analyzer) buildFinding(
 		RemediationSteps:   remediationSteps,
 		ImageReferences:    imageRefs,
 		Events:             events,
+		Evidence:           supportingEvidence,
 		IsTransient:        analysis.IsTransient,
 		FailureCount:       analysis.FailureCount,
 		FirstFailureTime:   &analysis.FirstFailureTime,
@@ -221,6 +338,16 @@ func (a *Analyzer) buildFinding(
 	return finding
 }
 
+// needsRegistryProbe reports whether registry connectivity checks help diagnose a root cause
+func needsRegistryProbe(rootCause types.RootCause) bool {
+	switch rootCause {
+	case types.RootCauseNetworkIssue, types.RootCauseTLSCertificate, types.RootCauseRegistryUnavailable:
+		return true
+	default:
+		return false
+	}
+}
+
 // getPodMetadata extracts pod name and namespace from pod object
 func getPodMetadata(pod interface{}) (string, string) {
 	// Type assertion to get pod metadata
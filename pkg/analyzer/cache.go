@@ -0,0 +1,213 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// cacheCauses are the root causes for which node image caches predict where a rescheduled pod starts
+// An invalid reference or a policy rejection fails on every node alike.
+var cacheCauses = map[types.RootCause]bool{
+	types.RootCauseImageNotFound:       true,
+	types.RootCauseAuthFailure:         true,
+	types.RootCauseNetworkIssue:        true,
+	types.RootCauseRateLimit:           true,
+	types.RootCausePermissionDenied:    true,
+	types.RootCauseManifestError:       true,
+	types.RootCauseTLSCertificate:      true,
+	types.RootCauseNodeDiskPressure:    true,
+	types.RootCauseImageNeverPull:      true,
+	types.RootCauseRegistryUnavailable: true,
+	types.RootCauseUnknown:             true,
+}
+
+// listNodes lists the cluster's nodes once per analyzer
+// Node caches, rollout correlation and node correlation all read status.images;
+// a scan reads them once rather than per pod. Only a denied list is cached: a
+// deadline hit by one pod must not disable node data for the rest of a scan.
+func (a *Analyzer) listNodes(ctx context.Context) ([]corev1.Node, error) {
+	a.nodesMu.Lock()
+	defer a.nodesMu.Unlock()
+	if a.nodesListed {
+		return a.nodes, nil
+	}
+	if a.nodesErr != nil {
+		return nil, a.nodesErr
+	}
+
+	a.auditLogger.LogNodeList()
+	nodes, err := a.k8sClient.ListNodes(ctx)
+	if err != nil {
+		a.auditLogger.LogWarning(fmt.Sprintf("Node image caches unavailable: %v", err))
+		if k8serrors.IsForbidden(err) || k8serrors.IsUnauthorized(err) {
+			a.nodesErr = err
+		}
+		return nil, err
+	}
+	a.nodes, a.nodesListed = nodes, true
+	return a.nodes, nil
+}
+
+// EffectivePullPolicy returns a container's imagePullPolicy, defaulted like the API server:
+// Always for :latest or untagged images, IfNotPresent otherwise
+func EffectivePullPolicy(container corev1.Container) corev1.PullPolicy {
+	if container.ImagePullPolicy != "" {
+		return container.ImagePullPolicy
+	}
+	if ref, err := types.ParseImageReference(container.Name, container.Image); err == nil && ref.Tag == "latest" {
+		return corev1.PullAlways
+	}
+	return corev1.PullIfNotPresent
+}
+
+// ImageCacheFor reports the nodes caching a container's image and predicts
+// where a pod using it would start without pulling
+func ImageCacheFor(container corev1.Container, nodes []corev1.Node) types.ImageCacheStatus {
+	policy := EffectivePullPolicy(container)
+	status := types.ImageCacheStatus{
+		Container:  container.Name,
+		Image:      container.Image,
+		PullPolicy: string(policy),
+		CachedOn:   k8s.NodeImageCache(nodes, container.Image),
+	}
+
+	cached := make(map[string]bool, len(status.CachedOn))
+	for _, c := range status.CachedOn {
+		cached[c.Node] = true
+	}
+	schedulable := k8s.SchedulableNodes(nodes)
+	status.SchedulableNodes = len(schedulable)
+	for _, node := range schedulable {
+		if cached[node.Name] && policy != corev1.PullAlways {
+			status.StartsOn = append(status.StartsOn, node.Name)
+		}
+	}
+	sort.Strings(status.StartsOn)
+	status.MustPull = status.SchedulableNodes - len(status.StartsOn)
+
+	switch {
+	case policy == corev1.PullAlways:
+		status.Prediction = fmt.Sprintf("imagePullPolicy Always: a rescheduled pod pulls on all %d schedulable nodes and fails wherever the pull fails", status.SchedulableNodes)
+	case policy == corev1.PullNever && status.MustPull > 0:
+		status.Prediction = fmt.Sprintf("imagePullPolicy Never: a rescheduled pod starts on %d of %d schedulable nodes and fails with ErrImageNeverPull on the other %d",
+			len(status.StartsOn), status.SchedulableNodes, status.MustPull)
+	case status.MustPull == 0:
+		status.Prediction = fmt.Sprintf("Cached on all %d schedulable nodes: a rescheduled pod starts without pulling", status.SchedulableNodes)
+	default:
+		status.Prediction = fmt.Sprintf("A rescheduled pod starts on %d of %d schedulable nodes from the cache and must pull on the other %d",
+			len(status.StartsOn), status.SchedulableNodes, status.MustPull)
+	}
+	return status
+}
+
+// imageCache reports the node caches of a pod's failing containers
+// Missing permission to list nodes leaves the finding without cache information.
+func (a *Analyzer) imageCache(ctx context.Context, pod *corev1.Pod, containers []string) []types.ImageCacheStatus {
+	nodes, err := a.listNodes(ctx)
+	if err != nil {
+		return nil
+	}
+
+	var statuses []types.ImageCacheStatus
+	for _, name := range containers {
+		if container, ok := findContainer(pod, name); ok {
+			statuses = append(statuses, ImageCacheFor(container, nodes))
+		}
+	}
+	return statuses
+}
+
+// ImageExposure rates, per workload, how exposed new pods are to a registry outage
+// With cache, the nodes' image caches are read; otherwise only the pull policy
+// is known and workloads pulling IfNotPresent or Never are left unrated.
+func (a *Analyzer) ImageExposure(ctx context.Context, pods []corev1.Pod, cache bool) ([]types.WorkloadExposure, error) {
+	var nodes []corev1.Node
+	if cache {
+		var err error
+		if nodes, err = a.listNodes(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	byWorkload := make(map[types.AffectedWorkload]*types.WorkloadExposure)
+	var order []types.AffectedWorkload
+	for i := range pods {
+		pod := &pods[i]
+		key := podWorkload(pod)
+		if exposure, ok := byWorkload[key]; ok {
+			exposure.Pods++
+			continue
+		}
+
+		exposure := &types.WorkloadExposure{Namespace: key.Namespace, Kind: key.Kind, Name: key.Name, Pods: 1}
+		for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+			status := types.ImageCacheStatus{
+				Container:  container.Name,
+				Image:      container.Image,
+				PullPolicy: string(EffectivePullPolicy(container)),
+			}
+			if cache {
+				status = ImageCacheFor(container, nodes)
+			}
+			exposure.Images = append(exposure.Images, status)
+		}
+		rateExposure(exposure, cache)
+		byWorkload[key] = exposure
+		order = append(order, key)
+	}
+
+	exposures := make([]types.WorkloadExposure, 0, len(order))
+	for _, key := range order {
+		exposures = append(exposures, *byWorkload[key])
+	}
+	sort.SliceStable(exposures, func(i, j int) bool {
+		a, b := exposures[i], exposures[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return exposures, nil
+}
+
+// rateExposure sets a workload's exposure from its most exposed image
+func rateExposure(exposure *types.WorkloadExposure, cache bool) {
+	rank := map[types.Severity]int{"": 0, types.SeverityLow: 1, types.SeverityMedium: 2, types.SeverityHigh: 3}
+	for _, image := range exposure.Images {
+		severity, reason := imageExposure(image, cache)
+		if rank[severity] > rank[exposure.Exposure] || exposure.Reason == "" {
+			exposure.Exposure = severity
+			exposure.Reason = fmt.Sprintf("%s: %s", image.Container, reason)
+		}
+	}
+}
+
+// imageExposure rates one image: HIGH when new pods need the registry (or
+// cannot start) on most nodes, MEDIUM when some nodes cache it, LOW when all do
+func imageExposure(image types.ImageCacheStatus, cache bool) (types.Severity, string) {
+	switch {
+	case image.PullPolicy == string(corev1.PullAlways):
+		return types.SeverityHigh, "imagePullPolicy Always, every new pod pulls"
+	case !cache:
+		return "", fmt.Sprintf("imagePullPolicy %s, node caches not read (use --cache)", image.PullPolicy)
+	case image.SchedulableNodes == 0:
+		return types.SeverityHigh, "no schedulable node"
+	case len(image.StartsOn) == 0:
+		return types.SeverityHigh, "cached on no schedulable node"
+	case image.MustPull == 0:
+		return types.SeverityLow, fmt.Sprintf("cached on all %d schedulable nodes", image.SchedulableNodes)
+	case image.PullPolicy == string(corev1.PullNever):
+		return types.SeverityHigh, fmt.Sprintf("imagePullPolicy Never, missing on %d of %d schedulable nodes", image.MustPull, image.SchedulableNodes)
+	default:
+		return types.SeverityMedium, fmt.Sprintf("cached on %d of %d schedulable nodes", len(image.StartsOn), image.SchedulableNodes)
+	}
+}
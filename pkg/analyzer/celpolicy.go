@@ -0,0 +1,421 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// celCostLimit bounds the evaluation of one policy against one pod or image
+const celCostLimit = 1000000
+
+// Scopes a CEL policy is evaluated in
+const (
+	CELScopeImage = "image" // Once per container image (default)
+	CELScopePod   = "pod"   // Once per pod
+)
+
+// CELPolicySet holds policies written in CEL and checked by k8t audit policies
+// Expressions see these variables:
+//
+//	pod       the pod, as in the API (pod.metadata.namespace, pod.spec.containers)
+//	image     the container image being checked (image scope), see imageVariable
+//	images    every image of the pod, including init containers
+//	node      the pod's node, as in the API; empty when unscheduled or not readable
+//	findings  the pod's image pull findings: rootCause, severity, summary, containers
+//
+// An expression evaluating to true is a violation. Policy IDs are root causes
+// of this set only: Policy resolves their description and severity.
+type CELPolicySet struct {
+	Policies []CELPolicy `yaml:"celPolicies"`
+
+	byID map[types.RootCause]*CELPolicy
+}
+
+// CELPolicy is a check written as a CEL expression
+type CELPolicy struct {
+	ID                string   `yaml:"id"` // Root cause of the findings it reports
+	Description       string   `yaml:"description"`
+	Scope             string   `yaml:"scope"`
+	Expression        string   `yaml:"expression"`
+	Message           string   `yaml:"message"`
+	MessageExpression string   `yaml:"messageExpression"` // CEL string expression, takes precedence over message
+	Severity          string   `yaml:"severity"`          // HIGH, MEDIUM (default) or LOW
+	Remediation       []string `yaml:"remediation"`
+
+	program        cel.Program
+	messageProgram cel.Program
+}
+
+// celEnv declares the variables policies are compiled against
+// Optional field selection (node.?metadata.?labels) lets policies read fields
+// an object may lack without failing evaluation.
+func celEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.OptionalTypes(),
+		cel.Variable("pod", cel.DynType),
+		cel.Variable("image", cel.DynType),
+		cel.Variable("images", cel.ListType(cel.DynType)),
+		cel.Variable("node", cel.DynType),
+		cel.Variable("findings", cel.ListType(cel.DynType)),
+	)
+}
+
+// LoadCELPolicies reads and compiles the celPolicies of one or more files
+func LoadCELPolicies(paths ...string) (*CELPolicySet, error) {
+	set := &CELPolicySet{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy file: %w", err)
+		}
+		file := &CELPolicySet{}
+		if err := yaml.Unmarshal(data, file); err != nil {
+			return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+		}
+		set.Policies = append(set.Policies, file.Policies...)
+	}
+
+	if err := set.compile(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// ParseCELPolicies parses and compiles policies from YAML
+func ParseCELPolicies(data []byte) (*CELPolicySet, error) {
+	set := &CELPolicySet{}
+	if err := yaml.Unmarshal(data, set); err != nil {
+		return nil, err
+	}
+	if err := set.compile(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// compile type-checks every policy and indexes it by ID
+func (s *CELPolicySet) compile() error {
+	if len(s.Policies) == 0 {
+		return fmt.Errorf("no celPolicies defined")
+	}
+	env, err := celEnv()
+	if err != nil {
+		return err
+	}
+
+	s.byID = make(map[types.RootCause]*CELPolicy, len(s.Policies))
+	for i := range s.Policies {
+		policy := &s.Policies[i]
+		if err := policy.compile(env); err != nil {
+			return fmt.Errorf("policy %d (%s): %w", i+1, policy.ID, err)
+		}
+		cause := types.RootCause(policy.ID)
+		if s.byID[cause] != nil {
+			return fmt.Errorf("policy %d: duplicate id %s", i+1, policy.ID)
+		}
+		s.byID[cause] = policy
+	}
+	return nil
+}
+
+// Policy returns the policy reporting a root cause, for its description and severity
+func (s *CELPolicySet) Policy(cause types.RootCause) (*CELPolicy, bool) {
+	policy, ok := s.byID[cause]
+	return policy, ok
+}
+
+// compile validates a policy and compiles its expressions
+func (p *CELPolicy) compile(env *cel.Env) error {
+	if !ruleIDRe.MatchString(p.ID) {
+		return fmt.Errorf("id must be upper case letters, digits and underscores")
+	}
+	if types.RootCause(p.ID).IsBuiltin() {
+		return fmt.Errorf("%s is a built-in root cause", p.ID)
+	}
+	if p.Description == "" {
+		return fmt.Errorf("description is required")
+	}
+	switch p.Scope {
+	case "":
+		p.Scope = CELScopeImage
+	case CELScopeImage, CELScopePod:
+	default:
+		return fmt.Errorf("scope must be %s or %s, got %q", CELScopeImage, CELScopePod, p.Scope)
+	}
+	switch types.Severity(strings.ToUpper(p.Severity)) {
+	case "":
+		p.Severity = string(types.SeverityMedium)
+	case types.SeverityHigh, types.SeverityMedium, types.SeverityLow:
+		p.Severity = strings.ToUpper(p.Severity)
+	default:
+		return fmt.Errorf("severity must be HIGH, MEDIUM or LOW, got %q", p.Severity)
+	}
+	if p.Expression == "" {
+		return fmt.Errorf("expression is required")
+	}
+
+	var err error
+	if p.program, err = compileCEL(env, p.Expression, cel.BoolType); err != nil {
+		return fmt.Errorf("expression: %w", err)
+	}
+	if p.MessageExpression != "" {
+		if p.messageProgram, err = compileCEL(env, p.MessageExpression, cel.StringType); err != nil {
+			return fmt.Errorf("messageExpression: %w", err)
+		}
+	}
+	return nil
+}
+
+// compileCEL compiles an expression whose result must have the given type
+// Expressions over dynamic variables are accepted and checked at evaluation.
+func compileCEL(env *cel.Env, expression string, want *cel.Type) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if out := ast.OutputType(); !out.IsExactType(want) && !out.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("must evaluate to %s, got %s", want, out)
+	}
+	return env.Program(ast, cel.CostLimit(celCostLimit))
+}
+
+// CELInput is what policies are evaluated against for one pod
+type CELInput struct {
+	Pod      *corev1.Pod
+	Node     *corev1.Node              // nil when unscheduled or not readable
+	Findings []types.DiagnosticFinding // Image pull findings of the pod, if it fails
+}
+
+// EvaluateCELPolicies runs the policies against pods, with their nodes and the
+// findings of those among results
+func (a *Analyzer) EvaluateCELPolicies(ctx context.Context, pods []corev1.Pod, results []PodResult, policies *CELPolicySet) ([]types.DiagnosticFinding, []error) {
+	findingsByPod := make(map[string][]types.DiagnosticFinding)
+	for _, result := range results {
+		if result.Err == nil && result.Report != nil {
+			findingsByPod[result.Pod.Namespace+"/"+result.Pod.Name] = result.Report.Findings
+		}
+	}
+
+	nodesByName := make(map[string]*corev1.Node)
+	if nodes, err := a.listNodes(ctx); err == nil {
+		for i := range nodes {
+			nodesByName[nodes[i].Name] = &nodes[i]
+		}
+	}
+
+	inputs := make([]CELInput, 0, len(pods))
+	for i := range pods {
+		pod := &pods[i]
+		inputs = append(inputs, CELInput{
+			Pod:      pod,
+			Node:     nodesByName[pod.Spec.NodeName],
+			Findings: findingsByPod[pod.Namespace+"/"+pod.Name],
+		})
+	}
+	return policies.Evaluate(inputs)
+}
+
+// celViolation is a policy matching a pod, or one of its images
+type celViolation struct {
+	policy  *CELPolicy
+	pod     *corev1.Pod
+	image   *types.ImageReference
+	message string
+}
+
+// Evaluate runs the policies against every pod and returns a workload-scoped
+// DiagnosticFinding per policy, workload and container it matches, with the
+// evaluation errors (a policy reading a missing field) kept apart
+func (s *CELPolicySet) Evaluate(inputs []CELInput) ([]types.DiagnosticFinding, []error) {
+	var violations []celViolation
+	var errs []error
+	for _, input := range inputs {
+		vars, err := celPodVariables(input)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pod %s/%s: %w", input.Pod.Namespace, input.Pod.Name, err))
+			continue
+		}
+		refs := k8s.GetContainerImages(input.Pod)
+
+		for i := range s.Policies {
+			policy := &s.Policies[i]
+			if policy.Scope == CELScopePod {
+				vars["image"] = map[string]interface{}{}
+				if v, err := policy.evaluate(vars, input.Pod, nil); err != nil {
+					errs = append(errs, err)
+				} else if v != nil {
+					violations = append(violations, *v)
+				}
+				continue
+			}
+			for j := range refs {
+				vars["image"] = imageVariable(refs[j])
+				if v, err := policy.evaluate(vars, input.Pod, &refs[j]); err != nil {
+					errs = append(errs, err)
+				} else if v != nil {
+					violations = append(violations, *v)
+				}
+			}
+		}
+	}
+	return groupCELViolations(violations), errs
+}
+
+// evaluate runs a policy; a nil violation means the pod or image complies
+func (p *CELPolicy) evaluate(vars map[string]interface{}, pod *corev1.Pod, ref *types.ImageReference) (*celViolation, error) {
+	subject := fmt.Sprintf("pod %s/%s", pod.Namespace, pod.Name)
+	if ref != nil {
+		subject += ", container " + ref.ContainerName
+	}
+
+	out, _, err := p.program.Eval(vars)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s on %s: %w", p.ID, subject, err)
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return nil, fmt.Errorf("policy %s on %s: expression returned %v, not a bool", p.ID, subject, out.Value())
+	}
+	if !matched {
+		return nil, nil
+	}
+
+	message := p.Message
+	if p.messageProgram != nil {
+		out, _, err := p.messageProgram.Eval(vars)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s on %s: messageExpression: %w", p.ID, subject, err)
+		}
+		message = fmt.Sprint(out.Value())
+	}
+	if message == "" {
+		message = p.Description
+	}
+	return &celViolation{policy: p, pod: pod, image: ref, message: message}, nil
+}
+
+// celPodVariables converts a pod, its node and findings to CEL variables
+func celPodVariables(input CELInput) (map[string]interface{}, error) {
+	pod, err := runtime.DefaultUnstructuredConverter.ToUnstructured(input.Pod)
+	if err != nil {
+		return nil, err
+	}
+	node := map[string]interface{}{}
+	if input.Node != nil {
+		if node, err = runtime.DefaultUnstructuredConverter.ToUnstructured(input.Node); err != nil {
+			return nil, err
+		}
+	}
+
+	images := []interface{}{}
+	for _, ref := range k8s.GetContainerImages(input.Pod) {
+		images = append(images, imageVariable(ref))
+	}
+	findings := []interface{}{}
+	for _, finding := range input.Findings {
+		containers := make([]interface{}, 0, len(finding.AffectedContainers))
+		for _, c := range finding.AffectedContainers {
+			containers = append(containers, c)
+		}
+		findings = append(findings, map[string]interface{}{
+			"rootCause":  string(finding.RootCause),
+			"severity":   string(finding.Severity),
+			"summary":    finding.Summary,
+			"containers": containers,
+		})
+	}
+
+	return map[string]interface{}{
+		"pod":      pod,
+		"node":     node,
+		"images":   images,
+		"findings": findings,
+	}, nil
+}
+
+// imageVariable exposes a parsed image reference: containerName, fullReference,
+// registry, repository, tag, digest, isDigest and parseError
+func imageVariable(ref types.ImageReference) map[string]interface{} {
+	return map[string]interface{}{
+		"containerName": ref.ContainerName,
+		"fullReference": ref.FullReference,
+		"registry":      ref.Registry,
+		"repository":    ref.Repository,
+		"tag":           ref.Tag,
+		"digest":        ref.Digest,
+		"isDigest":      ref.IsDigest,
+		"parseError":    ref.ParseError,
+	}
+}
+
+// groupCELViolations merges the violations of a policy by workload and container
+func groupCELViolations(violations []celViolation) []types.DiagnosticFinding {
+	type group struct {
+		first *celViolation
+		pods  int
+	}
+	byKey := make(map[string]*group)
+	var order []string
+	for i := range violations {
+		v := &violations[i]
+		workload := podWorkload(v.pod)
+		container := ""
+		if v.image != nil {
+			container = v.image.ContainerName
+		}
+		key := strings.Join([]string{v.policy.ID, workload.Namespace, workload.Kind, workload.Name, container}, "\x00")
+		if g, ok := byKey[key]; ok {
+			g.pods++
+			continue
+		}
+		byKey[key] = &group{first: v, pods: 1}
+		order = append(order, key)
+	}
+	sort.Strings(order)
+
+	findings := make([]types.DiagnosticFinding, 0, len(order))
+	for _, key := range order {
+		g := byKey[key]
+		findings = append(findings, buildCELFinding(g.first, g.pods))
+	}
+	return findings
+}
+
+// buildCELFinding creates a workload-scoped finding for a policy violation
+func buildCELFinding(v *celViolation, pods int) types.DiagnosticFinding {
+	policy := v.policy
+	workload := podWorkload(v.pod)
+	rootCause := types.RootCause(policy.ID)
+
+	finding := types.DiagnosticFinding{
+		RootCause:    rootCause,
+		Severity:     types.Severity(policy.Severity),
+		Scope:        types.FindingScopeWorkload,
+		Subject:      workload.Kind + "/" + workload.Name,
+		PodName:      v.pod.Name,
+		PodNamespace: v.pod.Namespace,
+		Summary:      fmt.Sprintf("%s: %s", string(rootCause), v.message),
+		Details: fmt.Sprintf("Policy %s (%s) matches %d pods of %s %s/%s",
+			policy.ID, policy.Description, pods, workload.Kind, workload.Namespace, workload.Name),
+		RemediationSteps: policy.Remediation,
+	}
+	if v.image != nil {
+		finding.AffectedContainers = []string{v.image.ContainerName}
+		finding.ImageReferences = []types.ImageReference{*v.image}
+	}
+	if len(finding.RemediationSteps) == 0 {
+		finding.RemediationSteps = []string{fmt.Sprintf("Change %s %s/%s to comply with policy %s: %s",
+			workload.Kind, workload.Namespace, workload.Name, policy.ID, policy.Description)}
+	}
+	return finding
+}
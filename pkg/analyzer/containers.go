@@ -0,0 +1,136 @@
+package analyzer
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// quotedImageRe captures the image named in kubelet pull messages:
+// Failed to pull image "registry.example.com/app:1.0": ...
+var quotedImageRe = regexp.MustCompile(`image "([^"]+)"`)
+
+// containerGroup is a set of affected containers that fail for the same root cause
+type containerGroup struct {
+	containers []string
+	imageRefs  []types.ImageReference
+	events     []types.EventSummary
+	evidence   []types.Evidence
+	analysis   *EventAnalysis
+	detection  *Detection
+}
+
+// groupContainersByRootCause detects a root cause for each affected container
+// and groups the containers that share one
+// Each container is scored only on the events and statuses that refer to it, so
+// an init container failing authentication and a sidecar hitting a rate limit
+// yield two groups. Groups keep the order of affectedContainers.
+func groupContainersByRootCause(pod *corev1.Pod, affectedContainers []string, imageRefs []types.ImageReference, events []types.EventSummary, evidence []types.Evidence, rules *RuleSet, now time.Time) []*containerGroup {
+	images := make(map[string]string, len(imageRefs))
+	for _, ref := range imageRefs {
+		images[ref.ContainerName] = ref.FullReference
+	}
+
+	var groups []*containerGroup
+	byCause := make(map[types.RootCause]*containerGroup)
+	for _, container := range affectedContainers {
+		group := newContainerGroup(pod, []string{container}, imageRefs, images, affectedContainers, events, evidence, rules, now)
+		cause := group.detection.RootCause
+		if existing, ok := byCause[cause]; ok {
+			existing.containers = append(existing.containers, container)
+			continue
+		}
+		byCause[cause] = group
+		groups = append(groups, group)
+	}
+
+	// Re-score multi-container groups on their combined evidence for an overall confidence
+	for i, group := range groups {
+		if len(group.containers) == 1 {
+			continue
+		}
+		combined := newContainerGroup(pod, group.containers, imageRefs, images, affectedContainers, events, evidence, rules, now)
+		if combined.detection.RootCause != group.detection.RootCause {
+			// Combined scoring picked another cause; keep the per-container verdict
+			combined.detection = group.detection
+		}
+		groups[i] = combined
+	}
+
+	return groups
+}
+
+// newContainerGroup scores the events and evidence that refer to the given containers
+func newContainerGroup(pod *corev1.Pod, containers []string, imageRefs []types.ImageReference, images map[string]string, affectedContainers []string, events []types.EventSummary, evidence []types.Evidence, rules *RuleSet, now time.Time) *containerGroup {
+	group := &containerGroup{containers: containers}
+
+	members := make(map[string]bool, len(containers))
+	for _, container := range containers {
+		members[container] = true
+	}
+	for _, ref := range imageRefs {
+		if members[ref.ContainerName] {
+			group.imageRefs = append(group.imageRefs, ref)
+		}
+	}
+
+	for _, event := range events {
+		if refersTo(members, event.Container, event.Message, images, affectedContainers) {
+			group.events = append(group.events, event)
+		}
+	}
+	for _, e := range evidence {
+		if refersTo(members, e.Container, e.Message, images, affectedContainers) {
+			group.evidence = append(group.evidence, e)
+		}
+	}
+
+	group.analysis = ParseEvents(group.events)
+	reconcileTransience(group.analysis, pod, group.evidence, now)
+	group.detection = ScoreRootCausesWithRules(group.evidence, group.analysis, rules)
+
+	return group
+}
+
+// refersTo reports whether a message concerns any of the member containers
+// The container named by the event or status wins; otherwise the image quoted
+// in the message is matched against the container images. Messages that name
+// no affected container apply to all of them.
+func refersTo(members map[string]bool, container, message string, images map[string]string, affectedContainers []string) bool {
+	if container != "" {
+		return members[container]
+	}
+
+	if m := quotedImageRe.FindStringSubmatch(message); m != nil {
+		matched := false
+		for _, affected := range affectedContainers {
+			if images[affected] != m[1] {
+				continue
+			}
+			if members[affected] {
+				return true
+			}
+			matched = true
+		}
+		if matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// primaryImage returns the image of the group's first container
+func (g *containerGroup) primaryImage() *types.ImageReference {
+	for i := range g.imageRefs {
+		if g.imageRefs[i].ContainerName == g.containers[0] {
+			return &g.imageRefs[i]
+		}
+	}
+	if len(g.imageRefs) > 0 {
+		return &g.imageRefs[0]
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package analyzer
 
 import (
+	"fmt"
+	"math"
+	"sort"
 	"strings"
 	"time"
 
@@ -8,104 +11,367 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
-// rootCausePatterns defines substring patterns for detecting each root cause type
-var rootCausePatterns = map[types.RootCause][]string{
+// signal is a message pattern with the weight it carries as evidence for a root cause
+// Specific phrases weigh 1.0; generic fragments that also show up in unrelated
+// messages (status codes, "forbidden", "timeout") weigh much less, so they only
+// decide the outcome when nothing more specific matched.
+type signal struct {
+	pattern string
+	weight  float64
+}
+
+// rootCauseSignals defines the weighted patterns for detecting each root cause type
+var rootCauseSignals = map[types.RootCause][]signal{
 	types.RootCauseImageNotFound: {
-		"manifest unknown",
-		"manifest not found",
-		"not found: manifest unknown",
-		"image not found",
-		"repository does not exist",
-		"404",
+		{"manifest unknown", 1.0},
+		{"manifest not found", 1.0},
+		{"image not found", 1.0},
+		{"repository does not exist", 0.8},
+		{"not found", 0.4},
+		{"404", 0.2},
 	},
 	types.RootCauseAuthFailure: {
-		"unauthorized",
-		"authentication required",
-		"authentication failed",
-		"authorization failed",
-		"401",
-		"403",
-		"no basic auth credentials",
-		"pull access denied",
-		"access denied",
-		"access forbidden",
-		"denied: access forbidden",
+		{"authentication required", 1.0},
+		{"authentication failed", 1.0},
+		{"no basic auth credentials", 1.0},
+		{"unauthorized", 0.9},
+		{"authorization failed", 0.8},
+		{"pull access denied", 0.8},
+		{"403 forbidden", 0.7},
+		{"access denied", 0.6},
+		{"access forbidden", 0.6},
+		{"401", 0.5},
+		{"403", 0.5},
 	},
 	types.RootCauseNetworkIssue: {
-		"dial tcp",
-		"timeout",
-		"i/o timeout",
-		"connection refused",
-		"no route to host",
-		"dns",
-		"failed",
-		"lookup",
-		"no such host",
+		{"no such host", 1.0},
+		{"no route to host", 1.0},
+		{"network is unreachable", 1.0},
+		{"i/o timeout", 0.9},
+		{"connection refused", 0.9},
+		{"connection reset by peer", 0.8},
+		{"dial tcp", 0.6},
+		{"timeout", 0.4},
+		{"lookup", 0.3},
+		{"dns", 0.3},
 	},
 	types.RootCauseRateLimit: {
-		"rate limit",
-		"too many requests",
-		"429",
-		"toomanyrequests",
+		{"rate limit", 1.0},
+		{"too many requests", 1.0},
+		{"toomanyrequests", 1.0},
+		{"429", 0.5},
 	},
 	types.RootCausePermissionDenied: {
-		"forbidden",
-		"permission denied",
-		"insufficient",
-		"permission",
+		{"permission denied", 0.7},
+		{"forbidden", 0.3},
+		{"insufficient", 0.2},
+		{"permission", 0.2},
 	},
 	types.RootCauseManifestError: {
-		"manifest invalid",
-		"unsupported",
-		"platform",
-		"no matching manifest",
-		"unknown blob",
+		{"manifest invalid", 1.0},
+		{"no matching manifest", 1.0},
+		{"unknown blob", 0.8},
+		{"unsupported", 0.3},
+		{"platform", 0.3},
+	},
+	types.RootCauseNodeDiskPressure: {
+		{"no space left on device", 1.0},
+		{"disk quota exceeded", 1.0},
+		{"not enough disk space", 1.0},
+	},
+	types.RootCausePolicyRejection: {
+		{"blocked due to the following policies", 1.0},
+		{"validatingadmissionpolicy", 1.0},
+		{"image policy webhook", 1.0},
+		{"denied the request", 0.9},
+	},
+	types.RootCauseTLSCertificate: {
+		{"x509:", 1.0},
+		{"certificate signed by unknown authority", 1.0},
+		{"certificate has expired", 1.0},
+		{"certificate is not valid", 1.0},
+		{"certificate is valid for", 1.0},
+		{"failed to verify certificate", 1.0},
+		{"tls: failed to verify", 1.0},
+		{"server gave http response to https client", 1.0},
+	},
+	types.RootCauseImageNeverPull: {
+		{"is not present with pull policy of never", 1.0},
+		{"errimageneverpull", 0.8},
+	},
+	types.RootCauseInvalidImageName: {
+		{"invalid reference format", 1.0},
+		{"couldn't parse image reference", 1.0},
+		{"failed to apply default image tag", 0.8},
 	},
+	types.RootCauseRegistryUnavailable: {
+		{"registry is unavailable", 1.0},
+		{"registry unavailable", 1.0},
+		{"service unavailable", 0.7},
+	},
+	types.RootCauseSignatureValidation: {
+		{"signature validation failed", 1.0},
+		{"signature verification failed", 1.0},
+		{"a signature was required", 1.0},
+		{"source image rejected", 0.9},
+	},
+}
+
+// waitingReasonRootCauses maps kubelet reasons that identify the failure on their own
+// They apply to container waiting reasons as well as event reasons.
+var waitingReasonRootCauses = map[string]types.RootCause{
+	"ErrImageNeverPull":         types.RootCauseImageNeverPull,
+	"InvalidImageName":          types.RootCauseInvalidImageName,
+	"RegistryUnavailable":       types.RootCauseRegistryUnavailable,
+	"SignatureValidationFailed": types.RootCauseSignatureValidation,
 }
 
-// DetectRootCause determines the root cause from event messages
-// Uses priority ordering: IMAGE_NOT_FOUND > AUTH > NETWORK > RATE_LIMIT > PERMISSION > MANIFEST > TRANSIENT > UNKNOWN
+// waitingReasonWeight is the weight of a conclusive kubelet reason
+// It outweighs any single message pattern.
+const waitingReasonWeight = 2.0
+
+// minConfidence is the confidence a candidate needs to be reported as the root cause
+// Below it, the failure is reported as TRANSIENT_FAILURE or UNKNOWN.
+const minConfidence = 0.25
+
+// maxAlternatives bounds the runner-up candidates kept in a finding
+const maxAlternatives = 3
+
+// rootCausePriority breaks ties between equally scored candidates, highest priority first
+var rootCausePriority = []types.RootCause{
+	types.RootCauseNodeDiskPressure,
+	types.RootCausePolicyRejection,
+	types.RootCauseImageNeverPull,
+	types.RootCauseInvalidImageName,
+	types.RootCauseSignatureValidation,
+	types.RootCauseTLSCertificate,
+	types.RootCauseImageNotFound,
+	types.RootCauseAuthFailure,
+	types.RootCauseNetworkIssue,
+	types.RootCauseRateLimit,
+	types.RootCausePermissionDenied,
+	types.RootCauseManifestError,
+	types.RootCauseRegistryUnavailable,
+}
+
+// Detection is the outcome of root cause scoring
+type Detection struct {
+	RootCause  types.RootCause
+	Confidence float64
+	Matches    []types.PatternMatch       // Matches supporting the selected root cause
+	Evidence   []types.Evidence           // Evidence items supporting the selected root cause
+	Candidates []types.RootCauseCandidate // All scored candidates, best first
+	Trace      []string                   // Human-readable decision trace (--explain)
+}
+
+// Alternatives returns the best-ranked candidates other than the selected root cause
+func (d *Detection) Alternatives() []types.RootCauseCandidate {
+	var alternatives []types.RootCauseCandidate
+	for _, c := range d.Candidates {
+		if c.RootCause == d.RootCause {
+			continue
+		}
+		alternatives = append(alternatives, c)
+		if len(alternatives) == maxAlternatives {
+			break
+		}
+	}
+	return alternatives
+}
+
+// DetectRootCause determines the root cause from event messages and the pod's container statuses
 func DetectRootCause(events []types.EventSummary, pod *corev1.Pod, analysis *EventAnalysis) types.RootCause {
-	// Concatenate all event messages for pattern matching
-	var messages strings.Builder
-	for _, event := range events {
-		messages.WriteString(strings.ToLower(event.Message))
-		messages.WriteString(" ")
+	rootCause, _ := DetectRootCauseFromEvidence(CollectEvidence(events, pod), analysis)
+	return rootCause
+}
+
+// DetectRootCauseFromEvidence determines the root cause from collected evidence
+// Returns the root cause together with the evidence items that support it,
+// so callers can report which source (event or container status) each conclusion came from.
+func DetectRootCauseFromEvidence(evidence []types.Evidence, analysis *EventAnalysis) (types.RootCause, []types.Evidence) {
+	detection := ScoreRootCauses(evidence, analysis)
+	return detection.RootCause, detection.Evidence
+}
+
+// ScoreRootCauses ranks candidate root causes by the weight of their evidence
+// Each evidence item contributes the weight of its strongest matching pattern to
+// every cause it matches, so the same message can support several candidates.
+// Confidence is the candidate's share of the total score scaled by its strongest
+// match: a cause supported only by generic patterns stays below minConfidence
+// even when nothing else matched. Conclusive kubelet reasons (ErrImageNeverPull,
+// InvalidImageName, ...) count with waitingReasonWeight.
+func ScoreRootCauses(evidence []types.Evidence, analysis *EventAnalysis) *Detection {
+	return ScoreRootCausesWithRules(evidence, analysis, nil)
+}
+
+// ScoreRootCausesWithRules ranks candidate root causes using the built-in table
+// merged with user-defined rules (see RuleSet)
+func ScoreRootCausesWithRules(evidence []types.Evidence, analysis *EventAnalysis, rules *RuleSet) *Detection {
+	detection := &Detection{}
+
+	type tally struct {
+		score      float64
+		best       float64
+		matches    []types.PatternMatch
+		supporting []types.Evidence
 	}
-	combinedMessages := messages.String()
+	tallies := make(map[types.RootCause]*tally)
+	get := func(cause types.RootCause) *tally {
+		if tallies[cause] == nil {
+			tallies[cause] = &tally{}
+		}
+		return tallies[cause]
+	}
+
+	for i, e := range evidence {
+		lower := strings.ToLower(e.Message)
+		bestPerCause := make(map[types.RootCause]float64)
 
-	// Check patterns in priority order
-	if matchPatterns(combinedMessages, rootCausePatterns[types.RootCauseImageNotFound]) {
-		return types.RootCauseImageNotFound
+		if cause, ok := waitingReasonRootCauses[e.Reason]; ok {
+			t := get(cause)
+			t.matches = append(t.matches, types.PatternMatch{
+				Pattern:   "reason=" + e.Reason,
+				Fragment:  e.Reason,
+				Weight:    waitingReasonWeight,
+				Source:    e.Source,
+				Container: e.Container,
+			})
+			bestPerCause[cause] = waitingReasonWeight
+			detection.Trace = append(detection.Trace, fmt.Sprintf("evidence[%d] %s: reason %s is conclusive for %s (weight %.2f)",
+				i, describeEvidence(e), e.Reason, string(cause), waitingReasonWeight))
+		}
+
+		for _, cause := range rules.causeOrder() {
+			for _, sig := range rules.signalsFor(cause) {
+				idx, length := rules.find(sig, e.Message, lower)
+				if idx < 0 {
+					continue
+				}
+				t := get(cause)
+				t.matches = append(t.matches, types.PatternMatch{
+					Pattern:   sig.pattern,
+					Fragment:  fragment(e.Message, lower, idx, length),
+					Weight:    sig.weight,
+					Source:    e.Source,
+					Container: e.Container,
+				})
+				if sig.weight > bestPerCause[cause] {
+					bestPerCause[cause] = sig.weight
+				}
+				detection.Trace = append(detection.Trace, fmt.Sprintf("evidence[%d] %s: matched %q for %s (weight %.2f)",
+					i, describeEvidence(e), sig.pattern, string(cause), sig.weight))
+			}
+		}
+
+		for cause, weight := range bestPerCause {
+			t := get(cause)
+			t.score += weight
+			if weight > t.best {
+				t.best = weight
+			}
+			t.supporting = append(t.supporting, e)
+		}
 	}
 
-	if matchPatterns(combinedMessages, rootCausePatterns[types.RootCauseAuthFailure]) {
-		return types.RootCauseAuthFailure
+	var total float64
+	for _, t := range tallies {
+		total += t.score
 	}
 
-	if matchPatterns(combinedMessages, rootCausePatterns[types.RootCauseNetworkIssue]) {
-		return types.RootCauseNetworkIssue
+	for cause, t := range tallies {
+		confidence := math.Min(1, t.best) * t.score / total
+		detection.Candidates = append(detection.Candidates, types.RootCauseCandidate{
+			RootCause:  cause,
+			Confidence: math.Round(confidence*100) / 100,
+			Score:      math.Round(t.score*100) / 100,
+			Matches:    t.matches,
+		})
 	}
+	sort.SliceStable(detection.Candidates, func(i, j int) bool {
+		a, b := detection.Candidates[i], detection.Candidates[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return rules.rank(a.RootCause) < rules.rank(b.RootCause)
+	})
 
-	if matchPatterns(combinedMessages, rootCausePatterns[types.RootCauseRateLimit]) {
-		return types.RootCauseRateLimit
+	for _, c := range detection.Candidates {
+		detection.Trace = append(detection.Trace, fmt.Sprintf("candidate %s: score %.2f, confidence %.2f", string(c.RootCause), c.Score, c.Confidence))
 	}
 
-	if matchPatterns(combinedMessages, rootCausePatterns[types.RootCausePermissionDenied]) {
-		return types.RootCausePermissionDenied
+	if len(detection.Candidates) > 0 && detection.Candidates[0].Confidence >= minConfidence {
+		best := detection.Candidates[0]
+		detection.RootCause = best.RootCause
+		detection.Confidence = best.Confidence
+		detection.Matches = best.Matches
+		detection.Evidence = tallies[best.RootCause].supporting
+		detection.Trace = append(detection.Trace, fmt.Sprintf("selected %s: highest score with confidence %.2f >= %.2f",
+			string(best.RootCause), best.Confidence, minConfidence))
+		return detection
 	}
 
-	if matchPatterns(combinedMessages, rootCausePatterns[types.RootCauseManifestError]) {
-		return types.RootCauseManifestError
+	if len(detection.Candidates) > 0 {
+		detection.Trace = append(detection.Trace, fmt.Sprintf("no candidate reached confidence %.2f", minConfidence))
+	} else {
+		detection.Trace = append(detection.Trace, "no pattern matched any evidence")
 	}
 
 	// Check for transient failure (logic-based, not pattern-based)
 	if analysis != nil && analysis.IsTransient {
-		return types.RootCauseTransient
+		detection.RootCause = types.RootCauseTransient
+		detection.Trace = append(detection.Trace, fmt.Sprintf("selected %s: %d failure(s), below the persistent threshold (3+ failures over 5+ minutes)",
+			string(types.RootCauseTransient), analysis.FailureCount))
+		return detection
+	}
+
+	detection.RootCause = types.RootCauseUnknown
+	detection.Trace = append(detection.Trace, "selected "+string(types.RootCauseUnknown))
+	return detection
+}
+
+// priorityIndex returns the tie-breaking rank of a root cause
+func priorityIndex(cause types.RootCause) int {
+	for i, c := range rootCausePriority {
+		if c == cause {
+			return i
+		}
 	}
+	return len(rootCausePriority)
+}
 
-	// Default to unknown if no patterns match
-	return types.RootCauseUnknown
+// describeEvidence renders an evidence item's origin for the decision trace
+func describeEvidence(e types.Evidence) string {
+	origin := string(e.Source)
+	if e.Container != "" {
+		origin += " " + e.Container
+	}
+	if e.Reason != "" {
+		origin += " (" + e.Reason + ")"
+	}
+	return origin
+}
+
+// fragment returns the matched text with some surrounding context
+// Offsets come from the lowercased message; they only map back to the original
+// when lowercasing did not change its length (always the case for ASCII).
+func fragment(message, lower string, idx, length int) string {
+	const contextChars = 30
+	source := message
+	if len(lower) != len(message) {
+		source = lower
+	}
+
+	start := idx - contextChars
+	prefix := "..."
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	end := idx + length + contextChars
+	suffix := "..."
+	if end >= len(source) {
+		end, suffix = len(source), ""
+	}
+	return prefix + source[start:end] + suffix
 }
 
 // matchPatterns checks if any of the patterns exist in the text
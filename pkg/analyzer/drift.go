@@ -0,0 +1,239 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// driftGroup accumulates the replicas of a workload running one container image
+type driftGroup struct {
+	workload  types.AffectedWorkload
+	container string
+	image     string
+	replicas  int
+	digests   map[string][]string // Digest -> pods running it
+	pod       *corev1.Pod         // A replica, to resolve the pod template owner from
+}
+
+// AnalyzeImageDrift detects image drift across the replicas of each workload
+// With resolve, every tag in use is resolved in its registry once, through the
+// configured mirror, to detect tags that moved since the pods pulled them.
+// Each drift gets a patch pinning the image by digest on the pod template
+// owner; pods whose template cannot be patched only get the pinned image.
+func (a *Analyzer) AnalyzeImageDrift(ctx context.Context, pods []corev1.Pod, resolve bool) []types.ImageDrift {
+	var registryDigests, registryErrors map[string]string
+	if resolve {
+		registryDigests, registryErrors = a.resolveTags(ctx, pods)
+	}
+
+	drifts, groups := detectImageDrift(pods, registryDigests)
+	for i := range drifts {
+		drift := &drifts[i]
+		drift.RegistryError = registryErrors[drift.Image]
+		if drift.PinnedImage == "" {
+			continue
+		}
+		p := &fixPlanner{a: a, pod: groups[i].pod}
+		if action, ok := p.setImage(ctx, "", drift.Container, drift.PinnedImage); ok {
+			action.Description = fmt.Sprintf("Pin container %s to %s", drift.Container, drift.PinnedImage)
+			drift.Action = &action
+		}
+	}
+	return drifts
+}
+
+// DetectImageDrift compares ContainerStatus.ImageID across the replicas of each
+// workload and reports containers whose replicas run different digests, whose
+// tag now resolves to a digest no replica runs, or that use :latest
+// registryDigests maps image references, as written in pod specs, to the digest
+// their registry serves now; nil skips the moved-tag check. Images referenced
+// by digest cannot drift and are never reported.
+func DetectImageDrift(pods []corev1.Pod, registryDigests map[string]string) []types.ImageDrift {
+	drifts, _ := detectImageDrift(pods, registryDigests)
+	return drifts
+}
+
+// detectImageDrift returns the drifts and, at the same index, the group each was built from
+func detectImageDrift(pods []corev1.Pod, registryDigests map[string]string) ([]types.ImageDrift, []*driftGroup) {
+	groupsByKey := make(map[string]*driftGroup)
+	var groups []*driftGroup
+	for i := range pods {
+		pod := &pods[i]
+		workload := podWorkload(pod)
+		digests := make(map[string]string)
+		for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+			digests[status.Name] = imageIDDigest(status.ImageID)
+		}
+
+		for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+			key := strings.Join([]string{workload.Namespace, workload.Kind, workload.Name, container.Name, container.Image}, "\x00")
+			group, ok := groupsByKey[key]
+			if !ok {
+				group = &driftGroup{workload: workload, container: container.Name, image: container.Image, digests: make(map[string][]string), pod: pod}
+				groupsByKey[key] = group
+				groups = append(groups, group)
+			}
+			group.replicas++
+			if digest := digests[container.Name]; digest != "" {
+				group.digests[digest] = append(group.digests[digest], pod.Name)
+			}
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.workload.Namespace != b.workload.Namespace {
+			return a.workload.Namespace < b.workload.Namespace
+		}
+		if a.workload.Kind != b.workload.Kind {
+			return a.workload.Kind < b.workload.Kind
+		}
+		if a.workload.Name != b.workload.Name {
+			return a.workload.Name < b.workload.Name
+		}
+		return a.container < b.container
+	})
+
+	var drifts []types.ImageDrift
+	var drifted []*driftGroup
+	for _, group := range groups {
+		if drift, ok := buildImageDrift(group, registryDigests[group.image]); ok {
+			drifts = append(drifts, drift)
+			drifted = append(drifted, group)
+		}
+	}
+	return drifts, drifted
+}
+
+// buildImageDrift rates a group of replicas; ok is false when the image does not drift
+func buildImageDrift(group *driftGroup, registryDigest string) (types.ImageDrift, bool) {
+	ref, err := types.ParseImageReference(group.container, group.image)
+	if err != nil || ref.IsDigest {
+		return types.ImageDrift{}, false
+	}
+
+	drift := types.ImageDrift{
+		Namespace:      group.workload.Namespace,
+		Kind:           group.workload.Kind,
+		Name:           group.workload.Name,
+		Container:      group.container,
+		Image:          group.image,
+		Replicas:       group.replicas,
+		RegistryDigest: registryDigest,
+	}
+	for digest, pods := range group.digests {
+		sort.Strings(pods)
+		drift.Digests = append(drift.Digests, types.ReplicaDigest{Digest: digest, Pods: pods})
+	}
+	sort.Slice(drift.Digests, func(i, j int) bool {
+		a, b := drift.Digests[i], drift.Digests[j]
+		if len(a.Pods) != len(b.Pods) {
+			return len(a.Pods) > len(b.Pods)
+		}
+		return a.Digest < b.Digest
+	})
+
+	if len(drift.Digests) > 1 {
+		running := make([]string, 0, len(drift.Digests))
+		for _, d := range drift.Digests {
+			running = append(running, fmt.Sprintf("%s (%d pods)", shortDigest(d.Digest), len(d.Pods)))
+		}
+		drift.Kinds = append(drift.Kinds, types.DriftReplicasDiffer)
+		drift.Details = append(drift.Details, fmt.Sprintf("%d replicas run %d different digests of %s: %s",
+			group.replicas, len(drift.Digests), group.image, strings.Join(running, ", ")))
+	}
+	if registryDigest != "" && len(drift.Digests) > 0 && group.digests[registryDigest] == nil {
+		drift.Kinds = append(drift.Kinds, types.DriftTagMoved)
+		drift.Details = append(drift.Details, fmt.Sprintf("Tag %s now resolves to %s in the registry, which no replica runs: new pods on nodes without a cached copy will run it",
+			ref.Tag, shortDigest(registryDigest)))
+	}
+	if ref.Tag == "latest" {
+		drift.Kinds = append(drift.Kinds, types.DriftLatestTag)
+		drift.Details = append(drift.Details, fmt.Sprintf("%s uses :latest (explicitly or by default): every pod start resolves it again, and rollbacks cannot return to a known image", group.image))
+	}
+	if len(drift.Kinds) == 0 {
+		return types.ImageDrift{}, false
+	}
+
+	drift.Severity = types.SeverityLow
+	for _, kind := range drift.Kinds {
+		switch kind {
+		case types.DriftReplicasDiffer:
+			drift.Severity = types.SeverityHigh
+		case types.DriftTagMoved:
+			if drift.Severity != types.SeverityHigh {
+				drift.Severity = types.SeverityMedium
+			}
+		}
+	}
+
+	// Pin to the digest most replicas run, known to work; otherwise to the registry's
+	pin := registryDigest
+	if len(drift.Digests) > 0 {
+		pin = drift.Digests[0].Digest
+	}
+	if pin != "" {
+		drift.PinnedImage = group.image + "@" + pin
+	}
+	return drift, true
+}
+
+// resolveTags resolves every tag-referenced image of the pods in its registry
+// Returns the digests and the errors, both keyed by image as written in the pod spec.
+func (a *Analyzer) resolveTags(ctx context.Context, pods []corev1.Pod) (map[string]string, map[string]string) {
+	var refs []types.ImageReference
+	seen := make(map[string]bool)
+	for _, pod := range pods {
+		for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+			if seen[container.Image] {
+				continue
+			}
+			seen[container.Image] = true
+			if ref, err := types.ParseImageReference(container.Name, container.Image); err == nil && !ref.IsDigest {
+				refs = append(refs, *ref)
+			}
+		}
+	}
+	a.resolveMirrors(refs)
+
+	results := make([]string, len(refs))
+	errs := make([]error, len(refs))
+	_ = RunConcurrently(ctx, DefaultConcurrency, len(refs), func(ctx context.Context, i int) {
+		results[i], errs[i] = ManifestDigest(ctx, refs[i], a.insecureRegistry(refs[i]))
+	}, nil)
+
+	digests := make(map[string]string, len(refs))
+	failures := make(map[string]string)
+	for i, ref := range refs {
+		switch {
+		case errs[i] != nil:
+			failures[ref.FullReference] = errs[i].Error()
+		case results[i] != "":
+			digests[ref.FullReference] = results[i]
+		}
+	}
+	return digests, failures
+}
+
+// imageIDDigest returns the repository digest of a ContainerStatus.ImageID
+// Runtimes report it as [docker-pullable://]name@digest; a bare image ID
+// (sha256 of the local config) is not comparable across nodes and is ignored.
+func imageIDDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	return ""
+}
+
+// shortDigest abbreviates a digest for messages, like the runtimes' CLIs
+func shortDigest(digest string) string {
+	if algorithm, encoded, ok := strings.Cut(digest, ":"); ok && len(encoded) > 12 {
+		return algorithm + ":" + encoded[:12]
+	}
+	return digest
+}
@@ -3,6 +3,7 @@ package analyzer
 import (
 	"time"
 
+	"github.com/aboigues/k8t/pkg/k8s"
 	"github.com/aboigues/k8t/pkg/types"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -74,19 +75,16 @@ func ParseEventsFromK8s(events []corev1.Event) *EventAnalysis {
 			continue
 		}
 
-		// Count failures
-		if event.Count > 0 {
-			analysis.FailureCount += int(event.Count)
-		} else {
-			analysis.FailureCount++
-		}
+		// Count failures (series events report occurrences in Series.Count)
+		analysis.FailureCount += k8s.EventCount(&event)
 
-		// Track first and last failure times
-		if analysis.FirstFailureTime.IsZero() || event.FirstTimestamp.Time.Before(analysis.FirstFailureTime) {
-			analysis.FirstFailureTime = event.FirstTimestamp.Time
+		// Track first and last failure times (normalized across event API versions)
+		firstSeen, lastSeen := k8s.EventFirstSeen(&event), k8s.EventLastSeen(&event)
+		if analysis.FirstFailureTime.IsZero() || firstSeen.Before(analysis.FirstFailureTime) {
+			analysis.FirstFailureTime = firstSeen
 		}
-		if analysis.LastFailureTime.IsZero() || event.LastTimestamp.Time.After(analysis.LastFailureTime) {
-			analysis.LastFailureTime = event.LastTimestamp.Time
+		if analysis.LastFailureTime.IsZero() || lastSeen.After(analysis.LastFailureTime) {
+			analysis.LastFailureTime = lastSeen
 		}
 
 		// Extract error message
@@ -123,5 +121,6 @@ func isFailureEvent(event *corev1.Event) bool {
 		reason == "ErrImagePull" ||
 		reason == "ImagePullBackOff" ||
 		reason == "FailedPull" ||
-		reason == "InspectFailed"
+		reason == "InspectFailed" ||
+		reason == "ErrImageNeverPull"
 }
@@ -0,0 +1,113 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CollectEvidence gathers the messages available for root cause detection
+// Events are listed first, followed by container status messages. Container
+// statuses keep the last pull error after the apiserver has expired the
+// events (~1h), so long-running failures can still be diagnosed.
+func CollectEvidence(events []types.EventSummary, pod *corev1.Pod) []types.Evidence {
+	evidence := make([]types.Evidence, 0, len(events))
+
+	for _, event := range events {
+		if event.Message == "" {
+			continue
+		}
+		evidence = append(evidence, types.Evidence{
+			Source:    types.EvidenceSourceEvent,
+			Container: event.Container,
+			Reason:    event.Reason,
+			Message:   event.Message,
+		})
+	}
+
+	if pod == nil {
+		return evidence
+	}
+
+	statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+
+	for _, cs := range statuses {
+		// Only image pull failures are relevant; other waiting reasons would add noise
+		if cs.State.Waiting == nil || !k8s.IsImagePullWaitingReason(cs.State.Waiting.Reason) {
+			continue
+		}
+
+		// Reasons such as ErrImageNeverPull are conclusive even without a message
+		message := cs.State.Waiting.Message
+		if _, conclusive := waitingReasonRootCauses[cs.State.Waiting.Reason]; message == "" && conclusive {
+			message = cs.State.Waiting.Reason
+		}
+		if message != "" {
+			evidence = append(evidence, types.Evidence{
+				Source:    types.EvidenceSourceContainerStatus,
+				Container: cs.Name,
+				Reason:    cs.State.Waiting.Reason,
+				Message:   output.RedactEventMessage(message),
+			})
+		}
+
+		if last := lastStateEvidence(cs); last != nil {
+			evidence = append(evidence, *last)
+		}
+	}
+
+	return evidence
+}
+
+// lastStateEvidence extracts the message of a container's previous state, if any
+func lastStateEvidence(cs corev1.ContainerStatus) *types.Evidence {
+	var reason, message string
+	switch {
+	case cs.LastTerminationState.Waiting != nil:
+		reason = cs.LastTerminationState.Waiting.Reason
+		message = cs.LastTerminationState.Waiting.Message
+	case cs.LastTerminationState.Terminated != nil:
+		reason = cs.LastTerminationState.Terminated.Reason
+		message = cs.LastTerminationState.Terminated.Message
+	}
+
+	if message == "" {
+		return nil
+	}
+
+	return &types.Evidence{
+		Source:    types.EvidenceSourceLastTerminationState,
+		Container: cs.Name,
+		Reason:    reason,
+		Message:   output.RedactEventMessage(message),
+	}
+}
+
+// hasStatusEvidence reports whether any evidence came from container statuses
+func hasStatusEvidence(evidence []types.Evidence) bool {
+	for _, e := range evidence {
+		if e.Source != types.EvidenceSourceEvent {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileTransience corrects the event-based transient verdict using the pod age
+// When events have expired only a few recent ones remain, which looks transient
+// even though the container has been failing to pull for much longer.
+func reconcileTransience(analysis *EventAnalysis, pod *corev1.Pod, evidence []types.Evidence, now time.Time) {
+	if analysis == nil || !analysis.IsTransient || pod == nil || pod.Status.StartTime == nil {
+		return
+	}
+	if !hasStatusEvidence(evidence) {
+		return
+	}
+	if now.Sub(pod.Status.StartTime.Time) >= 5*time.Minute {
+		analysis.IsTransient = false
+	}
+}
@@ -0,0 +1,369 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FixOptions carries user choices that remediation planning cannot infer
+type FixOptions struct {
+	PullSecret string            // Pull secret to reference for authentication failures (default: first secret matching the registry)
+	Images     map[string]string // Container name -> replacement image (--set-image)
+}
+
+// fixPlanner plans remediation actions for one pod, resolving its owner lazily
+type fixPlanner struct {
+	a     *Analyzer
+	pod   *corev1.Pod
+	owner *types.ObjectReference
+	err   error
+}
+
+// PlanRemediation turns the findings of a pod into machine-applicable actions
+// Each finding's Actions are set and all actions are returned in finding order.
+// Root causes without a safe automatic fix yield no action; their remediation
+// steps remain the guidance.
+func (a *Analyzer) PlanRemediation(ctx context.Context, pod *corev1.Pod, findings []types.DiagnosticFinding, opts FixOptions) ([]types.RemediationAction, error) {
+	p := &fixPlanner{a: a, pod: pod}
+
+	// Map order would make the plan and its dry-run output vary between runs
+	containers := make([]string, 0, len(opts.Images))
+	for container := range opts.Images {
+		containers = append(containers, container)
+	}
+	sort.Strings(containers)
+
+	for _, container := range containers {
+		if _, ok := findContainer(pod, container); !ok {
+			return nil, NewValidationError("set-image", fmt.Sprintf("container %q not found in pod %s", container, pod.Name))
+		}
+	}
+
+	var all []types.RemediationAction
+	explicit := make(map[string]bool, len(opts.Images))
+	for i := range findings {
+		finding := &findings[i]
+		var actions []types.RemediationAction
+
+		// Explicit image replacements apply whatever the root cause
+		for _, container := range finding.AffectedContainers {
+			if image, ok := opts.Images[container]; ok {
+				explicit[container] = true
+				if action, ok := p.setImage(ctx, finding.RootCause, container, image); ok {
+					actions = append(actions, action)
+				}
+			}
+		}
+
+		switch finding.RootCause {
+		case types.RootCauseAuthFailure:
+			if action, ok := p.addImagePullSecret(ctx, finding, opts.PullSecret); ok {
+				actions = append(actions, action)
+			}
+		case types.RootCauseImageNeverPull:
+			for _, container := range finding.AffectedContainers {
+				if action, ok := p.setImagePullPolicy(ctx, finding.RootCause, container, corev1.PullIfNotPresent); ok {
+					actions = append(actions, action)
+				}
+			}
+		case types.RootCauseInvalidImageName:
+			for _, container := range finding.AffectedContainers {
+				if explicit[container] {
+					continue
+				}
+				spec, _ := findContainer(pod, container)
+				image := normalizeImage(spec.Image)
+				if _, err := types.ParseImageReference(container, image); err == nil && image != spec.Image {
+					if action, ok := p.setImage(ctx, finding.RootCause, container, image); ok {
+						actions = append(actions, action)
+					}
+				}
+			}
+		}
+
+		finding.Actions = actions
+		all = append(all, actions...)
+	}
+
+	// --set-image for containers without a finding (e.g. a rollback of a healthy sidecar)
+	for _, container := range containers {
+		if !explicit[container] {
+			if action, ok := p.setImage(ctx, "", container, opts.Images[container]); ok {
+				all = append(all, action)
+			}
+		}
+	}
+
+	if len(all) == 0 && p.err != nil {
+		return nil, p.err
+	}
+	return all, nil
+}
+
+// templateOwner returns the object whose pod template must be patched
+func (p *fixPlanner) templateOwner(ctx context.Context) (types.ObjectReference, bool) {
+	if p.owner != nil {
+		return *p.owner, true
+	}
+	if p.err != nil {
+		return types.ObjectReference{}, false
+	}
+
+	if owner := metav1.GetControllerOf(p.pod); owner != nil && owner.Kind == "ReplicaSet" {
+		p.a.auditLogger.LogObjectGet("replicasets", owner.Name, p.pod.Namespace)
+	}
+	ref, err := p.a.k8sClient.PodTemplateOwner(ctx, p.pod)
+	if err != nil {
+		p.a.auditLogger.LogWarning(fmt.Sprintf("Cannot resolve the owner of pod %s: %v", p.pod.Name, err))
+		p.err = err
+		return types.ObjectReference{}, false
+	}
+	p.owner = &ref
+	return ref, true
+}
+
+// addImagePullSecret references a pull secret from the ServiceAccount, or from
+// the pod template when the pod lists its own imagePullSecrets (the
+// ServiceAccount's are then ignored by admission)
+func (p *fixPlanner) addImagePullSecret(ctx context.Context, finding *types.DiagnosticFinding, secret string) (types.RemediationAction, bool) {
+	ns := p.pod.Namespace
+	if secret == "" {
+		secret = p.matchingPullSecret(ctx, finding.ImageReferences)
+		if secret == "" {
+			return types.RemediationAction{}, false
+		}
+	}
+	for _, ref := range p.pod.Spec.ImagePullSecrets {
+		if ref.Name == secret {
+			// Already referenced: the credentials themselves are wrong
+			return types.RemediationAction{}, false
+		}
+	}
+
+	action := types.RemediationAction{
+		Kind:      types.ActionAddImagePullSecret,
+		RootCause: finding.RootCause,
+	}
+
+	if len(p.pod.Spec.ImagePullSecrets) > 0 {
+		target, ok := p.templateOwner(ctx)
+		if !ok || target.Kind == "Pod" {
+			// imagePullSecrets of a running pod are immutable
+			return types.RemediationAction{}, false
+		}
+		action.Target = target
+		action.Description = fmt.Sprintf("Add %s to the imagePullSecrets of the pod template", secret)
+		action.PatchType = types.PatchTypeStrategicMerge
+		action.Patch = podSpecPatch(target, map[string]interface{}{
+			"imagePullSecrets": []map[string]string{{"name": secret}},
+		})
+		return action, true
+	}
+
+	saName := p.pod.Spec.ServiceAccountName
+	if saName == "" {
+		saName = "default"
+	}
+	p.a.auditLogger.LogServiceAccountGet(saName, ns)
+	sa, err := p.a.k8sClient.GetServiceAccount(ctx, ns, saName)
+	if err != nil {
+		p.a.auditLogger.LogWarning(fmt.Sprintf("Cannot read service account %s: %v", saName, err))
+		return types.RemediationAction{}, false
+	}
+	for _, ref := range sa.ImagePullSecrets {
+		if ref.Name == secret {
+			// Added after the pod was admitted; only a restart picks it up
+			return types.RemediationAction{}, false
+		}
+	}
+
+	var ops []map[string]interface{}
+	if len(sa.ImagePullSecrets) == 0 {
+		ops = append(ops, map[string]interface{}{"op": "add", "path": "/imagePullSecrets", "value": []map[string]string{{"name": secret}}})
+	} else {
+		ops = append(ops, map[string]interface{}{"op": "add", "path": "/imagePullSecrets/-", "value": map[string]string{"name": secret}})
+	}
+	patch, _ := json.Marshal(ops)
+
+	action.Target = types.ObjectReference{Kind: "ServiceAccount", Namespace: ns, Name: saName}
+	action.Description = fmt.Sprintf("Add %s to the imagePullSecrets of service account %s (pods created afterwards use it; restart the workload to recreate failing pods)", secret, saName)
+	action.PatchType = types.PatchTypeJSON
+	action.Patch = string(patch)
+	return action, true
+}
+
+// matchingPullSecret returns the first pull secret with credentials for one of the images' registries
+func (p *fixPlanner) matchingPullSecret(ctx context.Context, imageRefs []types.ImageReference) string {
+	p.a.auditLogger.LogSecretList(p.pod.Namespace)
+	secrets, err := p.a.k8sClient.ListPullSecrets(ctx, p.pod.Namespace)
+	if err != nil {
+		p.a.auditLogger.LogWarning(fmt.Sprintf("Cannot list pull secrets: %v", err))
+		return ""
+	}
+
+	for _, ref := range imageRefs {
+		for i := range secrets {
+			for _, registry := range k8s.PullSecretRegistries(&secrets[i]) {
+				if registry == ref.Registry {
+					return secrets[i].Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// setImagePullPolicy changes a container's pull policy on its pod template
+func (p *fixPlanner) setImagePullPolicy(ctx context.Context, cause types.RootCause, container string, policy corev1.PullPolicy) (types.RemediationAction, bool) {
+	target, ok := p.templateOwner(ctx)
+	if !ok || target.Kind == "Pod" {
+		// imagePullPolicy of a running pod is immutable
+		return types.RemediationAction{}, false
+	}
+
+	return types.RemediationAction{
+		Kind:        types.ActionSetImagePullPolicy,
+		Description: fmt.Sprintf("Set imagePullPolicy of container %s to %s", container, policy),
+		RootCause:   cause,
+		Target:      target,
+		PatchType:   types.PatchTypeStrategicMerge,
+		Patch:       p.containerPatch(target, container, "imagePullPolicy", string(policy)),
+	}, true
+}
+
+// setImage changes a container's image; bare pods are patched directly since
+// the image is the one mutable field of a pod's containers
+func (p *fixPlanner) setImage(ctx context.Context, cause types.RootCause, container, image string) (types.RemediationAction, bool) {
+	target, ok := p.templateOwner(ctx)
+	if !ok {
+		return types.RemediationAction{}, false
+	}
+
+	spec, _ := findContainer(p.pod, container)
+	return types.RemediationAction{
+		Kind:        types.ActionSetImage,
+		Description: fmt.Sprintf("Change the image of container %s from %s to %s", container, spec.Image, image),
+		RootCause:   cause,
+		Target:      target,
+		PatchType:   types.PatchTypeStrategicMerge,
+		Patch:       p.containerPatch(target, container, "image", image),
+	}, true
+}
+
+// containerPatch builds a strategic-merge patch setting one field of a container
+func (p *fixPlanner) containerPatch(target types.ObjectReference, container, field, value string) string {
+	list := "containers"
+	for _, c := range p.pod.Spec.InitContainers {
+		if c.Name == container {
+			list = "initContainers"
+		}
+	}
+	return podSpecPatch(target, map[string]interface{}{
+		list: []map[string]string{{"name": container, field: value}},
+	})
+}
+
+// podSpecPatch wraps a pod spec fragment for the target: spec for a Pod,
+// spec.template.spec for a controller
+func podSpecPatch(target types.ObjectReference, podSpec map[string]interface{}) string {
+	doc := map[string]interface{}{"spec": podSpec}
+	if target.Kind != "Pod" {
+		doc = map[string]interface{}{"spec": map[string]interface{}{"template": doc}}
+	}
+	patch, _ := json.Marshal(doc)
+	return string(patch)
+}
+
+// findContainer returns a container or init container of the pod by name
+func findContainer(pod *corev1.Pod, name string) (corev1.Container, bool) {
+	for _, c := range pod.Spec.InitContainers {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return corev1.Container{}, false
+}
+
+// normalizeImage fixes the common ways an image reference is malformed:
+// surrounding whitespace, a URL scheme, upper case in the repository and an
+// empty tag. The tag and digest are kept as written.
+func normalizeImage(image string) string {
+	name := strings.TrimSpace(image)
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "https://"), "http://")
+
+	suffix := ""
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, suffix = name[:i], name[i:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, suffix = name[:i], name[i:]+suffix
+	}
+	if strings.HasPrefix(suffix, ":") && (len(suffix) == 1 || suffix[1] == '@') {
+		suffix = suffix[1:]
+	}
+
+	return strings.ToLower(name) + suffix
+}
+
+// ExecuteAction validates an action with a server-side dry-run, diffs the
+// object before and after, and persists the patch only when apply is set
+// Every patch, dry-run or not, is recorded in the audit log.
+func (a *Analyzer) ExecuteAction(ctx context.Context, action types.RemediationAction, apply bool) types.ActionResult {
+	result := types.ActionResult{Action: action}
+	target := action.Target
+	resource := k8s.ResourceName(target.Kind)
+
+	a.auditLogger.LogObjectGet(resource, target.Name, target.Namespace)
+	current, err := a.k8sClient.GetObject(ctx, target)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	before, err := k8s.ObjectYAML(current)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to render %s %s: %v", target.Kind, target.Name, err)
+		return result
+	}
+
+	a.auditLogger.LogPatch(resource, target.Name, target.Namespace, true)
+	patched, err := a.k8sClient.PatchObject(ctx, target, action.PatchType, []byte(action.Patch), true)
+	if err != nil {
+		result.Error = fmt.Sprintf("server-side dry-run rejected the change: %v", err)
+		return result
+	}
+	result.Validated = true
+
+	after, err := k8s.ObjectYAML(patched)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to render %s %s: %v", target.Kind, target.Name, err)
+		return result
+	}
+	name := fmt.Sprintf("%s/%s", strings.ToLower(target.Kind), target.Name)
+	result.Diff = output.UnifiedDiff(before, after, name+" (live)", name+" (patched)")
+
+	if !apply {
+		return result
+	}
+
+	a.auditLogger.LogPatch(resource, target.Name, target.Namespace, false)
+	if _, err := a.k8sClient.PatchObject(ctx, target, action.PatchType, []byte(action.Patch), false); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Applied = true
+	return result
+}
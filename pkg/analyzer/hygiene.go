@@ -0,0 +1,331 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/types"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ImagePolicy is the organization's image hygiene policy checked by k8t audit images
+// Checks left unset in the file are enabled; allowedRegistries and
+// digestNamespaces are only checked when listed.
+type ImagePolicy struct {
+	AllowedRegistries   []string                           `yaml:"allowedRegistries"`   // Registry hosts or host/path prefixes
+	DisallowLatest      *bool                              `yaml:"disallowLatest"`      // No :latest or tagless images
+	DigestNamespaces    []string                           `yaml:"digestNamespaces"`    // Namespaces (glob patterns) requiring digest pinning
+	PullPolicy          *bool                              `yaml:"pullPolicy"`          // imagePullPolicy consistent with tag mutability
+	DockerHubPullSecret *bool                              `yaml:"dockerHubPullSecret"` // Docker Hub images need a pull secret
+	ExcludeNamespaces   []string                           `yaml:"excludeNamespaces"`   // Namespaces (glob patterns) not audited
+	Severities          map[types.RootCause]types.Severity `yaml:"severities"`          // Severity overrides by rule
+}
+
+// hygieneRules are the rules of the policy; their default severity is RootCause.Severity
+var hygieneRules = map[types.RootCause]bool{
+	types.HygieneInvalidReference:   true,
+	types.HygieneDisallowedRegistry: true,
+	types.HygieneDigestRequired:     true,
+	types.HygieneLatestTag:          true,
+	types.HygienePullPolicyMismatch: true,
+	types.HygieneDockerHubNoSecret:  true,
+}
+
+// hygieneViolation is a rule a container's image violates
+type hygieneViolation struct {
+	rule        types.RootCause
+	message     string
+	remediation []string
+}
+
+// imagePolicyFile is the layout of a policy file, or of the k8t config file
+type imagePolicyFile struct {
+	ImagePolicy *ImagePolicy `yaml:"imagePolicy"`
+}
+
+// DefaultImagePolicy returns the policy used without a policy file: every
+// check that needs no organization-specific list
+func DefaultImagePolicy() *ImagePolicy {
+	policy := &ImagePolicy{}
+	policy.applyDefaults()
+	return policy
+}
+
+// LoadImagePolicy reads an image policy file
+// The file may be a dedicated policy file or the k8t config file; only the
+// top-level imagePolicy key is read.
+func LoadImagePolicy(path string) (*ImagePolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image policy: %w", err)
+	}
+
+	policy, err := ParseImagePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// ParseImagePolicy parses and validates an image policy from YAML
+// A document without an imagePolicy key yields the default policy.
+func ParseImagePolicy(data []byte) (*ImagePolicy, error) {
+	file := &imagePolicyFile{}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, err
+	}
+	policy := file.ImagePolicy
+	if policy == nil {
+		policy = &ImagePolicy{}
+	}
+
+	for i, registry := range policy.AllowedRegistries {
+		policy.AllowedRegistries[i] = normalizeMirrorPrefix(registry)
+		if policy.AllowedRegistries[i] == "" {
+			return nil, fmt.Errorf("allowedRegistries %d: empty registry", i+1)
+		}
+	}
+	for _, pattern := range append(append([]string{}, policy.DigestNamespaces...), policy.ExcludeNamespaces...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid namespace pattern %q: %w", pattern, err)
+		}
+	}
+	for rule, severity := range policy.Severities {
+		if !hygieneRules[rule] {
+			return nil, fmt.Errorf("severities: unknown rule %s", rule)
+		}
+		switch severity {
+		case types.SeverityHigh, types.SeverityMedium, types.SeverityLow:
+		default:
+			return nil, fmt.Errorf("severities: %s must be HIGH, MEDIUM or LOW, got %q", rule, severity)
+		}
+	}
+
+	policy.applyDefaults()
+	return policy, nil
+}
+
+// applyDefaults enables the checks the policy leaves unset
+func (p *ImagePolicy) applyDefaults() {
+	enabled := true
+	for _, check := range []**bool{&p.DisallowLatest, &p.PullPolicy, &p.DockerHubPullSecret} {
+		if *check == nil {
+			*check = &enabled
+		}
+	}
+}
+
+// AuditImages checks the images of every pod against the policy
+// Findings are workload-scoped, one per workload, container and rule, most
+// severe first. Docker Hub images pulled through a configured mirror are not
+// subject to its rate limits and need no pull secret.
+func (a *Analyzer) AuditImages(pods []corev1.Pod, policy *ImagePolicy) []types.DiagnosticFinding {
+	if policy == nil {
+		policy = DefaultImagePolicy()
+	}
+
+	type group struct {
+		pod       *corev1.Pod
+		workload  types.AffectedWorkload
+		container corev1.Container
+		violation hygieneViolation
+		pods      int
+	}
+	byKey := make(map[string]*group)
+	var groups []*group
+	for i := range pods {
+		pod := &pods[i]
+		if matchesNamespace(policy.ExcludeNamespaces, pod.Namespace) {
+			continue
+		}
+		workload := podWorkload(pod)
+
+		for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+			for _, violation := range a.checkImage(pod, container, policy) {
+				key := strings.Join([]string{workload.Namespace, workload.Kind, workload.Name, container.Name, string(violation.rule)}, "\x00")
+				if g, ok := byKey[key]; ok {
+					g.pods++
+					continue
+				}
+				g := &group{pod: pod, workload: workload, container: container, violation: violation, pods: 1}
+				byKey[key] = g
+				groups = append(groups, g)
+			}
+		}
+	}
+
+	findings := make([]types.DiagnosticFinding, 0, len(groups))
+	for _, g := range groups {
+		findings = append(findings, hygieneFinding(g.pod, g.workload, g.container, g.violation, g.pods, policy))
+	}
+
+	rank := map[types.Severity]int{types.SeverityHigh: 0, types.SeverityMedium: 1, types.SeverityLow: 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity != b.Severity {
+			return rank[a.Severity] < rank[b.Severity]
+		}
+		if a.PodNamespace != b.PodNamespace {
+			return a.PodNamespace < b.PodNamespace
+		}
+		if a.Subject != b.Subject {
+			return a.Subject < b.Subject
+		}
+		if a.AffectedContainers[0] != b.AffectedContainers[0] {
+			return a.AffectedContainers[0] < b.AffectedContainers[0]
+		}
+		return a.RootCause < b.RootCause
+	})
+	return findings
+}
+
+// hygieneFinding creates the workload-scoped finding of a rule violated by the pods of a workload
+func hygieneFinding(pod *corev1.Pod, workload types.AffectedWorkload, container corev1.Container, violation hygieneViolation, pods int, policy *ImagePolicy) types.DiagnosticFinding {
+	severity := violation.rule.Severity()
+	if override, ok := policy.Severities[violation.rule]; ok {
+		severity = override
+	}
+	ref := types.ImageReference{ContainerName: container.Name, FullReference: container.Image}
+	if parsed, err := types.ParseImageReference(container.Name, container.Image); err == nil {
+		ref = *parsed
+	} else {
+		ref.ParseError = err.Error()
+	}
+	return types.DiagnosticFinding{
+		RootCause:          violation.rule,
+		Severity:           severity,
+		Scope:              types.FindingScopeWorkload,
+		Subject:            workload.Kind + "/" + workload.Name,
+		PodName:            pod.Name,
+		PodNamespace:       workload.Namespace,
+		AffectedContainers: []string{container.Name},
+		Summary:            fmt.Sprintf("%s: %s", string(violation.rule), violation.message),
+		Details: fmt.Sprintf("Image policy rule %s matches %d pods of %s %s/%s",
+			string(violation.rule), pods, workload.Kind, workload.Namespace, workload.Name),
+		RemediationSteps: violation.remediation,
+		ImageReferences:  []types.ImageReference{ref},
+	}
+}
+
+// checkImage returns the rules a container's image violates
+func (a *Analyzer) checkImage(pod *corev1.Pod, container corev1.Container, policy *ImagePolicy) []hygieneViolation {
+	ref, err := types.ParseImageReference(container.Name, container.Image)
+	if err != nil {
+		return []hygieneViolation{{
+			rule:        types.HygieneInvalidReference,
+			message:     err.Error(),
+			remediation: []string{"Fix the image reference; kubelet rejects it with InvalidImageName"},
+		}}
+	}
+
+	var violations []hygieneViolation
+	name := ref.Registry + "/" + ref.Repository
+
+	if len(policy.AllowedRegistries) > 0 && !allowedRegistry(policy.AllowedRegistries, name) {
+		violations = append(violations, hygieneViolation{
+			rule:    types.HygieneDisallowedRegistry,
+			message: fmt.Sprintf("%s is not in an allowed registry", name),
+			remediation: []string{
+				fmt.Sprintf("Copy the image to an allowed registry (%s) and reference it from there", strings.Join(policy.AllowedRegistries, ", ")),
+				fmt.Sprintf("e.g. crane copy %s <allowed-registry>/%s", container.Image, ref.Repository),
+			},
+		})
+	}
+
+	if *policy.DisallowLatest && ref.Tag == "latest" && !ref.IsDigest {
+		message := fmt.Sprintf("%s uses the :latest tag", container.Image)
+		if !strings.HasSuffix(container.Image, ":latest") {
+			message = fmt.Sprintf("%s has no tag and defaults to :latest", container.Image)
+		}
+		violations = append(violations, hygieneViolation{
+			rule:    types.HygieneLatestTag,
+			message: message,
+			remediation: []string{
+				"Reference a versioned tag, or pin by digest: replicas started at different times may otherwise run different images",
+				fmt.Sprintf("Find the digest in use: k8t check drift -n %s", pod.Namespace),
+			},
+		})
+	}
+
+	if !ref.IsDigest && matchesNamespace(policy.DigestNamespaces, pod.Namespace) {
+		violations = append(violations, hygieneViolation{
+			rule:    types.HygieneDigestRequired,
+			message: fmt.Sprintf("Namespace %s requires images pinned by digest; %s is referenced by tag", pod.Namespace, container.Image),
+			remediation: []string{
+				fmt.Sprintf("Pin the image: %s@sha256:<digest> (crane digest %s)", container.Image, container.Image),
+				fmt.Sprintf("k8t check drift -n %s proposes the patch with the digest the replicas run", pod.Namespace),
+			},
+		})
+	}
+
+	if *policy.PullPolicy {
+		if violation, ok := pullPolicyViolation(container, ref); ok {
+			violations = append(violations, violation)
+		}
+	}
+
+	if *policy.DockerHubPullSecret && ref.Registry == types.DefaultRegistry && len(pod.Spec.ImagePullSecrets) == 0 {
+		endpoint := a.mirrors.Resolve(*ref)
+		if len(endpoint) == 0 || !endpoint[0].Mirror {
+			violations = append(violations, hygieneViolation{
+				rule:    types.HygieneDockerHubNoSecret,
+				message: fmt.Sprintf("%s is pulled anonymously from Docker Hub, which rate-limits anonymous pulls per IP address", container.Image),
+				remediation: []string{
+					"Reference a Docker Hub pull secret from the pod's ServiceAccount: kubectl patch serviceaccount <name> -p '{\"imagePullSecrets\":[{\"name\":\"<secret>\"}]}'",
+					"Or pull Docker Hub images through a mirror or pull-through cache (see --mirrors)",
+				},
+			})
+		}
+	}
+	return violations
+}
+
+// pullPolicyViolation reports an imagePullPolicy inconsistent with the mutability of the reference
+func pullPolicyViolation(container corev1.Container, ref *types.ImageReference) (hygieneViolation, bool) {
+	policy := EffectivePullPolicy(container)
+	switch {
+	case policy == corev1.PullNever:
+		return hygieneViolation{
+			rule:        types.HygienePullPolicyMismatch,
+			message:     fmt.Sprintf("imagePullPolicy Never: pods fail with ErrImageNeverPull on every node not preloaded with %s", container.Image),
+			remediation: []string{"Use IfNotPresent unless the image is preloaded on every node by other means"},
+		}, true
+	case ref.IsDigest && policy == corev1.PullAlways:
+		return hygieneViolation{
+			rule:        types.HygienePullPolicyMismatch,
+			message:     "imagePullPolicy Always for an image pinned by digest: every pod start needs the registry for an image that cannot change",
+			remediation: []string{"Use IfNotPresent: a digest always resolves to the same image"},
+		}, true
+	case !ref.IsDigest && ref.Tag == "latest" && policy == corev1.PullIfNotPresent:
+		return hygieneViolation{
+			rule:        types.HygienePullPolicyMismatch,
+			message:     fmt.Sprintf("imagePullPolicy IfNotPresent for the mutable tag of %s: each node keeps whichever image it pulled first", container.Image),
+			remediation: []string{"Pin the image by digest, or use imagePullPolicy Always for :latest"},
+		}, true
+	}
+	return hygieneViolation{}, false
+}
+
+// allowedRegistry reports whether registry/repository is under one of the allowed prefixes
+func allowedRegistry(allowed []string, name string) bool {
+	for _, prefix := range allowed {
+		if name == prefix || strings.HasPrefix(name, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNamespace reports whether a namespace matches one of the glob patterns
+func matchesNamespace(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
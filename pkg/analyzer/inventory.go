@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// BuildImageInventory lists every image reference used by the pods, including
+// init and ephemeral containers, with the workloads, pods and pull secrets using it
+// Entries are keyed by the reference as written, so nginx and docker.io/library/nginx
+// are listed separately; they share registry and repository.
+func BuildImageInventory(pods []corev1.Pod) []types.ImageInventoryEntry {
+	entries := make(map[string]*types.ImageInventoryEntry)
+	imageIDs := make(map[string]map[string]bool)
+	workloads := make(map[string]map[types.AffectedWorkload]int)
+	secrets := make(map[string]map[string]bool)
+
+	for i := range pods {
+		pod := &pods[i]
+		workload := podWorkload(pod)
+
+		statusIDs := make(map[string]string)
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range append(statuses, pod.Status.EphemeralContainerStatuses...) {
+			statusIDs[status.Name] = status.ImageID
+		}
+
+		counted := make(map[string]bool)
+		for _, ref := range append(k8s.GetContainerImages(pod), k8s.GetEphemeralContainerImages(pod)...) {
+			image := ref.FullReference
+			entry, ok := entries[image]
+			if !ok {
+				entry = &types.ImageInventoryEntry{
+					Image:      image,
+					Registry:   ref.Registry,
+					Repository: ref.Repository,
+					Tag:        ref.Tag,
+					Digest:     ref.Digest,
+					ParseError: ref.ParseError,
+				}
+				entries[image] = entry
+				imageIDs[image] = make(map[string]bool)
+				workloads[image] = make(map[types.AffectedWorkload]int)
+				secrets[image] = make(map[string]bool)
+			}
+			if id := statusIDs[ref.ContainerName]; id != "" {
+				imageIDs[image][id] = true
+			}
+
+			// A pod running the image in several containers counts once
+			if counted[image] {
+				continue
+			}
+			counted[image] = true
+			entry.Pods++
+			workloads[image][workload]++
+			for _, secret := range pod.Spec.ImagePullSecrets {
+				secrets[image][pod.Namespace+"/"+secret.Name] = true
+			}
+		}
+	}
+
+	inventory := make([]types.ImageInventoryEntry, 0, len(entries))
+	for image, entry := range entries {
+		for id := range imageIDs[image] {
+			entry.ImageIDs = append(entry.ImageIDs, id)
+		}
+		sort.Strings(entry.ImageIDs)
+		for secret := range secrets[image] {
+			entry.PullSecrets = append(entry.PullSecrets, secret)
+		}
+		sort.Strings(entry.PullSecrets)
+		for workload, count := range workloads[image] {
+			workload.Pods = count
+			entry.Workloads = append(entry.Workloads, workload)
+		}
+		sort.Slice(entry.Workloads, func(i, j int) bool {
+			a, b := entry.Workloads[i], entry.Workloads[j]
+			if a.Namespace != b.Namespace {
+				return a.Namespace < b.Namespace
+			}
+			if a.Kind != b.Kind {
+				return a.Kind < b.Kind
+			}
+			return a.Name < b.Name
+		})
+		inventory = append(inventory, *entry)
+	}
+	sort.Slice(inventory, func(i, j int) bool {
+		a, b := inventory[i], inventory[j]
+		if a.Registry != b.Registry {
+			return a.Registry < b.Registry
+		}
+		if a.Repository != b.Repository {
+			return a.Repository < b.Repository
+		}
+		return a.Image < b.Image
+	})
+	return inventory
+}
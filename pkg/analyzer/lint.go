@@ -0,0 +1,326 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// LintOptions controls the checks of LintManifests
+type LintOptions struct {
+	Probe       bool // Ask each registry whether the tag exists
+	Offline     bool // Skip the cluster lookups of pull secrets and service accounts
+	Concurrency int  // Registry probes run in parallel
+}
+
+// lintCredentials are the pull secrets a pod template would be admitted with
+type lintCredentials struct {
+	serviceAccount string
+	registries     map[string]bool // Registries the existing pull secrets have credentials for
+	known          bool            // False when a secret could not be looked up (offline)
+	missing        []string        // Referenced secrets that do not exist
+	unchecked      []string        // Referenced secrets neither in the manifests nor looked up (offline)
+}
+
+// lintProbe is the answer of a registry for one image
+type lintProbe struct {
+	exists bool
+	err    error
+}
+
+// LintManifests predicts the image pull failures of the pod templates in manifests,
+// before they are applied. Pull secrets and ServiceAccounts are looked up in the
+// manifests first, then in the cluster unless offline.
+func (a *Analyzer) LintManifests(ctx context.Context, manifests *k8s.Manifests, opts LintOptions) []types.LintFinding {
+	l := &linter{
+		a:          a,
+		manifests:  manifests,
+		opts:       opts,
+		secrets:    make(map[string]map[string]*corev1.Secret),
+		secretErrs: make(map[string]error),
+		accounts:   make(map[string]clusterServiceAccount),
+	}
+
+	var findings []types.LintFinding
+	refsByTemplate := make([][]*types.ImageReference, len(manifests.Templates))
+	for i := range manifests.Templates {
+		tmpl := &manifests.Templates[i]
+		for _, container := range append(append([]corev1.Container{}, tmpl.Spec.InitContainers...), tmpl.Spec.Containers...) {
+			ref, err := types.ParseImageReference(container.Name, container.Image)
+			if err != nil {
+				findings = append(findings, lintFinding(tmpl, types.RootCauseInvalidImageName, container.Name, container.Image,
+					err.Error(), "Fix the image reference; kubelet rejects it with InvalidImageName"))
+				continue
+			}
+			ref.PullEndpoints = a.mirrors.Resolve(*ref)
+			refsByTemplate[i] = append(refsByTemplate[i], ref)
+		}
+	}
+
+	var probes map[string]lintProbe
+	if opts.Probe {
+		probes = l.probe(ctx, refsByTemplate)
+	}
+
+	for i := range manifests.Templates {
+		tmpl := &manifests.Templates[i]
+		creds := l.credentials(ctx, tmpl)
+
+		// Kubelet pulls public images without a missing secret: only a registry
+		// known to require credentials makes it fail the deploy
+		var private []string
+		seen := make(map[string]bool)
+		for _, ref := range refsByTemplate[i] {
+			probe, ok := probes[ref.FullReference]
+			if ok && errors.Is(probe.err, errCredentialsRequired) && !creds.registries[ref.Registry] && !seen[ref.Registry] {
+				seen[ref.Registry] = true
+				private = append(private, ref.Registry)
+			}
+		}
+		for _, name := range creds.missing {
+			message := fmt.Sprintf("imagePullSecret %s does not exist in namespace %s; kubelet pulls without it", name, tmpl.Namespace)
+			if len(private) > 0 {
+				message += fmt.Sprintf(", and %s requires credentials", strings.Join(private, ", "))
+			}
+			finding := lintFinding(tmpl, types.RootCauseAuthFailure, "", "", message,
+				fmt.Sprintf("Create the secret before deploying: kubectl create secret docker-registry %s -n %s --docker-server=<registry> --docker-username=<user> --docker-password=<token>", name, tmpl.Namespace),
+				"Or add the Secret to the manifests applied with the workload")
+			if len(private) == 0 {
+				finding.Severity = types.SeverityMedium
+			}
+			findings = append(findings, finding)
+		}
+		for _, name := range creds.unchecked {
+			finding := lintFinding(tmpl, types.RootCauseAuthFailure, "", "",
+				fmt.Sprintf("pull secret %s not defined in the manifests; not checked offline", name),
+				fmt.Sprintf("Run without --offline to check that secret %s exists in namespace %s", name, tmpl.Namespace),
+				"Or add the Secret to the manifests applied with the workload")
+			finding.Severity = types.SeverityLow
+			findings = append(findings, finding)
+		}
+
+		for _, ref := range refsByTemplate[i] {
+			probe, ok := probes[ref.FullReference]
+			if !ok {
+				continue
+			}
+			if finding, ok := lintProbeFinding(tmpl, ref, probe, creds); ok {
+				findings = append(findings, finding)
+			}
+		}
+	}
+
+	rank := map[types.Severity]int{types.SeverityHigh: 0, types.SeverityMedium: 1, types.SeverityLow: 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return rank[findings[i].Severity] < rank[findings[j].Severity]
+	})
+	return findings
+}
+
+// linter holds the lookups shared by the templates of a lint run
+type linter struct {
+	a          *Analyzer
+	manifests  *k8s.Manifests
+	opts       LintOptions
+	secrets    map[string]map[string]*corev1.Secret // Cluster pull secrets by namespace and name
+	secretErrs map[string]error
+	accounts   map[string]clusterServiceAccount // Cluster ServiceAccounts by namespace/name
+}
+
+// clusterServiceAccount caches the lookup of a ServiceAccount in the cluster
+type clusterServiceAccount struct {
+	sa    *corev1.ServiceAccount
+	known bool
+}
+
+// probe asks the registries for each distinct image, in parallel
+func (l *linter) probe(ctx context.Context, refsByTemplate [][]*types.ImageReference) map[string]lintProbe {
+	var unique []*types.ImageReference
+	seen := make(map[string]bool)
+	for _, refs := range refsByTemplate {
+		for _, ref := range refs {
+			if !seen[ref.FullReference] {
+				seen[ref.FullReference] = true
+				unique = append(unique, ref)
+			}
+		}
+	}
+
+	// Images left unprobed when the context expires are not reported
+	results := make([]*lintProbe, len(unique))
+	_ = RunConcurrently(ctx, l.opts.Concurrency, len(unique), func(ctx context.Context, i int) {
+		exists, err := CheckManifest(ctx, *unique[i], l.a.insecureRegistry(*unique[i]))
+		results[i] = &lintProbe{exists: exists, err: err}
+	}, nil)
+
+	probes := make(map[string]lintProbe, len(unique))
+	for i, ref := range unique {
+		if results[i] != nil {
+			probes[ref.FullReference] = *results[i]
+		}
+	}
+	return probes
+}
+
+// credentials resolves the pull secrets of a template and of its ServiceAccount
+func (l *linter) credentials(ctx context.Context, tmpl *k8s.ManifestTemplate) lintCredentials {
+	creds := lintCredentials{serviceAccount: tmpl.Spec.ServiceAccountName, registries: make(map[string]bool), known: true}
+	if creds.serviceAccount == "" {
+		creds.serviceAccount = "default"
+	}
+
+	names := make([]string, 0, len(tmpl.Spec.ImagePullSecrets))
+	for _, ref := range tmpl.Spec.ImagePullSecrets {
+		names = append(names, ref.Name)
+	}
+	sa, known := l.serviceAccount(ctx, tmpl.Namespace, creds.serviceAccount)
+	creds.known = known
+	if sa != nil {
+		for _, ref := range sa.ImagePullSecrets {
+			names = append(names, ref.Name)
+		}
+	}
+
+	for _, name := range names {
+		secret, found, known := l.secret(ctx, tmpl.Namespace, name)
+		switch {
+		case secret != nil:
+			for _, registry := range k8s.PullSecretRegistries(secret) {
+				creds.registries[registry] = true
+			}
+		case !known:
+			creds.known = false
+			if l.opts.Offline {
+				creds.unchecked = append(creds.unchecked, name)
+			}
+		case !found:
+			creds.missing = append(creds.missing, name)
+		}
+	}
+	return creds
+}
+
+// serviceAccount looks a ServiceAccount up in the manifests, then in the cluster
+// known is false when it could not be looked up
+func (l *linter) serviceAccount(ctx context.Context, namespace, name string) (*corev1.ServiceAccount, bool) {
+	for i := range l.manifests.ServiceAccounts {
+		sa := &l.manifests.ServiceAccounts[i]
+		if sa.Namespace == namespace && sa.Name == name {
+			return sa, true
+		}
+	}
+	if l.opts.Offline {
+		return nil, false
+	}
+
+	key := namespace + "/" + name
+	if cached, ok := l.accounts[key]; ok {
+		return cached.sa, cached.known
+	}
+	l.a.auditLogger.LogServiceAccountGet(name, namespace)
+	sa, err := l.a.k8sClient.GetServiceAccount(ctx, namespace, name)
+	var lookup clusterServiceAccount
+	switch {
+	case err == nil:
+		lookup = clusterServiceAccount{sa: sa, known: true}
+	case k8serrors.IsNotFound(err):
+		// Admission rejects pods of a missing ServiceAccount; that is not a pull failure
+		lookup = clusterServiceAccount{known: true}
+	default:
+		l.a.auditLogger.LogWarning(fmt.Sprintf("Cannot read service account %s: %v", key, err))
+	}
+	l.accounts[key] = lookup
+	return lookup.sa, lookup.known
+}
+
+// secret looks a pull secret up in the manifests, then in the cluster
+// known is false when it could not be looked up
+func (l *linter) secret(ctx context.Context, namespace, name string) (secret *corev1.Secret, found, known bool) {
+	for i := range l.manifests.Secrets {
+		s := &l.manifests.Secrets[i]
+		if s.Namespace == namespace && s.Name == name {
+			return s, true, true
+		}
+	}
+	if l.opts.Offline {
+		return nil, false, false
+	}
+
+	byName, listed := l.secrets[namespace]
+	if !listed && l.secretErrs[namespace] == nil {
+		l.a.auditLogger.LogSecretList(namespace)
+		secrets, err := l.a.k8sClient.ListPullSecrets(ctx, namespace)
+		if err != nil {
+			l.a.auditLogger.LogWarning(fmt.Sprintf("Cannot list pull secrets: %v", err))
+			l.secretErrs[namespace] = err
+			return nil, false, false
+		}
+		byName = make(map[string]*corev1.Secret, len(secrets))
+		for i := range secrets {
+			byName[secrets[i].Name] = &secrets[i]
+		}
+		l.secrets[namespace] = byName
+	}
+	if l.secretErrs[namespace] != nil {
+		return nil, false, false
+	}
+	secret, found = byName[name]
+	return secret, found, true
+}
+
+// lintProbeFinding turns the registry's answer for an image into a finding
+func lintProbeFinding(tmpl *k8s.ManifestTemplate, ref *types.ImageReference, probe lintProbe, creds lintCredentials) (types.LintFinding, bool) {
+	switch {
+	case probe.err == nil && probe.exists:
+		return types.LintFinding{}, false
+	case probe.err == nil:
+		return lintFinding(tmpl, types.RootCauseImageNotFound, ref.ContainerName, ref.FullReference,
+			fmt.Sprintf("%s does not exist in %s", ref.FullReference, ref.Registry),
+			fmt.Sprintf("Check the tags of the repository: crane ls %s/%s", ref.Registry, ref.Repository),
+			"Fix the tag in the manifest, or in the values passed to helm or kustomize, or push the image before deploying"), true
+	case errors.Is(probe.err, errCredentialsRequired):
+		if creds.registries[ref.Registry] {
+			// A pull secret has credentials; k8t never reads them to check the tag
+			return types.LintFinding{}, false
+		}
+		if !creds.known {
+			finding := lintFinding(tmpl, types.RootCauseAuthFailure, ref.ContainerName, ref.FullReference,
+				fmt.Sprintf("%s requires credentials; the pull secrets could not be checked offline", ref.Registry),
+				fmt.Sprintf("Run without --offline to check for a pull secret with credentials for %s", ref.Registry))
+			finding.Severity = types.SeverityMedium
+			return finding, true
+		}
+		return lintFinding(tmpl, types.RootCauseAuthFailure, ref.ContainerName, ref.FullReference,
+			fmt.Sprintf("%s requires credentials and no pull secret of the pod or of service account %s has credentials for it", ref.Registry, creds.serviceAccount),
+			fmt.Sprintf("Reference a pull secret for %s in imagePullSecrets of the pod template, or of service account %s", ref.Registry, creds.serviceAccount)), true
+	default:
+		finding := lintFinding(tmpl, types.RootCauseNetworkIssue, ref.ContainerName, ref.FullReference,
+			fmt.Sprintf("Could not check %s: %v", ref.FullReference, probe.err),
+			"The registry was not reachable from this machine; nodes may still reach it")
+		finding.Severity = types.SeverityLow
+		return finding, true
+	}
+}
+
+// lintFinding creates a finding for a template with the root cause's severity
+func lintFinding(tmpl *k8s.ManifestTemplate, cause types.RootCause, container, image, message string, remediation ...string) types.LintFinding {
+	return types.LintFinding{
+		RootCause:   cause,
+		Severity:    cause.Severity(),
+		Source:      tmpl.Source,
+		Namespace:   tmpl.Namespace,
+		Kind:        tmpl.Kind,
+		Name:        tmpl.Name,
+		Container:   container,
+		Image:       image,
+		Message:     message,
+		Remediation: remediation,
+	}
+}
@@ -0,0 +1,197 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/types"
+)
+
+// manifestAccept lists the manifest media types container runtimes accept when pulling
+var manifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// errCredentialsRequired is returned when a registry refuses anonymous manifest reads
+var errCredentialsRequired = errors.New("registry requires credentials")
+
+// challengeParamRe captures the key="value" parameters of a WWW-Authenticate header
+var challengeParamRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// CheckManifest reports whether a registry still serves the manifest of an image
+// Public images are checked with an anonymous bearer token. Registries that
+// require credentials yield an error: k8t never reads pull secret values.
+// Like ProbeRegistry, the check runs from the machine executing k8t. When a
+// mirror is configured the mirror is asked, as the runtime would. The answer
+// decides admission and rollback advice, so the registry's certificate is
+// verified unless insecure is set.
+func CheckManifest(ctx context.Context, ref types.ImageReference, insecure bool) (bool, error) {
+	status, _, err := requestManifest(ctx, ref, insecure)
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, manifestStatusError(status, ref)
+	}
+}
+
+// ManifestDigest returns the digest a registry currently serves for an image's tag
+// Like CheckManifest, it asks anonymously and through the configured mirror.
+// The digest is the one runtimes record in a container's imageID: the image
+// index for multi-platform images. Users pin the digest, so the registry's
+// certificate is verified unless insecure is set.
+func ManifestDigest(ctx context.Context, ref types.ImageReference, insecure bool) (string, error) {
+	status, header, err := requestManifest(ctx, ref, insecure)
+	if err != nil {
+		return "", err
+	}
+	switch status {
+	case http.StatusOK:
+		digest := header.Get("Docker-Content-Digest")
+		if digest == "" {
+			return "", fmt.Errorf("registry did not return the manifest digest")
+		}
+		return digest, nil
+	case http.StatusNotFound:
+		return "", fmt.Errorf("registry has no manifest for %s", ref.FullReference)
+	default:
+		return "", manifestStatusError(status, ref)
+	}
+}
+
+// requestManifest sends an anonymous HEAD request for an image's manifest,
+// fetching a bearer token when the registry asks for one. insecure skips
+// certificate verification.
+func requestManifest(ctx context.Context, ref types.ImageReference, insecure bool) (int, http.Header, error) {
+	registry, repository, scheme := ref.Registry, ref.Repository, "https"
+	if endpoint := ref.EffectiveEndpoint(); endpoint != nil {
+		registry, repository = endpoint.Host, endpoint.Repository
+		if endpoint.PlainHTTP {
+			scheme = "http"
+		}
+	}
+	if registry == "docker.io" {
+		registry = "registry-1.docker.io"
+	}
+	reference := ref.Tag
+	if ref.IsDigest {
+		reference = ref.Digest
+	}
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, registry, repository, reference)
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+	}}
+
+	status, header, err := headManifest(ctx, client, manifestURL, "")
+	if err != nil {
+		return 0, nil, err
+	}
+	if status == http.StatusUnauthorized {
+		token, err := anonymousToken(ctx, client, header.Get("WWW-Authenticate"))
+		if err != nil {
+			return 0, nil, err
+		}
+		if status, header, err = headManifest(ctx, client, manifestURL, token); err != nil {
+			return 0, nil, err
+		}
+	}
+	return status, header, nil
+}
+
+// manifestStatusError describes a manifest response other than found or not found
+func manifestStatusError(status int, ref types.ImageReference) error {
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		repository := ref.Repository
+		if endpoint := ref.EffectiveEndpoint(); endpoint != nil {
+			repository = endpoint.Repository
+		}
+		return fmt.Errorf("%w to read %s", errCredentialsRequired, repository)
+	}
+	return fmt.Errorf("registry returned HTTP %d for the manifest", status)
+}
+
+// headManifest sends a HEAD request for a manifest and returns the status and response headers
+func headManifest(ctx context.Context, client *http.Client, manifestURL, token string) (int, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Accept", manifestAccept)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, resp.Header, nil
+}
+
+// anonymousToken requests a pull token from the realm named in a Bearer challenge
+func anonymousToken(ctx context.Context, client *http.Client, challenge string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", fmt.Errorf("%w (%s)", errCredentialsRequired, strings.SplitN(challenge, " ", 2)[0])
+	}
+
+	params := make(map[string]string)
+	for _, m := range challengeParamRe.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid token realm %q", params["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w (token endpoint returned HTTP %d)", errCredentialsRequired, resp.StatusCode)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
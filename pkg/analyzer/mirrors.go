@@ -0,0 +1,222 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// MirrorConfig describes the registry rewrites configured on the nodes
+// It mirrors containerd hosts.toml ([host] entries under certs.d/<registry>)
+// and CRI-O registries.conf ([[registry]] prefix/location and
+// [[registry.mirror]]) in a runtime-independent form.
+type MirrorConfig struct {
+	Mirrors []Mirror `yaml:"mirrors"`
+}
+
+// Mirror rewrites the images under a registry or repository prefix to mirror endpoints
+// The matched prefix is replaced by each endpoint: with registry docker.io and
+// endpoint mirror.example.com/dockerhub, docker.io/library/nginx is pulled as
+// mirror.example.com/dockerhub/library/nginx. Endpoints are tried in order,
+// then the upstream registry unless SkipUpstream is set.
+type Mirror struct {
+	Registry     string   `yaml:"registry"`     // Registry host, optionally followed by a repository path prefix
+	Endpoints    []string `yaml:"endpoints"`    // host[:port][/path], with http:// for plain HTTP
+	SkipUpstream bool     `yaml:"skipUpstream"` // containerd hosts.toml without server; CRI-O blocked upstream
+}
+
+// LoadMirrors reads a mirror configuration file
+// The file may be a dedicated file or the k8t config file; only the top-level
+// mirrors key is read.
+func LoadMirrors(path string) (*MirrorConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror configuration: %w", err)
+	}
+
+	config, err := ParseMirrors(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mirror configuration %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseMirrors parses and validates a mirror configuration from YAML
+func ParseMirrors(data []byte) (*MirrorConfig, error) {
+	config := &MirrorConfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(config.Mirrors))
+	for i := range config.Mirrors {
+		m := &config.Mirrors[i]
+		m.Registry = normalizeMirrorPrefix(m.Registry)
+		if m.Registry == "" {
+			return nil, fmt.Errorf("mirror %d: registry is required", i+1)
+		}
+		if seen[m.Registry] {
+			return nil, fmt.Errorf("mirror %d: duplicate registry %s", i+1, m.Registry)
+		}
+		seen[m.Registry] = true
+		if len(m.Endpoints) == 0 {
+			return nil, fmt.Errorf("mirror %d (%s): at least one endpoint is required", i+1, m.Registry)
+		}
+		for j, endpoint := range m.Endpoints {
+			if _, err := parseMirrorEndpoint(endpoint); err != nil {
+				return nil, fmt.Errorf("mirror %d (%s): endpoint %d: %w", i+1, m.Registry, j+1, err)
+			}
+		}
+	}
+
+	// Longest prefix first, so docker.io/library wins over docker.io
+	sort.SliceStable(config.Mirrors, func(i, j int) bool {
+		return len(config.Mirrors[i].Registry) > len(config.Mirrors[j].Registry)
+	})
+	return config, nil
+}
+
+// Resolve returns the endpoints an image is pulled from, in the order the
+// runtime tries them, or nil when no mirror applies
+// The receiver may be nil (no mirrors configured).
+func (mc *MirrorConfig) Resolve(ref types.ImageReference) []types.RegistryEndpoint {
+	if mc == nil || ref.ParseError != "" {
+		return nil
+	}
+
+	name := ref.Registry + "/" + ref.Repository
+	for _, m := range mc.Mirrors {
+		if name != m.Registry && !strings.HasPrefix(name, m.Registry+"/") {
+			continue
+		}
+		remainder := strings.TrimPrefix(strings.TrimPrefix(name, m.Registry), "/")
+
+		endpoints := make([]types.RegistryEndpoint, 0, len(m.Endpoints)+1)
+		for _, raw := range m.Endpoints {
+			endpoint, _ := parseMirrorEndpoint(raw)
+			endpoint.Repository = strings.TrimPrefix(endpoint.Repository+"/"+remainder, "/")
+			endpoints = append(endpoints, endpoint)
+		}
+		if !m.SkipUpstream {
+			endpoints = append(endpoints, types.RegistryEndpoint{Host: ref.Registry, Repository: ref.Repository})
+		}
+		return endpoints
+	}
+	return nil
+}
+
+// parseMirrorEndpoint parses host[:port][/path] with an optional http(s):// scheme
+// The path becomes the repository prefix on the mirror.
+func parseMirrorEndpoint(raw string) (types.RegistryEndpoint, error) {
+	endpoint := types.RegistryEndpoint{Mirror: true}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return endpoint, err
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		endpoint.PlainHTTP = true
+	default:
+		return endpoint, fmt.Errorf("unsupported scheme %s (use http or https)", u.Scheme)
+	}
+	if u.Host == "" {
+		return endpoint, fmt.Errorf("missing host in %q", raw)
+	}
+
+	endpoint.Host = u.Host
+	// containerd mirror URLs carry the API path (https://mirror/v2/dockerhub); it is not part of the repository
+	path := strings.Trim(u.Path, "/")
+	if path == "v2" || strings.HasPrefix(path, "v2/") {
+		path = strings.TrimPrefix(path[len("v2"):], "/")
+	}
+	endpoint.Repository = path
+	return endpoint, nil
+}
+
+// normalizeMirrorPrefix applies Docker Hub normalization to a mirror registry prefix
+func normalizeMirrorPrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "index.docker.io" || strings.HasPrefix(prefix, "index.docker.io/") {
+		prefix = types.DefaultRegistry + strings.TrimPrefix(prefix, "index.docker.io")
+	}
+	return prefix
+}
+
+// resolveMirrors records the effective pull endpoints of each image
+func (a *Analyzer) resolveMirrors(imageRefs []types.ImageReference) {
+	for i := range imageRefs {
+		imageRefs[i].PullEndpoints = a.mirrors.Resolve(imageRefs[i])
+	}
+}
+
+// probeEndpoint probes the host the runtime pulls an image from
+// With a mirror, the mirror is probed and the upstream registry recorded.
+func probeEndpoint(ctx context.Context, ref *types.ImageReference) *types.NetworkDiagnostics {
+	endpoint := ref.EffectiveEndpoint()
+	if endpoint == nil || !endpoint.Mirror {
+		return ProbeRegistry(ctx, ref.Registry)
+	}
+
+	host := endpoint.Host
+	if _, _, err := net.SplitHostPort(host); err != nil && endpoint.PlainHTTP {
+		host = net.JoinHostPort(host, "80")
+	}
+	diag := ProbeRegistry(ctx, host)
+	diag.MirrorOf = ref.Registry
+	return diag
+}
+
+// mirrorRemediation returns the mirror-specific steps that precede a root cause's own steps
+func mirrorRemediation(rootCause types.RootCause, ref *types.ImageReference) []string {
+	if ref == nil {
+		return nil
+	}
+	endpoint := ref.EffectiveEndpoint()
+	if endpoint == nil || !endpoint.Mirror {
+		return nil
+	}
+
+	scheme := "https"
+	if endpoint.PlainHTTP {
+		scheme = "http"
+	}
+	fallback := fmt.Sprintf("then %s if the mirror fails", ref.Registry)
+	if ref.PullEndpoints[len(ref.PullEndpoints)-1].Mirror {
+		fallback = fmt.Sprintf("with no fallback to %s", ref.Registry)
+	}
+	steps := []string{
+		fmt.Sprintf("Images from %s are pulled through the mirror %s (%s); check the mirror before the upstream registry", ref.Registry, endpoint.Host, fallback),
+	}
+	reference := ref.Tag
+	if ref.IsDigest {
+		reference = ref.Digest
+	}
+
+	switch rootCause {
+	case types.RootCauseNetworkIssue, types.RootCauseRegistryUnavailable:
+		steps = append(steps, fmt.Sprintf("Test the mirror from the node: curl -sI %s://%s/v2/", scheme, endpoint.Host))
+	case types.RootCauseTLSCertificate:
+		steps = append(steps, fmt.Sprintf("The certificate to trust is the mirror's (%s), not %s's", endpoint.Host, ref.Registry))
+	case types.RootCauseImageNotFound, types.RootCauseManifestError:
+		steps = append(steps,
+			fmt.Sprintf("Check that the mirror serves the image: curl -sI %s://%s/v2/%s/manifests/%s", scheme, endpoint.Host, endpoint.Repository, reference),
+			fmt.Sprintf("A pull-through mirror fetches on demand: verify it can reach %s and has not cached a stale or missing tag", ref.Registry))
+	case types.RootCauseAuthFailure, types.RootCausePermissionDenied:
+		steps = append(steps, fmt.Sprintf("The runtime sends the %s credentials to the mirror; check that %s accepts them or allows anonymous pulls", ref.Registry, endpoint.Host))
+	case types.RootCauseRateLimit:
+		steps = append(steps, fmt.Sprintf("Rate limits hit %s directly when the mirror is bypassed; check the mirror's health and its upstream credentials", ref.Registry))
+	}
+	return steps
+}
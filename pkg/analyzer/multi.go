@@ -0,0 +1,160 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ScanOptions configures analysis of multiple pods
+type ScanOptions struct {
+	Concurrency int                   // Maximum number of pods analyzed in parallel
+	Progress    func(done, total int) // Optional callback invoked as pods complete
+
+	// Sample analyzes one representative per group of pods sharing an owner,
+	// image set and waiting reasons, and projects its findings onto the rest
+	Sample bool
+}
+
+// PodResult holds the outcome of analyzing a single pod during a scan
+type PodResult struct {
+	Pod      *corev1.Pod
+	Report   *types.AnalysisReport
+	Err      error
+	Inferred bool // Report was projected from a representative pod (sampling)
+}
+
+// AnalyzePods analyzes the given pods concurrently using a bounded worker pool
+// Every pod is analyzed under the analyzer's per-pod timeout in addition to
+// any deadline carried by ctx. Results are ordered by namespace and pod name
+// regardless of completion order.
+func (a *Analyzer) AnalyzePods(ctx context.Context, pods []corev1.Pod, opts ScanOptions) ([]PodResult, error) {
+	// Nodes are listed under the scan's context, not the first worker's per-pod deadline
+	if len(pods) > 0 {
+		_, _ = a.listNodes(ctx)
+	}
+
+	if opts.Sample {
+		return a.analyzeSampled(ctx, pods, opts)
+	}
+
+	sorted := make([]corev1.Pod, len(pods))
+	copy(sorted, pods)
+	SortPods(sorted)
+
+	results := make([]PodResult, len(sorted))
+	err := RunConcurrently(ctx, opts.Concurrency, len(sorted), func(ctx context.Context, i int) {
+		pod := &sorted[i]
+		report, err := a.AnalyzePodObject(ctx, pod)
+		results[i] = PodResult{Pod: pod, Report: report, Err: err}
+	}, opts.Progress)
+
+	// Pods that were never dispatched because ctx expired still get a result
+	for i := range results {
+		if results[i].Pod == nil {
+			results[i] = PodResult{
+				Pod: &sorted[i],
+				Err: fmt.Errorf("analysis of pod '%s' skipped: %w", sorted[i].Name, err),
+			}
+		}
+	}
+
+	return results, err
+}
+
+// analyzeSampled deep-analyzes one representative per sampling group and
+// projects the representative's report onto the other members
+func (a *Analyzer) analyzeSampled(ctx context.Context, pods []corev1.Pod, opts ScanOptions) ([]PodResult, error) {
+	groups := GroupPodsForSampling(pods)
+
+	representatives := make([]corev1.Pod, len(groups))
+	for i, group := range groups {
+		representatives[i] = *group.Representative
+	}
+
+	// Representatives are already in sorted order, so results line up with groups
+	repResults, err := a.AnalyzePods(ctx, representatives, ScanOptions{
+		Concurrency: opts.Concurrency,
+		Progress:    opts.Progress,
+	})
+
+	results := make([]PodResult, 0, len(pods))
+	for i, group := range groups {
+		rep := repResults[i]
+		results = append(results, rep)
+		for _, member := range group.Members {
+			results = append(results, PodResult{
+				Pod:      member,
+				Report:   ProjectReport(rep.Report, rep.Pod, member),
+				Err:      rep.Err,
+				Inferred: true,
+			})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Pod.Namespace != results[j].Pod.Namespace {
+			return results[i].Pod.Namespace < results[j].Pod.Namespace
+		}
+		return results[i].Pod.Name < results[j].Pod.Name
+	})
+
+	return results, err
+}
+
+// SortPods orders pods by namespace, then by name
+func SortPods(pods []corev1.Pod) {
+	sort.SliceStable(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+}
+
+// MergeReports combines per-pod results into a single multi-pod report
+// Failed pod analyses are skipped; findings keep the order of results.
+func MergeReports(targetType types.TargetType, targetName, namespace string, results []PodResult) *types.AnalysisReport {
+	report := &types.AnalysisReport{
+		TargetType:  targetType,
+		TargetName:  targetName,
+		Namespace:   namespace,
+		GeneratedAt: time.Now(),
+		Findings:    []types.DiagnosticFinding{},
+		Summary: types.ReportSummary{
+			RootCauseBreakdown: make(map[types.RootCause]int),
+		},
+		AuditLog: []types.AuditEntry{},
+	}
+
+	for _, result := range results {
+		if result.Err != nil || result.Report == nil {
+			continue
+		}
+
+		summary := result.Report.Summary
+		if result.Inferred {
+			report.Summary.InferredPods += summary.TotalPodsAnalyzed
+		} else {
+			report.Summary.DirectlyAnalyzedPods += summary.TotalPodsAnalyzed
+		}
+		report.Summary.TotalPodsAnalyzed += summary.TotalPodsAnalyzed
+		report.Summary.PodsWithIssues += summary.PodsWithIssues
+		report.Summary.TotalContainers += summary.TotalContainers
+		report.Summary.ContainersWithIssues += summary.ContainersWithIssues
+		report.Summary.HighSeverityCount += summary.HighSeverityCount
+		report.Summary.MediumSeverityCount += summary.MediumSeverityCount
+		report.Summary.LowSeverityCount += summary.LowSeverityCount
+		for cause, count := range summary.RootCauseBreakdown {
+			report.Summary.RootCauseBreakdown[cause] += count
+		}
+
+		report.Findings = append(report.Findings, result.Report.Findings...)
+	}
+
+	return report
+}
@@ -0,0 +1,209 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/types"
+)
+
+// probeTimeout bounds each individual network check
+const probeTimeout = 5 * time.Second
+
+// ProbeRegistry runs DNS, TCP, TLS and HTTP checks against a registry host
+// The host may carry a port (registry.example.com:5000); 443 is assumed otherwise.
+// Checks run from the machine executing k8t, not from the node, so results
+// may differ when the node sits behind a different network path.
+func ProbeRegistry(ctx context.Context, registry string) *types.NetworkDiagnostics {
+	// Docker Hub images reference docker.io but are served from registry-1.docker.io
+	if registry == "docker.io" {
+		registry = "registry-1.docker.io"
+	}
+
+	host, port := splitRegistryHost(registry)
+	diag := &types.NetworkDiagnostics{RegistryHost: registry}
+
+	diag.DNSResolution = probeDNS(ctx, host)
+	if !diag.DNSResolution.Success {
+		return diag
+	}
+
+	diag.TCPConnection = probeTCP(ctx, host, port)
+	if !diag.TCPConnection.Success {
+		return diag
+	}
+
+	diag.TLSCheck = InspectRegistryCertificate(ctx, net.JoinHostPort(host, strconv.Itoa(port)), nil)
+	diag.HTTPCheck = probeHTTP(ctx, registry, diag.TLSCheck.PlainHTTP)
+
+	return diag
+}
+
+// InspectRegistryCertificate fetches the certificate chain presented at addr
+// The chain is verified against roots, or the system pool when roots is nil.
+// Verification failures are reported rather than aborting the handshake, so the
+// issuer, SANs and expiry of an untrusted certificate are still available.
+func InspectRegistryCertificate(ctx context.Context, addr string, roots *x509.CertPool) *types.TLSResult {
+	start := time.Now()
+	result := &types.TLSResult{}
+	defer func() { result.DurationMs = time.Since(start).Milliseconds() }()
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName: host,
+		// Verification is done below to report why a chain is rejected
+		InsecureSkipVerify: true,
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		var recordErr tls.RecordHeaderError
+		if errors.As(err, &recordErr) && strings.HasPrefix(string(recordErr.RecordHeader[:]), "HTTP/") {
+			result.PlainHTTP = true
+		}
+		return result
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		result.ErrorMessage = "server presented no certificate"
+		return result
+	}
+
+	leaf := certs[0]
+	result.Subject = leaf.Subject.String()
+	result.Issuer = leaf.Issuer.String()
+	result.SANs = append(result.SANs, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		result.SANs = append(result.SANs, ip.String())
+	}
+	result.NotAfter = leaf.NotAfter
+	now := time.Now()
+	result.Expired = now.After(leaf.NotAfter) || now.Before(leaf.NotBefore)
+	for _, cert := range certs {
+		result.Chain = append(result.Chain, cert.Subject.String())
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		result.VerifyError = err.Error()
+		return result
+	}
+
+	result.Trusted = true
+	result.Success = true
+	return result
+}
+
+// splitRegistryHost separates an optional port from a registry host
+func splitRegistryHost(registry string) (string, int) {
+	host, portStr, err := net.SplitHostPort(registry)
+	if err != nil {
+		return registry, 443
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return host, 443
+	}
+	return host, port
+}
+
+// probeDNS resolves the registry host
+func probeDNS(ctx context.Context, host string) *types.DNSResult {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	result := &types.DNSResult{
+		Success:     err == nil,
+		ResolvedIPs: addrs,
+		DurationMs:  time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.ErrorMessage = err.Error()
+	}
+	return result
+}
+
+// probeTCP opens a TCP connection to the registry port
+func probeTCP(ctx context.Context, host string, port int) *types.TCPResult {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	result := &types.TCPResult{
+		Success:    err == nil,
+		Port:       port,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	conn.Close()
+	return result
+}
+
+// probeHTTP sends a HEAD request to the registry API root (/v2/)
+// A 401 is expected from registries requiring authentication and counts as success.
+func probeHTTP(ctx context.Context, registry string, plainHTTP bool) *types.HTTPResult {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	scheme := "https"
+	if plainHTTP {
+		scheme = "http"
+	}
+
+	result := &types.HTTPResult{}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fmt.Sprintf("%s://%s/v2/", scheme, registry), nil)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	// Certificate problems are reported by the TLS check; only reachability matters here
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	resp, err := client.Do(req)
+	result.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.Success = resp.StatusCode < 500
+	return result
+}
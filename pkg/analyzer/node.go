@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// diagnoseNodeDisk reads the disk condition and image GC settings of a node
+// Missing permissions degrade the diagnostics instead of failing the analysis.
+func (a *Analyzer) diagnoseNodeDisk(ctx context.Context, nodeName string) *types.NodeDiagnostics {
+	diag := &types.NodeDiagnostics{NodeName: nodeName}
+
+	a.auditLogger.LogNodeGet(nodeName)
+	node, err := a.k8sClient.GetNode(ctx, nodeName)
+	if err != nil {
+		diag.ErrorMessage = err.Error()
+		return diag
+	}
+
+	if cond := k8s.NodeCondition(node, corev1.NodeDiskPressure); cond != nil {
+		diag.DiskPressure = cond.Status == corev1.ConditionTrue
+		diag.DiskPressureMessage = cond.Message
+	}
+	if qty, ok := node.Status.Capacity[corev1.ResourceEphemeralStorage]; ok {
+		diag.EphemeralStorageCapacity = qty.String()
+	}
+	if qty, ok := node.Status.Allocatable[corev1.ResourceEphemeralStorage]; ok {
+		diag.EphemeralStorageAllocatable = qty.String()
+	}
+
+	a.auditLogger.LogKubeletConfigGet(nodeName)
+	config, err := a.k8sClient.GetKubeletDiskConfig(ctx, nodeName)
+	if err != nil {
+		a.auditLogger.LogWarning(fmt.Sprintf("Using default image GC thresholds: %v", err))
+		config = &k8s.KubeletDiskConfig{}
+		diag.ThresholdsSource = "kubelet defaults"
+	} else {
+		diag.ThresholdsSource = "kubelet configz"
+	}
+
+	diag.ImageGCHighThresholdPercent = k8s.DefaultImageGCHighThresholdPercent
+	if config.ImageGCHighThresholdPercent != nil {
+		diag.ImageGCHighThresholdPercent = *config.ImageGCHighThresholdPercent
+	}
+	diag.ImageGCLowThresholdPercent = k8s.DefaultImageGCLowThresholdPercent
+	if config.ImageGCLowThresholdPercent != nil {
+		diag.ImageGCLowThresholdPercent = *config.ImageGCLowThresholdPercent
+	}
+	diag.ImageFSEvictionThreshold = config.EvictionHard["imagefs.available"]
+	diag.NodeFSEvictionThreshold = config.EvictionHard["nodefs.available"]
+
+	return diag
+}
+
+// nodeDiskRemediation returns node-specific steps that precede the generic NODE_DISK_PRESSURE steps
+func nodeDiskRemediation(diag *types.NodeDiagnostics) []string {
+	if diag == nil || diag.NodeName == "" {
+		return nil
+	}
+
+	var steps []string
+	if diag.DiskPressure {
+		steps = append(steps, fmt.Sprintf("Node %s reports DiskPressure: %s", diag.NodeName, diag.DiskPressureMessage))
+	}
+	steps = append(steps, fmt.Sprintf("Check disk usage on the node: kubectl debug node/%s -it --image=busybox -- df -h /host/var/lib", diag.NodeName))
+	if diag.ImageGCHighThresholdPercent > 0 {
+		steps = append(steps, fmt.Sprintf("Image GC starts at %d%% disk usage and frees down to %d%% (%s); lower imageGCHighThresholdPercent if images fill the disk before GC runs",
+			diag.ImageGCHighThresholdPercent, diag.ImageGCLowThresholdPercent, diag.ThresholdsSource))
+	}
+	return steps
+}
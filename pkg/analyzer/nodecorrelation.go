@@ -0,0 +1,291 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// minNodeFailures is the number of failing pods a node needs before failures are attributed to it
+const minNodeFailures = 2
+
+// zoneLabels name a node's zone, most specific first
+var zoneLabels = []string{
+	"topology.kubernetes.io/zone",
+	"failure-domain.beta.kubernetes.io/zone",
+}
+
+// nodePoolLabels name a node's pool on the common providers and autoscalers
+var nodePoolLabels = []string{
+	"cloud.google.com/gke-nodepool",
+	"eks.amazonaws.com/nodegroup",
+	"alpha.eksctl.io/nodegroup-name",
+	"kubernetes.azure.com/agentpool",
+	"karpenter.sh/nodepool",
+	"node.kubernetes.io/pool",
+}
+
+// pullCounts counts pods failing and having pulled an image
+type pullCounts struct {
+	failed, healthy int
+}
+
+// CorrelateNodes reports nodes where image pulls fail while the same images pull on other nodes
+// pods must include the healthy pods of the scan, not only the failing ones:
+// they are the baseline the failures are compared with. Nodes are listed for
+// their zone, pool and conditions; without permission the findings are still
+// reported, without them.
+func (a *Analyzer) CorrelateNodes(ctx context.Context, pods []corev1.Pod) []types.DiagnosticFinding {
+	nodes, _ := a.listNodes(ctx)
+	return CorrelateNodeFailures(pods, nodes)
+}
+
+// CorrelateNodeFailures groups image pull failures by node and returns a
+// node-scoped NODE_SPECIFIC_FAILURE finding for every node where they concentrate
+// A node qualifies when at least minNodeFailures of its pods fail to pull images
+// that are pulled by pods on other nodes, most of its pods using those images
+// fail, and it has at least as many failures as all other nodes combined.
+func CorrelateNodeFailures(pods []corev1.Pod, nodes []corev1.Node) []types.DiagnosticFinding {
+	nodesByName := make(map[string]*corev1.Node, len(nodes))
+	for i := range nodes {
+		nodesByName[nodes[i].Name] = &nodes[i]
+	}
+
+	// image -> node -> counts, and node -> failing pods per image
+	counts := make(map[string]map[string]*pullCounts)
+	failing := make(map[string]map[string][]string)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		failed, pulled := podPullState(pod)
+		for image := range failed {
+			countFor(counts, image, pod.Spec.NodeName).failed++
+			if failing[pod.Spec.NodeName] == nil {
+				failing[pod.Spec.NodeName] = make(map[string][]string)
+			}
+			failing[pod.Spec.NodeName][image] = append(failing[pod.Spec.NodeName][image], pod.Namespace+"/"+pod.Name)
+		}
+		for image := range pulled {
+			if !failed[image] {
+				countFor(counts, image, pod.Spec.NodeName).healthy++
+			}
+		}
+	}
+
+	var findings []types.DiagnosticFinding
+	for nodeName, byImage := range failing {
+		correlation := correlateNode(nodeName, byImage, counts, nodesByName)
+		if correlation != nil {
+			findings = append(findings, buildNodeFinding(correlation))
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].Subject < findings[j].Subject
+	})
+	return findings
+}
+
+// correlateNode compares a node's failures with the other nodes pulling the same images
+func correlateNode(nodeName string, byImage map[string][]string, counts map[string]map[string]*pullCounts, nodesByName map[string]*corev1.Node) *types.NodeCorrelation {
+	var images []string
+	podSet := make(map[string]bool)
+	var here, elsewhere pullCounts
+	for image, failedPods := range byImage {
+		var imageElsewhere pullCounts
+		for node, c := range counts[image] {
+			if node != nodeName {
+				imageElsewhere.failed += c.failed
+				imageElsewhere.healthy += c.healthy
+			}
+		}
+		// Images no other node pulls say nothing about this node
+		if imageElsewhere.healthy == 0 {
+			continue
+		}
+
+		images = append(images, image)
+		for _, pod := range failedPods {
+			podSet[pod] = true
+		}
+		here.failed += counts[image][nodeName].failed
+		here.healthy += counts[image][nodeName].healthy
+		elsewhere.failed += imageElsewhere.failed
+		elsewhere.healthy += imageElsewhere.healthy
+	}
+
+	if len(podSet) < minNodeFailures || here.failed < elsewhere.failed {
+		return nil
+	}
+	rate, elsewhereRate := failureRate(here), failureRate(elsewhere)
+	if rate < 0.5 || rate <= elsewhereRate {
+		return nil
+	}
+
+	sort.Strings(images)
+	failedPods := make([]string, 0, len(podSet))
+	for pod := range podSet {
+		failedPods = append(failedPods, pod)
+	}
+	sort.Strings(failedPods)
+
+	correlation := &types.NodeCorrelation{
+		NodeName:             nodeName,
+		FailedPods:           failedPods,
+		Images:               images,
+		FailureRate:          rate,
+		ElsewhereFailureRate: elsewhereRate,
+		HealthyElsewhere:     elsewhere.healthy,
+	}
+
+	node, ok := nodesByName[nodeName]
+	if !ok {
+		return correlation
+	}
+	correlation.Zone = firstLabel(node, zoneLabels)
+	correlation.NodePool = firstLabel(node, nodePoolLabels)
+	for _, cond := range node.Status.Conditions {
+		correlation.Conditions = append(correlation.Conditions, types.NodeConditionStatus{
+			Type:    string(cond.Type),
+			Status:  string(cond.Status),
+			Reason:  cond.Reason,
+			Message: cond.Message,
+			Healthy: (cond.Type == corev1.NodeReady) == (cond.Status == corev1.ConditionTrue),
+		})
+	}
+
+	if correlation.Zone != "" {
+		correlation.Groups = append(correlation.Groups, failureGroup("zone", correlation.Zone, zoneLabels, images, counts, nodesByName))
+	}
+	if correlation.NodePool != "" {
+		correlation.Groups = append(correlation.Groups, failureGroup("node pool", correlation.NodePool, nodePoolLabels, images, counts, nodesByName))
+	}
+	return correlation
+}
+
+// failureGroup sums the counts of the correlated images over the nodes sharing a zone or pool
+func failureGroup(dimension, name string, labels []string, images []string, counts map[string]map[string]*pullCounts, nodesByName map[string]*corev1.Node) types.FailureGroup {
+	group := types.FailureGroup{Dimension: dimension, Name: name}
+	for nodeName, node := range nodesByName {
+		if firstLabel(node, labels) != name {
+			continue
+		}
+		group.Nodes++
+		for _, image := range images {
+			if c, ok := counts[image][nodeName]; ok {
+				group.FailedPods += c.failed
+				group.HealthyPods += c.healthy
+			}
+		}
+	}
+	return group
+}
+
+// podPullState returns the images a pod fails to pull and the images it has pulled
+// Images are canonicalized so short Docker Hub names match fully qualified ones.
+func podPullState(pod *corev1.Pod) (failed, pulled map[string]bool) {
+	specImages := make(map[string]string)
+	for _, c := range pod.Spec.InitContainers {
+		specImages[c.Name] = c.Image
+	}
+	for _, c := range pod.Spec.Containers {
+		specImages[c.Name] = c.Image
+	}
+
+	failed, pulled = make(map[string]bool), make(map[string]bool)
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		image, ok := specImages[status.Name]
+		if !ok {
+			continue
+		}
+		image = k8s.CanonicalImage(image)
+		switch {
+		case status.State.Waiting != nil && k8s.IsImagePullWaitingReason(status.State.Waiting.Reason):
+			failed[image] = true
+		case status.ImageID != "":
+			// The runtime resolved the image, so it was pulled (or already present) on this node
+			pulled[image] = true
+		}
+	}
+	return failed, pulled
+}
+
+// buildNodeFinding creates a node-scoped NODE_SPECIFIC_FAILURE finding
+func buildNodeFinding(correlation *types.NodeCorrelation) types.DiagnosticFinding {
+	rootCause := types.RootCauseNodeSpecific
+	return types.DiagnosticFinding{
+		RootCause: rootCause,
+		Severity:  rootCause.Severity(),
+		Scope:     types.FindingScopeNode,
+		Subject:   "Node/" + correlation.NodeName,
+		Summary:   fmt.Sprintf("%s: %s", string(rootCause), rootCause.String()),
+		Details: fmt.Sprintf("%d pods on node %s fail to pull %s (%.0f%% failing) while %d pods on other nodes pulled them (%.0f%% failing elsewhere)",
+			len(correlation.FailedPods), correlation.NodeName, strings.Join(correlation.Images, ", "),
+			correlation.FailureRate*100, correlation.HealthyElsewhere, correlation.ElsewhereFailureRate*100),
+		RemediationSteps: nodeCorrelationRemediation(correlation),
+		FailureCount:     len(correlation.FailedPods),
+		NodeCorrelation:  correlation,
+	}
+}
+
+// nodeCorrelationRemediation returns the steps for a node where pulls fail
+func nodeCorrelationRemediation(correlation *types.NodeCorrelation) []string {
+	node := correlation.NodeName
+	var steps []string
+	for _, cond := range correlation.Conditions {
+		if !cond.Healthy {
+			steps = append(steps, fmt.Sprintf("Node %s reports %s=%s: %s", node, cond.Type, cond.Status, cond.Message))
+		}
+	}
+	for _, group := range correlation.Groups {
+		if group.HealthyPods == 0 && group.Nodes > 1 {
+			steps = append(steps, fmt.Sprintf("No pod in %s %s has pulled these images (%d failing, %d nodes in the %s): check what its nodes share (network path, DNS, runtime configuration)",
+				group.Dimension, group.Name, group.FailedPods, group.Nodes, group.Dimension))
+		}
+	}
+
+	registry := "registry-1.docker.io"
+	if ref, err := types.ParseImageReference("", correlation.Images[0]); err == nil && ref.Registry != types.DefaultRegistry {
+		registry, _ = splitRegistryHost(ref.Registry)
+	}
+	return append(steps,
+		fmt.Sprintf("Check name resolution from the node: kubectl debug node/%s -it --image=busybox -- nslookup %s", node, registry),
+		fmt.Sprintf("Check disk space for images: kubectl debug node/%s -it --image=busybox -- df -h /host/var/lib", node),
+		"Compare the runtime's registry configuration (hosts.toml, registries.conf, proxy settings) with a healthy node",
+		fmt.Sprintf("Keep new pods off the node while investigating: kubectl cordon %s, then delete the failing pods so they are rescheduled", node),
+	)
+}
+
+// firstLabel returns the value of the first label set on a node
+func firstLabel(node *corev1.Node, labels []string) string {
+	for _, label := range labels {
+		if value := node.Labels[label]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func countFor(counts map[string]map[string]*pullCounts, image, node string) *pullCounts {
+	if counts[image] == nil {
+		counts[image] = make(map[string]*pullCounts)
+	}
+	if counts[image][node] == nil {
+		counts[image][node] = &pullCounts{}
+	}
+	return counts[image][node]
+}
+
+func failureRate(c pullCounts) float64 {
+	if c.failed+c.healthy == 0 {
+		return 0
+	}
+	return float64(c.failed) / float64(c.failed+c.healthy)
+}
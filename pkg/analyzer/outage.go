@@ -0,0 +1,250 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Thresholds for attributing failures to a registry outage
+const (
+	minOutagePods  = 3   // Failing pods against the registry
+	minOutageShare = 0.5 // Share of all failing pods in the scan
+)
+
+// outageCauses are the root causes a registry that is down or unreachable produces
+var outageCauses = map[types.RootCause]bool{
+	types.RootCauseNetworkIssue:        true,
+	types.RootCauseRegistryUnavailable: true,
+	types.RootCauseTLSCertificate:      true,
+}
+
+// registryFailures accumulates the failing pods of one registry host
+type registryFailures struct {
+	host, mirrorOf string
+	pods           map[string]bool
+	causes         map[types.RootCause]int
+	workloads      map[types.AffectedWorkload]int
+	images         map[string]types.ImageReference
+	events         int
+	first, last    time.Time
+	node           string // A node of a failing pod, to test the registry from
+}
+
+// AnalyzeRegistryOutages detects registry outages in scan results and, with
+// probe, checks each unavailable registry (or its mirror) from this machine
+func (a *Analyzer) AnalyzeRegistryOutages(ctx context.Context, results []PodResult, probe bool) []types.DiagnosticFinding {
+	findings := DetectRegistryOutages(results)
+	if probe {
+		for i := range findings {
+			if len(findings[i].ImageReferences) > 0 {
+				findings[i].NetworkDiagnostics = probeEndpoint(ctx, &findings[i].ImageReferences[0])
+			}
+		}
+	}
+	return findings
+}
+
+// DetectRegistryOutages aggregates failing pulls by registry host and root cause
+// and returns a cluster-scoped finding for every registry that accounts for most
+// of the failures with connection-level root causes. Pods pulling through a
+// mirror are attributed to the mirror.
+func DetectRegistryOutages(results []PodResult) []types.DiagnosticFinding {
+	byHost := make(map[string]*registryFailures)
+	failingPods := 0
+
+	for _, result := range results {
+		if result.Err != nil || result.Report == nil || len(result.Report.Findings) == 0 {
+			continue
+		}
+		failingPods++
+		podKey := result.Pod.Namespace + "/" + result.Pod.Name
+
+		for _, finding := range result.Report.Findings {
+			if !outageCauses[finding.RootCause] {
+				continue
+			}
+			for _, ref := range finding.ImageReferences {
+				if ref.ParseError != "" {
+					continue
+				}
+				host, mirrorOf := ref.Registry, ""
+				if endpoint := ref.EffectiveEndpoint(); endpoint != nil && endpoint.Mirror {
+					host, mirrorOf = endpoint.Host, ref.Registry
+				}
+
+				failures, ok := byHost[host]
+				if !ok {
+					failures = &registryFailures{
+						host:      host,
+						mirrorOf:  mirrorOf,
+						pods:      make(map[string]bool),
+						causes:    make(map[types.RootCause]int),
+						workloads: make(map[types.AffectedWorkload]int),
+						images:    make(map[string]types.ImageReference),
+					}
+					byHost[host] = failures
+				}
+				failures.images[ref.FullReference] = ref
+				if failures.pods[podKey] {
+					continue
+				}
+				failures.pods[podKey] = true
+				failures.causes[finding.RootCause]++
+				failures.workloads[podWorkload(result.Pod)]++
+				failures.events += finding.FailureCount
+				failures.observe(finding.FirstFailureTime, finding.LastFailureTime)
+				if failures.node == "" {
+					failures.node = result.Pod.Spec.NodeName
+				}
+			}
+		}
+	}
+
+	var findings []types.DiagnosticFinding
+	for _, failures := range byHost {
+		share := float64(len(failures.pods)) / float64(failingPods)
+		if len(failures.pods) < minOutagePods || share < minOutageShare {
+			continue
+		}
+		findings = append(findings, buildOutageFinding(failures, share))
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i].RegistryOutage, findings[j].RegistryOutage
+		if a.AffectedPods != b.AffectedPods {
+			return a.AffectedPods > b.AffectedPods
+		}
+		return a.Registry < b.Registry
+	})
+	return findings
+}
+
+// observe widens the failure window with a finding's first and last failure
+func (f *registryFailures) observe(first, last *time.Time) {
+	if first != nil && (f.first.IsZero() || first.Before(f.first)) {
+		f.first = *first
+	}
+	if last != nil && last.After(f.last) {
+		f.last = *last
+	}
+}
+
+// podWorkload identifies the workload of a pod from its controller reference
+// ReplicaSets created by a Deployment are reported as the Deployment, recognized
+// by the pod-template-hash suffix, without reading the ReplicaSet.
+func podWorkload(pod *corev1.Pod) types.AffectedWorkload {
+	workload := types.AffectedWorkload{Namespace: pod.Namespace, Kind: "Pod", Name: pod.Name}
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return workload
+	}
+	workload.Kind, workload.Name = owner.Kind, owner.Name
+	if hash := pod.Labels["pod-template-hash"]; owner.Kind == "ReplicaSet" && hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+		workload.Kind, workload.Name = "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+	}
+	return workload
+}
+
+// buildOutageFinding creates a cluster-scoped REGISTRY_UNAVAILABLE finding
+func buildOutageFinding(failures *registryFailures, share float64) types.DiagnosticFinding {
+	outage := &types.RegistryOutage{
+		Registry:        failures.host,
+		MirrorOf:        failures.mirrorOf,
+		Since:           failures.first,
+		LastFailure:     failures.last,
+		AffectedPods:    len(failures.pods),
+		ShareOfFailures: share,
+		RootCauses:      failures.causes,
+	}
+	for workload, pods := range failures.workloads {
+		workload.Pods = pods
+		outage.Workloads = append(outage.Workloads, workload)
+	}
+	sort.Slice(outage.Workloads, func(i, j int) bool {
+		a, b := outage.Workloads[i], outage.Workloads[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+
+	var refs []types.ImageReference
+	for _, ref := range failures.images {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].FullReference < refs[j].FullReference
+	})
+
+	causes := make([]string, 0, len(failures.causes))
+	for cause, pods := range failures.causes {
+		causes = append(causes, fmt.Sprintf("%s (%d)", cause, pods))
+	}
+	sort.Strings(causes)
+
+	rootCause := types.RootCauseRegistryUnavailable
+	summary := fmt.Sprintf("%s: Registry %s unavailable", string(rootCause), failures.host)
+	if !outage.Since.IsZero() {
+		summary += " since " + outage.Since.UTC().Format(time.RFC3339)
+	}
+
+	finding := types.DiagnosticFinding{
+		RootCause: rootCause,
+		Severity:  types.SeverityHigh,
+		Scope:     types.FindingScopeCluster,
+		Subject:   "Registry/" + failures.host,
+		Summary:   summary,
+		Details: fmt.Sprintf("%d pods in %d workloads (%.0f%% of failing pods) cannot pull from %s: %s",
+			outage.AffectedPods, len(outage.Workloads), share*100, failures.host, strings.Join(causes, ", ")),
+		RemediationSteps: outageRemediation(outage, failures.node),
+		ImageReferences:  refs,
+		FailureCount:     failures.events,
+		RegistryOutage:   outage,
+	}
+	if !outage.Since.IsZero() {
+		first, last := outage.Since, outage.LastFailure
+		finding.FirstFailureTime = &first
+		finding.LastFailureTime = &last
+		finding.FailureDuration = formatDuration(last.Sub(first))
+	}
+	return finding
+}
+
+// outageRemediation returns the steps for a registry failing pulls across the cluster
+func outageRemediation(outage *types.RegistryOutage, node string) []string {
+	namespaces := make(map[string]bool)
+	for _, workload := range outage.Workloads {
+		namespaces[workload.Namespace] = true
+	}
+
+	steps := []string{
+		fmt.Sprintf("%d workloads in %d namespaces fail for the same reason: treat this as an outage of %s rather than per-workload issues",
+			len(outage.Workloads), len(namespaces), outage.Registry),
+	}
+	if outage.MirrorOf != "" {
+		steps = append(steps, fmt.Sprintf("%s is the mirror for %s: check the mirror service and its storage before the upstream registry", outage.Registry, outage.MirrorOf))
+	} else {
+		steps = append(steps, fmt.Sprintf("Check the status page or on-call of %s", outage.Registry))
+	}
+	if node != "" {
+		host := outage.Registry
+		if host == types.DefaultRegistry {
+			host = "registry-1.docker.io"
+		}
+		steps = append(steps, fmt.Sprintf("Confirm from a node: kubectl debug node/%s -it --image=busybox -- wget -S -O /dev/null https://%s/v2/", node, host))
+	}
+	steps = append(steps, "Once the registry recovers, kubelet retries with back-off of up to 5 minutes; delete the failing pods to retry immediately")
+	if outage.MirrorOf == "" {
+		steps = append(steps, fmt.Sprintf("To ride out future outages, pull %s images through a pull-through cache or mirror", outage.Registry))
+	}
+	return steps
+}
@@ -0,0 +1,168 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+)
+
+var (
+	// admission webhook "validate.kyverno.svc-fail" denied the request: ...
+	webhookDenialRe = regexp.MustCompile(`admission webhook "([^"]+)" denied the request`)
+	// Kyverno lists "policy:" lines followed by indented "rule: message" lines
+	kyvernoPolicyRe = regexp.MustCompile(`(?m)^([a-z0-9][a-z0-9.-]*):[ \t]*$`)
+	kyvernoRuleRe   = regexp.MustCompile(`(?m)^[ \t]+([a-z0-9][a-z0-9.-]*):[ \t]`)
+	// Gatekeeper prefixes each violation with the constraint name: [allowed-repos] ...
+	gatekeeperConstraintRe = regexp.MustCompile(`\[([a-z0-9][a-z0-9.-]*)\]`)
+	// sigstore policy-controller: validation failed: failed policy: image-policy: ...
+	sigstorePolicyRe = regexp.MustCompile(`failed policy: ([a-z0-9][a-z0-9.-]*)`)
+	// ValidatingAdmissionPolicy 'name' with binding 'binding' denied request: ...
+	validatingPolicyRe = regexp.MustCompile(`ValidatingAdmissionPolicy '([^']+)'`)
+)
+
+// signatureHints mark policies that verify image signatures or attestations
+var signatureHints = []string{
+	"signature",
+	"verify image",
+	"verifyimages",
+	"attestation",
+	"attestor",
+	"cosign",
+	"keyless",
+}
+
+// ParsePolicyRejection recognizes admission and image policy denials
+// Returns nil when the message is not a policy rejection. Kyverno, Gatekeeper,
+// the sigstore policy-controller, ValidatingAdmissionPolicy and the
+// ImagePolicyWebhook admission plugin are recognized by name; other webhooks
+// are reported as generic admission webhook denials.
+func ParsePolicyRejection(message string) *types.PolicyRejection {
+	rejection := &types.PolicyRejection{Message: output.RedactEventMessage(strings.TrimSpace(message))}
+
+	switch {
+	case webhookDenialRe.MatchString(message):
+		rejection.Webhook = webhookDenialRe.FindStringSubmatch(message)[1]
+		denial := message[strings.Index(message, "denied the request")+len("denied the request"):]
+		webhook := strings.ToLower(rejection.Webhook)
+
+		switch {
+		case strings.Contains(webhook, "kyverno"):
+			rejection.Controller = types.PolicyControllerKyverno
+			if idx := strings.Index(denial, "following policies"); idx >= 0 {
+				blocked := denial[idx:]
+				rejection.Policies = submatches(kyvernoPolicyRe, blocked)
+				rejection.Rules = submatches(kyvernoRuleRe, blocked)
+			}
+		case strings.Contains(webhook, "gatekeeper"):
+			rejection.Controller = types.PolicyControllerGatekeeper
+			rejection.Policies = submatches(gatekeeperConstraintRe, denial)
+		case strings.Contains(webhook, "sigstore"):
+			rejection.Controller = types.PolicyControllerSigstore
+			rejection.Policies = submatches(sigstorePolicyRe, denial)
+			rejection.Signature = true
+		default:
+			rejection.Controller = types.PolicyControllerWebhook
+		}
+	case validatingPolicyRe.MatchString(message):
+		rejection.Controller = types.PolicyControllerValidatingPolicy
+		rejection.Policies = submatches(validatingPolicyRe, message)
+	case strings.Contains(message, "image policy webhook"):
+		rejection.Controller = types.PolicyControllerImagePolicyWebhook
+	default:
+		return nil
+	}
+
+	lower := strings.ToLower(message)
+	for _, hint := range signatureHints {
+		if strings.Contains(lower, hint) {
+			rejection.Signature = true
+			break
+		}
+	}
+
+	return rejection
+}
+
+// submatches returns the unique first capture groups of all matches, in order
+func submatches(re *regexp.Regexp, s string) []string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, m := range re.FindAllStringSubmatch(s, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			values = append(values, m[1])
+		}
+	}
+	return values
+}
+
+// policyRejectionRemediation returns steps for POLICY_REJECTION
+// Steps name the blocking policy when the denial message identifies it.
+func policyRejectionRemediation(p *types.PolicyRejection) []string {
+	if p == nil {
+		return []string{
+			"An admission or image policy rejected the pod",
+			"Find the webhook that denied it: kubectl get validatingwebhookconfigurations,mutatingwebhookconfigurations",
+			"Check the owning ReplicaSet/StatefulSet events: kubectl describe rs <name>",
+			"Either make the image compliant (allowed registry, signed image) or ask the policy owner for an exception",
+		}
+	}
+
+	policy := "<policy>"
+	if len(p.Policies) > 0 {
+		policy = p.Policies[0]
+	}
+
+	var steps []string
+	switch p.Controller {
+	case types.PolicyControllerKyverno:
+		steps = []string{
+			fmt.Sprintf("Blocked by Kyverno policy %s", strings.Join(p.Policies, ", ")),
+			fmt.Sprintf("Inspect the policy: kubectl get clusterpolicy,policy -A | grep %s", policy),
+			"Review policy reports for details: kubectl get policyreport,clusterpolicyreport -A",
+			"If the workload is legitimately exempt, create a Kyverno PolicyException for it",
+		}
+	case types.PolicyControllerGatekeeper:
+		steps = []string{
+			fmt.Sprintf("Blocked by Gatekeeper constraint %s", strings.Join(p.Policies, ", ")),
+			fmt.Sprintf("Inspect the constraint and its parameters: kubectl get constraints | grep %s", policy),
+			"Use an image from an allowed repository, or update the constraint parameters (e.g. allowed repos)",
+			"Exempt the namespace through the constraint's match.excludedNamespaces if appropriate",
+		}
+	case types.PolicyControllerSigstore:
+		steps = []string{
+			fmt.Sprintf("Blocked by sigstore ClusterImagePolicy %s", strings.Join(p.Policies, ", ")),
+			fmt.Sprintf("Inspect the policy authorities: kubectl get clusterimagepolicy %s -o yaml", policy),
+			"Check which namespaces are enforced: kubectl get ns -l policy.sigstore.dev/include=true",
+		}
+	case types.PolicyControllerValidatingPolicy:
+		steps = []string{
+			fmt.Sprintf("Blocked by ValidatingAdmissionPolicy %s", policy),
+			fmt.Sprintf("Inspect the policy and its bindings: kubectl get validatingadmissionpolicy %s -o yaml; kubectl get validatingadmissionpolicybinding", policy),
+		}
+	case types.PolicyControllerImagePolicyWebhook:
+		steps = []string{
+			"Blocked by the ImagePolicyWebhook admission plugin",
+			"Check the image policy backend configured on the API server (--admission-control-config-file)",
+		}
+	default:
+		steps = []string{
+			fmt.Sprintf("Blocked by admission webhook %s", p.Webhook),
+			fmt.Sprintf("Inspect the webhook: kubectl get validatingwebhookconfigurations,mutatingwebhookconfigurations | grep %s", p.Webhook),
+		}
+	}
+
+	if p.Signature {
+		steps = append(steps,
+			"Sign the image with a key or identity the policy trusts: cosign sign --key <key> <image>@<digest>",
+			"Verify the signature as the policy would: cosign verify --key <public-key> <image>",
+			"Reference images by digest so the signature matches the exact image being deployed")
+	} else {
+		steps = append(steps, "Make the image compliant (allowed registry, required tag or digest) or add it to the policy allowlist")
+	}
+
+	return steps
+}
@@ -0,0 +1,63 @@
+package analyzer
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultConcurrency is the number of workers used when none is configured
+const DefaultConcurrency = 10
+
+// RunConcurrently calls fn for every index in [0, total) using at most
+// concurrency workers. Callers store results into a pre-sized slice by index,
+// which keeps output order independent of completion order.
+// onDone (optional) is invoked after each item with the number completed so far.
+// Returns ctx.Err() if the context was cancelled before all items were dispatched.
+func RunConcurrently(ctx context.Context, concurrency, total int, fn func(ctx context.Context, i int), onDone func(done, total int)) error {
+	if total <= 0 {
+		return nil
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	if concurrency > total {
+		concurrency = total
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(ctx, i)
+
+				if onDone != nil {
+					mu.Lock()
+					done++
+					onDone(done, total)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	var err error
+dispatch:
+	for i := 0; i < total; i++ {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break dispatch
+		case indexes <- i:
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return err
+}
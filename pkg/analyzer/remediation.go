@@ -2,15 +2,23 @@ package analyzer
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/aboigues/k8t/pkg/types"
 )
 
 // GenerateRemediationSteps returns actionable steps for a root cause
 func GenerateRemediationSteps(rootCause types.RootCause, imageRef *types.ImageReference) []string {
+	return GenerateRuntimeRemediationSteps(rootCause, imageRef, types.RuntimeUnknown)
+}
+
+// GenerateRuntimeRemediationSteps returns actionable steps with node-side commands
+// (pull, prune, registry CA and insecure registry configuration) for the node's
+// container runtime
+func GenerateRuntimeRemediationSteps(rootCause types.RootCause, imageRef *types.ImageReference, runtime types.ContainerRuntime) []string {
 	switch rootCause {
 	case types.RootCauseImageNotFound:
-		return imageNotFoundRemediation(imageRef)
+		return imageNotFoundRemediation(imageRef, runtime)
 	case types.RootCauseAuthFailure:
 		return authFailureRemediation(imageRef)
 	case types.RootCauseNetworkIssue:
@@ -21,17 +29,31 @@ func GenerateRemediationSteps(rootCause types.RootCause, imageRef *types.ImageRe
 		return permissionDeniedRemediation(imageRef)
 	case types.RootCauseManifestError:
 		return manifestErrorRemediation(imageRef)
+	case types.RootCauseTLSCertificate:
+		return tlsCertificateRemediation(imageRef, runtime)
+	case types.RootCausePolicyRejection:
+		return policyRejectionRemediation(nil)
+	case types.RootCauseNodeDiskPressure:
+		return nodeDiskPressureRemediation(imageRef, runtime)
+	case types.RootCauseImageNeverPull:
+		return imageNeverPullRemediation(imageRef, runtime)
+	case types.RootCauseInvalidImageName:
+		return invalidImageNameRemediation(imageRef)
+	case types.RootCauseRegistryUnavailable:
+		return registryUnavailableRemediation(imageRef)
+	case types.RootCauseSignatureValidation:
+		return signatureValidationRemediation(imageRef)
 	case types.RootCauseTransient:
 		return transientFailureRemediation()
 	case types.RootCauseUnknown:
-		return unknownRemediation(imageRef)
+		return unknownRemediation(imageRef, runtime)
 	default:
 		return []string{"No remediation steps available for this root cause"}
 	}
 }
 
 // imageNotFoundRemediation returns steps for IMAGE_NOT_FOUND
-func imageNotFoundRemediation(img *types.ImageReference) []string {
+func imageNotFoundRemediation(img *types.ImageReference, runtime types.ContainerRuntime) []string {
 	if img == nil {
 		return []string{
 			"Verify the image name and tag are correct in your pod specification",
@@ -43,7 +65,7 @@ func imageNotFoundRemediation(img *types.ImageReference) []string {
 
 	return []string{
 		fmt.Sprintf("Verify the image name and tag are correct: %s", img.FullReference),
-		fmt.Sprintf("Check if the image exists: docker pull %s", img.FullReference),
+		fmt.Sprintf("Check if the image exists: %s", pullCommand(runtime, img.FullReference)),
 		"Ensure the image was pushed to the registry after building",
 		fmt.Sprintf("Verify registry '%s' is accessible from your cluster", img.Registry),
 		"Check if the image tag was deleted or moved",
@@ -145,6 +167,128 @@ func manifestErrorRemediation(img *types.ImageReference) []string {
 	return steps
 }
 
+// tlsCertificateRemediation returns steps for TLS_CERTIFICATE_ERROR
+func tlsCertificateRemediation(img *types.ImageReference, runtime types.ContainerRuntime) []string {
+	registry := "<registry>"
+	if img != nil {
+		registry = img.Registry
+	}
+
+	steps := []string{
+		fmt.Sprintf("Inspect the registry certificate: openssl s_client -connect %s -showcerts (add :443 if no port)", registry),
+		"If the certificate has expired or does not cover the registry hostname, renew it on the registry",
+	}
+	if runtime != types.RuntimeUnknown {
+		steps = append(steps, runtimeTLSRemediation(runtime, registry)...)
+		return append(steps, "Run k8t with --detailed to see the certificate issuer, SANs and expiry")
+	}
+
+	return append(steps,
+		fmt.Sprintf("For a private CA (containerd): copy the CA to /etc/containerd/certs.d/%s/ca.crt on every node", registry),
+		fmt.Sprintf("Reference it in /etc/containerd/certs.d/%s/hosts.toml: [host.\"https://%s\"] ca = \"/etc/containerd/certs.d/%s/ca.crt\"", registry, registry, registry),
+		"Ensure containerd uses the certs.d directory: config_path = \"/etc/containerd/certs.d\" under [plugins.\"io.containerd.grpc.v1.cri\".registry]",
+		fmt.Sprintf("For a plain HTTP registry, declare it insecure: server = \"http://%s\" in hosts.toml (containerd), insecure-registries in /etc/docker/daemon.json (Docker) or insecure = true in /etc/containers/registries.conf (CRI-O)", registry),
+		"Run k8t with --detailed to see the certificate issuer, SANs and expiry",
+	)
+}
+
+// nodeDiskPressureRemediation returns steps for NODE_DISK_PRESSURE
+// The image itself is fine; the node has no room to extract its layers.
+func nodeDiskPressureRemediation(img *types.ImageReference, runtime types.ContainerRuntime) []string {
+	steps := []string{
+		"The image is valid; the node ran out of disk space while extracting its layers",
+		fmt.Sprintf("Remove unused images on the node: %s", pruneCommand(runtime)),
+		"Check the kubelet image GC thresholds (imageGCHighThresholdPercent / imageGCLowThresholdPercent) and eviction thresholds (imagefs.available, nodefs.available)",
+		"Increase the node's root or image filesystem, or move the container runtime data to a larger volume",
+		"Cordon and drain the node if it keeps failing, so pods are rescheduled elsewhere",
+	}
+
+	if img != nil {
+		steps = append(steps, fmt.Sprintf("Consider reducing the image size of %s (multi-stage builds, smaller base image)", img.FullReference))
+	}
+
+	return steps
+}
+
+// imageNeverPullRemediation returns steps for IMAGE_NEVER_PULL
+func imageNeverPullRemediation(img *types.ImageReference, runtime types.ContainerRuntime) []string {
+	steps := []string{
+		"The pod uses imagePullPolicy: Never but the image is not present on the node",
+		"Either preload the image on every node that may run the pod, or change the pull policy",
+		"Switch to imagePullPolicy: IfNotPresent to let the kubelet pull the image when missing",
+	}
+
+	if img != nil {
+		preload := fmt.Sprintf("crictl pull %s", img.FullReference)
+		if runtime != types.RuntimeUnknown {
+			preload = pullCommand(runtime, img.FullReference)
+		}
+		steps = append(steps, fmt.Sprintf("Preload the image on the node: %s", preload))
+	}
+
+	steps = append(steps, "For local clusters, load the image into the node (kind load docker-image, minikube image load)")
+
+	return steps
+}
+
+// invalidImageNameRemediation returns steps for INVALID_IMAGE_NAME
+func invalidImageNameRemediation(img *types.ImageReference) []string {
+	steps := []string{
+		"Fix the image reference in the pod specification; the kubelet cannot parse it",
+	}
+
+	// The parser names the component and character at fault
+	if img != nil && img.ParseError != "" {
+		steps = append(steps, strings.ToUpper(img.ParseError[:1])+img.ParseError[1:])
+	}
+
+	steps = append(steps,
+		"Repository names must be lowercase (e.g. registry.example.com/team/app)",
+		"Tags may only contain letters, digits, '_', '.' and '-' and must not exceed 128 characters",
+		"Digests must use the form <name>@sha256:<64 hex characters>",
+		"Check for stray whitespace, quotes or unresolved template variables in the image field",
+	)
+
+	if img != nil {
+		steps = append(steps, fmt.Sprintf("Current reference: %s", img.FullReference))
+	}
+
+	return steps
+}
+
+// registryUnavailableRemediation returns steps for REGISTRY_UNAVAILABLE
+func registryUnavailableRemediation(img *types.ImageReference) []string {
+	steps := []string{
+		"The registry reported itself as unavailable; check its status page or health endpoint",
+	}
+
+	if img != nil {
+		steps = append(steps, fmt.Sprintf("Test registry health: curl -sI https://%s/v2/", img.Registry))
+	}
+
+	steps = append(steps, "Configure a registry mirror in the container runtime to keep pulling during outages")
+	steps = append(steps, "Kubernetes will keep retrying; the pod should start once the registry recovers")
+
+	return steps
+}
+
+// signatureValidationRemediation returns steps for SIGNATURE_VALIDATION_FAILED
+func signatureValidationRemediation(img *types.ImageReference) []string {
+	steps := []string{
+		"The container runtime rejected the image because its signature could not be validated",
+		"Review the node's signature policy: /etc/containers/policy.json (CRI-O) or the runtime's verification plugin",
+		"Ensure the image was signed with a key trusted by the policy",
+	}
+
+	if img != nil {
+		steps = append(steps, fmt.Sprintf("Verify the signature: cosign verify --key <public-key> %s", img.FullReference))
+	}
+
+	steps = append(steps, "If an admission controller signs or verifies images, check its logs for the rejection reason")
+
+	return steps
+}
+
 // transientFailureRemediation returns steps for TRANSIENT_FAILURE
 func transientFailureRemediation() []string {
 	return []string{
@@ -157,18 +301,18 @@ func transientFailureRemediation() []string {
 }
 
 // unknownRemediation returns steps for UNKNOWN root cause
-func unknownRemediation(img *types.ImageReference) []string {
+func unknownRemediation(img *types.ImageReference, runtime types.ContainerRuntime) []string {
 	steps := []string{
 		"Review the full error message in pod events for more details",
 		"Check pod events: kubectl describe pod <pod-name>",
 		"Verify the image reference is correct and complete",
-		"Test image pull manually: docker pull <image>",
+		fmt.Sprintf("Test image pull manually: %s", pullCommand(runtime, "<image>")),
 		"Check registry status and availability",
 		"Review cluster logs for additional error context",
 	}
 
 	if img != nil {
-		steps = append(steps, fmt.Sprintf("Manually test pull: docker pull %s", img.FullReference))
+		steps = append(steps, fmt.Sprintf("Manually test pull: %s", pullCommand(runtime, img.FullReference)))
 	}
 
 	steps = append(steps, "Contact registry support if the issue persists")
@@ -0,0 +1,190 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// imageChangeCauses are the root causes a rollback to an earlier image can fix
+// Registry, network and node problems affect every image alike and are excluded.
+var imageChangeCauses = map[types.RootCause]bool{
+	types.RootCauseImageNotFound:       true,
+	types.RootCauseAuthFailure:         true,
+	types.RootCausePermissionDenied:    true,
+	types.RootCauseManifestError:       true,
+	types.RootCauseInvalidImageName:    true,
+	types.RootCauseImageNeverPull:      true,
+	types.RootCauseSignatureValidation: true,
+	types.RootCausePolicyRejection:     true,
+}
+
+// correlateRollout finds the Deployment revision that changed the images of the
+// failing containers, and whether the previous images can be rolled back to
+// Returns nil when the pod is not part of a Deployment or no earlier revision
+// ran different images. Missing permissions degrade to nil with a warning.
+func (a *Analyzer) correlateRollout(ctx context.Context, pod *corev1.Pod, containers []string) *types.RolloutCorrelation {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "ReplicaSet" {
+		return nil
+	}
+
+	a.auditLogger.LogObjectGet("replicasets", owner.Name, pod.Namespace)
+	current, err := a.k8sClient.GetReplicaSet(ctx, pod.Namespace, owner.Name)
+	if err != nil {
+		a.auditLogger.LogWarning(fmt.Sprintf("Rollout history unavailable: %v", err))
+		return nil
+	}
+	deploy := metav1.GetControllerOf(current)
+	if deploy == nil || deploy.Kind != "Deployment" {
+		return nil
+	}
+
+	a.auditLogger.LogReplicaSetList(pod.Namespace)
+	replicaSets, err := a.k8sClient.ListDeploymentReplicaSets(ctx, pod.Namespace, deploy.Name)
+	if err != nil {
+		a.auditLogger.LogWarning(fmt.Sprintf("Rollout history unavailable: %v", err))
+		return nil
+	}
+
+	correlation := compareRevisions(current, replicaSets, containers)
+	if correlation == nil {
+		return nil
+	}
+	correlation.Deployment = deploy.Name
+
+	a.checkPreviousImages(ctx, correlation)
+	return correlation
+}
+
+// compareRevisions walks the revisions older than current, newest first, to
+// the last one whose images differ for the given containers
+func compareRevisions(current *appsv1.ReplicaSet, replicaSets []appsv1.ReplicaSet, containers []string) *types.RolloutCorrelation {
+	revision := k8s.ReplicaSetRevision(current)
+	if revision == 0 {
+		return nil
+	}
+
+	older := make([]appsv1.ReplicaSet, 0, len(replicaSets))
+	for _, rs := range replicaSets {
+		if r := k8s.ReplicaSetRevision(&rs); r > 0 && r < revision {
+			older = append(older, rs)
+		}
+	}
+	sort.Slice(older, func(i, j int) bool {
+		return k8s.ReplicaSetRevision(&older[i]) > k8s.ReplicaSetRevision(&older[j])
+	})
+
+	currentImages := k8s.TemplateImages(&current.Spec.Template.Spec)
+	introduced := revision
+	for i := range older {
+		rs := &older[i]
+		images := k8s.TemplateImages(&rs.Spec.Template.Spec)
+
+		var changes []types.ImageChange
+		for _, container := range containers {
+			previous, ok := images[container]
+			if ok && previous != currentImages[container] {
+				changes = append(changes, types.ImageChange{
+					Container:     container,
+					PreviousImage: previous,
+					CurrentImage:  currentImages[container],
+				})
+			}
+		}
+		if len(changes) == 0 {
+			// Same images: the change happened before this revision
+			introduced = k8s.ReplicaSetRevision(rs)
+			continue
+		}
+
+		return &types.RolloutCorrelation{
+			Revision:           revision,
+			IntroducedRevision: introduced,
+			PreviousRevision:   k8s.ReplicaSetRevision(rs),
+			PreviousReplicaSet: rs.Name,
+			PreviousReady:      rs.Status.ReadyReplicas,
+			ImageChanges:       changes,
+		}
+	}
+	return nil
+}
+
+// checkPreviousImages records where the previous images are cached and, with
+// --detailed, whether the registry still serves them
+func (a *Analyzer) checkPreviousImages(ctx context.Context, correlation *types.RolloutCorrelation) {
+	nodes, _ := a.listNodes(ctx)
+
+	for i := range correlation.ImageChanges {
+		change := &correlation.ImageChanges[i]
+		change.CachedOnNodes = k8s.NodesWithImage(nodes, change.PreviousImage)
+
+		// Detailed mode asks the registry; its answer wins when conclusive
+		if a.detailed {
+			if ref, err := types.ParseImageReference(change.Container, change.PreviousImage); err == nil {
+				ref.PullEndpoints = a.mirrors.Resolve(*ref)
+				exists, err := CheckManifest(ctx, *ref, a.insecureRegistry(*ref))
+				switch {
+				case err != nil:
+					change.PullableReason = fmt.Sprintf("registry check inconclusive: %v", err)
+				case exists:
+					change.Pullable = boolPtr(true)
+					change.PullableReason = "manifest found in the registry"
+					continue
+				default:
+					change.Pullable = boolPtr(false)
+					change.PullableReason = "manifest no longer in the registry"
+					continue
+				}
+			}
+		}
+
+		if change.PullableReason == "" {
+			change.PullableReason = "not checked (use --detailed to query the registry)"
+		}
+		// Ready pods only prove the image is cached on their nodes: a rollback
+		// scheduled elsewhere still pulls it
+		switch {
+		case correlation.PreviousReady > 0:
+			change.PullableReason += fmt.Sprintf("; cached: %d ready pods of revision %d run it", correlation.PreviousReady, correlation.PreviousRevision)
+		case len(change.CachedOnNodes) > 0:
+			change.PullableReason += fmt.Sprintf("; cached on %d nodes", len(change.CachedOnNodes))
+		}
+	}
+}
+
+// rolloutRemediation returns the rollback steps that precede the root cause's own steps
+func rolloutRemediation(namespace string, correlation *types.RolloutCorrelation) []string {
+	var steps []string
+	for _, change := range correlation.ImageChanges {
+		steps = append(steps, fmt.Sprintf("Revision %d of deployment %s changed the image of container %s from %s to %s",
+			correlation.IntroducedRevision, correlation.Deployment, change.Container, change.PreviousImage, change.CurrentImage))
+	}
+
+	rollback := fmt.Sprintf("Roll back to revision %d: kubectl rollout undo deployment/%s --to-revision=%d -n %s",
+		correlation.PreviousRevision, correlation.Deployment, correlation.PreviousRevision, namespace)
+	var notes []string
+	for _, change := range correlation.ImageChanges {
+		if change.Pullable != nil && !*change.Pullable {
+			notes = append(notes, fmt.Sprintf("%s is no longer in the registry", change.PreviousImage))
+			if len(change.CachedOnNodes) > 0 {
+				notes = append(notes, fmt.Sprintf("only nodes %s still cache it", strings.Join(change.CachedOnNodes, ", ")))
+			}
+		}
+	}
+	if len(notes) > 0 {
+		rollback += " (caution: " + strings.Join(notes, "; ") + ")"
+	}
+	return append(steps, rollback)
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,280 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/aboigues/k8t/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// ruleIDRe restricts custom root cause IDs to the style of the built-in ones
+var ruleIDRe = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// RuleSet holds user-defined root cause rules merged with the built-in detection table
+// Rules can introduce new root cause IDs or add matchers to a built-in one.
+// When candidates score the same, root causes named by rules rank ahead of the
+// built-in ones, higher priority first.
+type RuleSet struct {
+	Rules []Rule `yaml:"rules"`
+
+	order   []types.RootCause // Tie-breaking order: rule causes by priority, then built-ins
+	signals map[types.RootCause][]signal
+	regexes map[string]*regexp.Regexp // Regex matchers by pattern
+	byID    map[types.RootCause]*Rule
+}
+
+// Rule maps message matchers to a root cause with its severity and remediation
+// Remediation steps are Go templates executed with the failing container's
+// ImageReference, e.g. "Request access to {{.Repository}} on {{.Registry}}".
+type Rule struct {
+	ID          string        `yaml:"id"`
+	Description string        `yaml:"description"`
+	Severity    string        `yaml:"severity"` // HIGH, MEDIUM or LOW
+	Priority    int           `yaml:"priority"`
+	Match       []RuleMatcher `yaml:"match"`
+	Remediation []string      `yaml:"remediation"`
+
+	templates []*template.Template
+}
+
+// RuleMatcher is a substring or regular expression matched against evidence messages
+// Substrings match case-insensitively. Weight defaults to 1.0, the weight of the
+// most specific built-in patterns.
+type RuleMatcher struct {
+	Contains string  `yaml:"contains"`
+	Regex    string  `yaml:"regex"`
+	Weight   float64 `yaml:"weight"`
+}
+
+// LoadRules reads a rules file
+// The file may be a dedicated rules file or the k8t config file; only the
+// top-level rules key is read.
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ParseRules parses and validates rules from YAML
+func ParseRules(data []byte) (*RuleSet, error) {
+	rules := &RuleSet{}
+	if err := yaml.Unmarshal(data, rules); err != nil {
+		return nil, err
+	}
+	if err := rules.compile(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// compile validates the rules and merges their matchers with the built-in table
+func (rs *RuleSet) compile() error {
+	rs.signals = make(map[types.RootCause][]signal, len(rootCauseSignals)+len(rs.Rules))
+	for cause, signals := range rootCauseSignals {
+		rs.signals[cause] = signals
+	}
+	rs.regexes = make(map[string]*regexp.Regexp)
+	rs.byID = make(map[types.RootCause]*Rule, len(rs.Rules))
+
+	for i := range rs.Rules {
+		rule := &rs.Rules[i]
+		if err := rule.compile(); err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i+1, rule.ID, err)
+		}
+
+		cause := types.RootCause(rule.ID)
+		if rs.byID[cause] != nil {
+			return fmt.Errorf("rule %d: duplicate id %s", i+1, rule.ID)
+		}
+		rs.byID[cause] = rule
+		if _, builtin := rootCauseSignals[cause]; !builtin {
+			severity := types.Severity(rule.Severity)
+			if severity == "" {
+				severity = types.SeverityMedium
+			}
+			if err := types.RegisterRootCause(cause, rule.Description, severity); err != nil {
+				return fmt.Errorf("rule %d: %w", i+1, err)
+			}
+		}
+
+		for _, m := range rule.Match {
+			sig := signal{pattern: strings.ToLower(m.Contains), weight: m.Weight}
+			if m.Regex != "" {
+				sig.pattern = m.Regex
+				rs.regexes[m.Regex] = regexp.MustCompile(m.Regex)
+			}
+			rs.signals[cause] = append(rs.signals[cause], sig)
+		}
+	}
+
+	ranked := make([]*Rule, 0, len(rs.Rules))
+	for i := range rs.Rules {
+		ranked = append(ranked, &rs.Rules[i])
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Priority > ranked[j].Priority
+	})
+
+	rs.order = make([]types.RootCause, 0, len(ranked)+len(rootCausePriority))
+	for _, rule := range ranked {
+		rs.order = append(rs.order, types.RootCause(rule.ID))
+	}
+	for _, cause := range rootCausePriority {
+		if rs.byID[cause] == nil {
+			rs.order = append(rs.order, cause)
+		}
+	}
+
+	return nil
+}
+
+// compile validates a rule and parses its remediation templates
+func (r *Rule) compile() error {
+	if !ruleIDRe.MatchString(r.ID) {
+		return fmt.Errorf("id must be upper case letters, digits and underscores")
+	}
+	cause := types.RootCause(r.ID)
+	if cause == types.RootCauseTransient || cause == types.RootCauseUnknown || cause == types.RootCauseNodeSpecific {
+		return fmt.Errorf("%s is decided by the analyzer and cannot be matched by a rule", r.ID)
+	}
+	if _, builtin := rootCauseSignals[cause]; !builtin && r.Description == "" {
+		return fmt.Errorf("description is required for custom root causes")
+	}
+
+	switch types.Severity(strings.ToUpper(r.Severity)) {
+	case "", types.SeverityHigh, types.SeverityMedium, types.SeverityLow:
+		r.Severity = strings.ToUpper(r.Severity)
+	default:
+		return fmt.Errorf("severity must be HIGH, MEDIUM or LOW, got %q", r.Severity)
+	}
+
+	if len(r.Match) == 0 {
+		return fmt.Errorf("at least one matcher is required")
+	}
+	for i := range r.Match {
+		m := &r.Match[i]
+		if (m.Contains == "") == (m.Regex == "") {
+			return fmt.Errorf("matcher %d: exactly one of contains or regex is required", i+1)
+		}
+		if m.Regex != "" {
+			if _, err := regexp.Compile(m.Regex); err != nil {
+				return fmt.Errorf("matcher %d: %w", i+1, err)
+			}
+		}
+		if m.Weight < 0 {
+			return fmt.Errorf("matcher %d: weight must not be negative", i+1)
+		}
+		if m.Weight == 0 {
+			m.Weight = 1.0
+		}
+	}
+
+	r.templates = make([]*template.Template, len(r.Remediation))
+	for i, step := range r.Remediation {
+		tmpl, err := template.New(fmt.Sprintf("%s-%d", r.ID, i+1)).Parse(step)
+		if err != nil {
+			return fmt.Errorf("remediation step %d: %w", i+1, err)
+		}
+		// Catch references to fields ImageReference does not have
+		if err := tmpl.Execute(&bytes.Buffer{}, types.ImageReference{}); err != nil {
+			return fmt.Errorf("remediation step %d: %w", i+1, err)
+		}
+		r.templates[i] = tmpl
+	}
+
+	return nil
+}
+
+// causeOrder returns the root causes to match, in tie-breaking order
+func (rs *RuleSet) causeOrder() []types.RootCause {
+	if rs == nil {
+		return rootCausePriority
+	}
+	return rs.order
+}
+
+// signalsFor returns the built-in and user-defined signals of a root cause
+func (rs *RuleSet) signalsFor(cause types.RootCause) []signal {
+	if rs == nil {
+		return rootCauseSignals[cause]
+	}
+	return rs.signals[cause]
+}
+
+// find returns the offset and length of a signal in a message, or -1
+// Regex matchers run against the original message, substrings against the
+// lowercased one.
+func (rs *RuleSet) find(sig signal, message, lower string) (int, int) {
+	if rs != nil {
+		if re := rs.regexes[sig.pattern]; re != nil {
+			loc := re.FindStringIndex(message)
+			if loc == nil {
+				return -1, 0
+			}
+			return loc[0], loc[1] - loc[0]
+		}
+	}
+	return strings.Index(lower, sig.pattern), len(sig.pattern)
+}
+
+// rank returns the tie-breaking rank of a root cause
+func (rs *RuleSet) rank(cause types.RootCause) int {
+	if rs == nil {
+		return priorityIndex(cause)
+	}
+	for i, c := range rs.order {
+		if c == cause {
+			return i
+		}
+	}
+	return len(rs.order)
+}
+
+// applyTo overrides the severity, summary and remediation of a finding whose
+// root cause is defined or customized by a rule
+func (rs *RuleSet) applyTo(finding *types.DiagnosticFinding, img *types.ImageReference) {
+	if rs == nil {
+		return
+	}
+	rule := rs.byID[finding.RootCause]
+	if rule == nil {
+		return
+	}
+
+	if rule.Severity != "" {
+		finding.Severity = types.Severity(rule.Severity)
+	}
+	if rule.Description != "" {
+		finding.Summary = fmt.Sprintf("%s: %s", string(finding.RootCause), rule.Description)
+	}
+
+	if len(rule.templates) > 0 {
+		data := types.ImageReference{}
+		if img != nil {
+			data = *img
+		}
+		steps := make([]string, 0, len(rule.templates))
+		for i, tmpl := range rule.templates {
+			var b bytes.Buffer
+			if err := tmpl.Execute(&b, data); err != nil {
+				steps = append(steps, rule.Remediation[i])
+				continue
+			}
+			steps = append(steps, b.String())
+		}
+		finding.RemediationSteps = steps
+	}
+}
@@ -0,0 +1,169 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+)
+
+// runtimeSignals are the patterns specific to one container runtime's wording
+// The kubelet passes the runtime's pull error through unchanged, so the same
+// failure reads differently on containerd, CRI-O and Docker (cri-dockerd).
+// They are scored in addition to the runtime-independent rootCauseSignals.
+var runtimeSignals = map[types.ContainerRuntime]map[types.RootCause][]signal{
+	types.RuntimeContainerd: {
+		types.RootCauseImageNotFound: {
+			// failed to resolve reference "registry.example.com/app:v2": registry.example.com/app:v2: not found
+			{": not found", 0.8},
+		},
+		types.RootCauseAuthFailure: {
+			{"failed to authorize", 1.0},
+			{"failed to fetch anonymous token", 1.0},
+			{"failed to fetch oauth token", 1.0},
+		},
+		types.RootCauseNetworkIssue: {
+			{"failed to do request", 0.7},
+		},
+		types.RootCauseManifestError: {
+			{"no match for platform in manifest", 1.0},
+		},
+		types.RootCauseNodeDiskPressure: {
+			{"failed to extract layer", 0.3},
+		},
+	},
+	types.RuntimeCRIO: {
+		types.RootCauseImageNotFound: {
+			{"name unknown", 1.0},
+			{"reading manifest", 0.3},
+		},
+		types.RootCauseAuthFailure: {
+			{"requested access to the resource is denied", 1.0},
+			{"unable to retrieve auth token", 1.0},
+			{"invalid username/password", 1.0},
+		},
+		types.RootCauseNetworkIssue: {
+			{"pinging container registry", 0.8},
+			{"pinging docker registry", 0.8},
+		},
+		types.RootCauseManifestError: {
+			{"no image found in manifest list for architecture", 1.0},
+			{"choosing image instance", 0.6},
+		},
+		types.RootCauseNodeDiskPressure: {
+			{"writing blob", 0.3},
+		},
+	},
+	types.RuntimeDocker: {
+		types.RootCauseAuthFailure: {
+			// Docker reports missing credentials and missing repositories the same way
+			{"may require 'docker login'", 1.0},
+			{"requested access to the resource is denied", 1.0},
+		},
+		types.RootCauseNetworkIssue: {
+			{"request canceled while waiting for connection", 1.0},
+			{"client.timeout exceeded while awaiting headers", 1.0},
+		},
+		types.RootCauseManifestError: {
+			{"cannot be used on this platform", 1.0},
+		},
+		types.RootCauseNodeDiskPressure: {
+			{"failed to register layer", 0.3},
+		},
+	},
+}
+
+// ForRuntime returns the rules merged with the message catalog of a container runtime
+// The receiver may be nil (built-in table only); it is not modified.
+func (rs *RuleSet) ForRuntime(runtime types.ContainerRuntime) *RuleSet {
+	catalog := runtimeSignals[runtime]
+	if len(catalog) == 0 {
+		return rs
+	}
+
+	if rs == nil {
+		// An empty rule set holds only the built-in table and always compiles
+		rs = &RuleSet{}
+		_ = rs.compile()
+	}
+
+	merged := *rs
+	merged.signals = make(map[types.RootCause][]signal, len(rs.signals))
+	for cause, signals := range rs.signals {
+		merged.signals[cause] = signals
+	}
+	for cause, signals := range catalog {
+		combined := make([]signal, 0, len(merged.signals[cause])+len(signals))
+		combined = append(combined, merged.signals[cause]...)
+		merged.signals[cause] = append(combined, signals...)
+	}
+	return &merged
+}
+
+// nodeRuntime returns the container runtime of a node and its version string
+// Nodes are looked up once per analyzer; without permission to read nodes the
+// runtime stays unknown and runtime-independent detection is used.
+func (a *Analyzer) nodeRuntime(ctx context.Context, nodeName string) (types.ContainerRuntime, string) {
+	if nodeName == "" {
+		return types.RuntimeUnknown, ""
+	}
+	if version, ok := a.runtimes.Load(nodeName); ok {
+		return k8s.ParseContainerRuntime(version.(string)), version.(string)
+	}
+
+	a.auditLogger.LogNodeGet(nodeName)
+	node, err := a.k8sClient.GetNode(ctx, nodeName)
+	if err != nil {
+		a.auditLogger.LogWarning(fmt.Sprintf("Container runtime of node %s unknown: %v", nodeName, err))
+		a.runtimes.Store(nodeName, "")
+		return types.RuntimeUnknown, ""
+	}
+
+	version := node.Status.NodeInfo.ContainerRuntimeVersion
+	a.runtimes.Store(nodeName, version)
+	return k8s.ParseContainerRuntime(version), version
+}
+
+// pullCommand returns the node-side command that pulls an image like the runtime does
+func pullCommand(runtime types.ContainerRuntime, image string) string {
+	switch runtime {
+	case types.RuntimeContainerd:
+		return fmt.Sprintf("crictl pull %s (or ctr -n k8s.io images pull %s)", image, image)
+	case types.RuntimeCRIO:
+		return fmt.Sprintf("crictl pull %s (or podman pull %s, which shares CRI-O's image store)", image, image)
+	default:
+		return fmt.Sprintf("docker pull %s", image)
+	}
+}
+
+// pruneCommand returns the node-side command that removes unused images
+func pruneCommand(runtime types.ContainerRuntime) string {
+	if runtime == types.RuntimeDocker {
+		return "docker image prune -a"
+	}
+	return "crictl rmi --prune"
+}
+
+// runtimeTLSRemediation returns the steps to trust a registry CA and allow plain HTTP for a runtime
+func runtimeTLSRemediation(runtime types.ContainerRuntime, registry string) []string {
+	switch runtime {
+	case types.RuntimeCRIO:
+		return []string{
+			fmt.Sprintf("For a private CA (CRI-O): copy the CA to /etc/containers/certs.d/%s/ca.crt on every node (no restart needed)", registry),
+			fmt.Sprintf("For a plain HTTP registry, declare it insecure in /etc/containers/registries.conf: [[registry]] location = \"%s\" insecure = true, then restart crio", registry),
+		}
+	case types.RuntimeDocker:
+		return []string{
+			fmt.Sprintf("For a private CA (Docker): copy the CA to /etc/docker/certs.d/%s/ca.crt on every node", registry),
+			fmt.Sprintf("For a plain HTTP registry, add \"%s\" to insecure-registries in /etc/docker/daemon.json and restart docker", registry),
+		}
+	default:
+		return []string{
+			fmt.Sprintf("For a private CA (containerd): copy the CA to /etc/containerd/certs.d/%s/ca.crt on every node", registry),
+			fmt.Sprintf("Reference it in /etc/containerd/certs.d/%s/hosts.toml: [host.\"https://%s\"] ca = \"/etc/containerd/certs.d/%s/ca.crt\"", registry, registry, registry),
+			"Ensure containerd uses the certs.d directory: config_path = \"/etc/containerd/certs.d\" under [plugins.\"io.containerd.grpc.v1.cri\".registry]",
+			fmt.Sprintf("For a plain HTTP registry, declare it insecure: server = \"http://%s\" in hosts.toml", registry),
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodGroup is a set of pods expected to fail for the same reason
+// Only the representative is analyzed; its findings are projected onto the members.
+type PodGroup struct {
+	Key            string
+	Representative *corev1.Pod
+	Members        []*corev1.Pod // Other pods in the group, excluding the representative
+}
+
+// SamplingKey identifies pods that share an owner, image set and waiting reasons
+// Pods without a controller get a key unique to the pod so they are never grouped.
+func SamplingKey(pod *corev1.Pod) string {
+	owner := "Pod/" + pod.Name
+	if ref := metav1.GetControllerOf(pod); ref != nil {
+		owner = ref.Kind + "/" + ref.Name
+	}
+
+	var images []string
+	for _, c := range pod.Spec.InitContainers {
+		images = append(images, c.Name+"="+c.Image)
+	}
+	for _, c := range pod.Spec.Containers {
+		images = append(images, c.Name+"="+c.Image)
+	}
+	sort.Strings(images)
+
+	var reasons []string
+	for _, cs := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		if cs.State.Waiting != nil {
+			reasons = append(reasons, cs.Name+"="+cs.State.Waiting.Reason)
+		}
+	}
+	sort.Strings(reasons)
+
+	return strings.Join([]string{
+		pod.Namespace,
+		owner,
+		strings.Join(images, ","),
+		strings.Join(reasons, ","),
+	}, "|")
+}
+
+// GroupPodsForSampling groups pods by SamplingKey
+// Pods are sorted first, so the representative of each group is its first pod
+// by name and groups are returned in representative order.
+func GroupPodsForSampling(pods []corev1.Pod) []PodGroup {
+	sorted := make([]corev1.Pod, len(pods))
+	copy(sorted, pods)
+	SortPods(sorted)
+
+	var groups []PodGroup
+	index := make(map[string]int)
+
+	for i := range sorted {
+		pod := &sorted[i]
+		key := SamplingKey(pod)
+		if gi, ok := index[key]; ok {
+			groups[gi].Members = append(groups[gi].Members, pod)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, PodGroup{Key: key, Representative: pod})
+	}
+
+	return groups
+}
+
+// ProjectReport copies a representative's report onto another pod of the same group
+// Findings are marked as inferred and reference the representative pod.
+func ProjectReport(report *types.AnalysisReport, representative, pod *corev1.Pod) *types.AnalysisReport {
+	if report == nil {
+		return nil
+	}
+
+	projected := *report
+	projected.TargetName = pod.Name
+	projected.Namespace = pod.Namespace
+	projected.Findings = make([]types.DiagnosticFinding, len(report.Findings))
+
+	source := fmt.Sprintf("%s/%s", representative.Namespace, representative.Name)
+	for i, finding := range report.Findings {
+		finding.PodName = pod.Name
+		finding.PodNamespace = pod.Namespace
+		finding.Inferred = true
+		finding.InferredFrom = source
+		projected.Findings[i] = finding
+	}
+
+	return &projected
+}
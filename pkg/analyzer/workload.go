@@ -0,0 +1,123 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+)
+
+// podCreatingKinds are the controllers that report pod creation failures as FailedCreate events
+var podCreatingKinds = map[string]bool{
+	"ReplicaSet":            true,
+	"StatefulSet":           true,
+	"DaemonSet":             true,
+	"Job":                   true,
+	"ReplicationController": true,
+}
+
+// AnalyzeAdmissionRejections finds workloads whose pods were rejected by a policy
+// Rejected pods are never created, so they cannot be found by listing pods; the
+// only trace is a FailedCreate event on the owning controller. One finding is
+// returned per controller, based on its most recent denial.
+func (a *Analyzer) AnalyzeAdmissionRejections(ctx context.Context, namespace string) ([]types.DiagnosticFinding, error) {
+	a.auditLogger.LogEventList(namespace)
+	eventList, err := a.k8sClient.ListEventsByReason(ctx, namespace, "FailedCreate")
+	if err != nil {
+		return nil, err
+	}
+
+	type workloadDenials struct {
+		subject string
+		events  []types.EventSummary
+		policy  *types.PolicyRejection
+	}
+	bySubject := make(map[string]*workloadDenials)
+
+	// Events are sorted oldest first, so the last parsed denial is the most recent
+	for i := range eventList.Items {
+		event := &eventList.Items[i]
+		if !podCreatingKinds[event.InvolvedObject.Kind] {
+			continue
+		}
+		policy := ParsePolicyRejection(event.Message)
+		if policy == nil {
+			continue
+		}
+
+		subject := fmt.Sprintf("%s/%s", event.InvolvedObject.Kind, event.InvolvedObject.Name)
+		denials, ok := bySubject[subject]
+		if !ok {
+			denials = &workloadDenials{subject: subject}
+			bySubject[subject] = denials
+		}
+		denials.events = append(denials.events, k8s.ConvertToEventSummary(eventList.Items[i:i+1], true)...)
+		denials.policy = policy
+	}
+
+	findings := make([]types.DiagnosticFinding, 0, len(bySubject))
+	for _, denials := range bySubject {
+		findings = append(findings, buildPolicyFinding(namespace, denials.subject, denials.policy, denials.events))
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].Subject < findings[j].Subject
+	})
+
+	return findings, nil
+}
+
+// buildPolicyFinding creates a workload-scoped POLICY_REJECTION finding
+func buildPolicyFinding(namespace, subject string, policy *types.PolicyRejection, events []types.EventSummary) types.DiagnosticFinding {
+	rootCause := types.RootCausePolicyRejection
+
+	failureCount := 0
+	for _, event := range events {
+		failureCount += event.Count
+	}
+
+	finding := types.DiagnosticFinding{
+		RootCause:        rootCause,
+		Severity:         rootCause.Severity(),
+		Scope:            types.FindingScopeWorkload,
+		Subject:          subject,
+		PodNamespace:     namespace,
+		Summary:          fmt.Sprintf("%s: %s", rootCause, rootCause.String()),
+		Details:          policy.Message,
+		RemediationSteps: policyRejectionRemediation(policy),
+		Events:           events,
+		Evidence: []types.Evidence{{
+			Source:  types.EvidenceSourceEvent,
+			Reason:  "FailedCreate",
+			Message: policy.Message,
+		}},
+		FailureCount: failureCount,
+		Policy:       policy,
+	}
+
+	if len(events) > 0 {
+		first, last := events[0].FirstSeen, events[len(events)-1].LastSeen
+		finding.FirstFailureTime = &first
+		finding.LastFailureTime = &last
+		finding.FailureDuration = formatDuration(last.Sub(first))
+	}
+
+	return finding
+}
+
+// AddWorkloadFindings appends workload- or node-scoped findings to a merged report
+func AddWorkloadFindings(report *types.AnalysisReport, findings []types.DiagnosticFinding) {
+	for _, finding := range findings {
+		report.Findings = append(report.Findings, finding)
+		report.Summary.RootCauseBreakdown[finding.RootCause]++
+		switch finding.Severity {
+		case types.SeverityHigh:
+			report.Summary.HighSeverityCount++
+		case types.SeverityMedium:
+			report.Summary.MediumSeverityCount++
+		case types.SeverityLow:
+			report.Summary.LowSeverityCount++
+		}
+	}
+}
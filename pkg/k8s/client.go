@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -14,8 +15,18 @@ import (
 
 // Client wraps the Kubernetes client with error handling
 type Client struct {
-	Clientset *kubernetes.Clientset
+	Clientset kubernetes.Interface
 	Config    *rest.Config
+
+	// Lazily discovered API availability
+	eventsAPIOnce sync.Once
+	eventsV1      bool
+}
+
+// ClientOptions tunes client-side rate limiting of the API client
+type ClientOptions struct {
+	QPS   float32 // Sustained queries per second (0 keeps the client-go default)
+	Burst int     // Maximum burst above QPS (0 keeps the client-go default)
 }
 
 // NewClient initializes Kubernetes client from kubeconfig
@@ -25,6 +36,12 @@ type Client struct {
 // 3. ~/.kube/config (default)
 // 4. In-cluster config (if running inside a pod)
 func NewClient(kubeconfigPath string) (*Client, error) {
+	return NewClientWithOptions(kubeconfigPath, ClientOptions{})
+}
+
+// NewClientWithOptions initializes Kubernetes client like NewClient and
+// applies the given rate limiting options to the REST config
+func NewClientWithOptions(kubeconfigPath string, opts ClientOptions) (*Client, error) {
 	var config *rest.Config
 	var err error
 
@@ -32,6 +49,7 @@ func NewClient(kubeconfigPath string) (*Client, error) {
 	if kubeconfigPath == "" {
 		config, err = rest.InClusterConfig()
 		if err == nil {
+			applyClientOptions(config, opts)
 			clientset, err := kubernetes.NewForConfig(config)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create in-cluster client: %w", err)
@@ -69,6 +87,7 @@ func NewClient(kubeconfigPath string) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to build config from kubeconfig %s: %w", kubeconfig, err)
 	}
+	applyClientOptions(config, opts)
 
 	// Create clientset
 	clientset, err := kubernetes.NewForConfig(config)
@@ -82,6 +101,16 @@ func NewClient(kubeconfigPath string) (*Client, error) {
 	}, nil
 }
 
+// applyClientOptions copies non-zero rate limiting options onto the REST config
+func applyClientOptions(config *rest.Config, opts ClientOptions) {
+	if opts.QPS > 0 {
+		config.QPS = opts.QPS
+	}
+	if opts.Burst > 0 {
+		config.Burst = opts.Burst
+	}
+}
+
 // Validate checks if client can communicate with the cluster
 func (c *Client) Validate() error {
 	if c.Clientset == nil {
@@ -4,15 +4,25 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/aboigues/k8t/pkg/output"
 	"github.com/aboigues/k8t/pkg/types"
 	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// eventsV1GroupVersion is the group/version of the structured Events API
+const eventsV1GroupVersion = "events.k8s.io/v1"
+
 // GetPodEvents fetches events related to a specific pod
+// Reads the events.k8s.io/v1 API when the cluster serves it and falls back to
+// core/v1 otherwise (or when RBAC only grants access to core events). Events
+// from the new API are converted to core/v1 form so callers handle one type.
+// Events are sorted oldest first using normalized timestamps (see EventFirstSeen).
 func (c *Client) GetPodEvents(ctx context.Context, namespace, podName string) (*corev1.EventList, error) {
 	// Validate inputs
 	if err := ValidateNamespace(namespace); err != nil {
@@ -22,13 +32,10 @@ func (c *Client) GetPodEvents(ctx context.Context, namespace, podName string) (*
 		return nil, fmt.Errorf("invalid pod name: %w", err)
 	}
 
-	// Create field selector for pod-specific events
-	fieldSelector := fmt.Sprintf("involvedObject.name=%s,involvedObject.kind=Pod", podName)
+	fieldSelectorCore := fmt.Sprintf("involvedObject.name=%s,involvedObject.kind=Pod", podName)
+	fieldSelectorV1 := fmt.Sprintf("regarding.name=%s,regarding.kind=Pod", podName)
 
-	// Fetch events from Kubernetes API
-	eventList, err := c.Clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
-		FieldSelector: fieldSelector,
-	})
+	eventList, err := c.listEvents(ctx, namespace, fieldSelectorCore, fieldSelectorV1)
 	if err != nil {
 		if k8serrors.IsForbidden(err) || k8serrors.IsUnauthorized(err) {
 			return nil, fmt.Errorf("insufficient permissions to list events in namespace '%s': %w", namespace, err)
@@ -36,14 +43,174 @@ func (c *Client) GetPodEvents(ctx context.Context, namespace, podName string) (*
 		return nil, fmt.Errorf("failed to list events for pod '%s' in namespace '%s': %w", podName, namespace, err)
 	}
 
-	// Sort events by timestamp (oldest first)
-	sort.Slice(eventList.Items, func(i, j int) bool {
-		return eventList.Items[i].FirstTimestamp.Time.Before(eventList.Items[j].FirstTimestamp.Time)
+	sortEvents(eventList.Items)
+	return eventList, nil
+}
+
+// ListEventsByReason fetches the events of a namespace with the given reason
+// Used for failures recorded on controllers rather than pods (e.g. FailedCreate
+// on a ReplicaSet when an admission webhook rejects its pods).
+func (c *Client) ListEventsByReason(ctx context.Context, namespace, reason string) (*corev1.EventList, error) {
+	if err := ValidateNamespace(namespace); err != nil {
+		return nil, fmt.Errorf("invalid namespace: %w", err)
+	}
+
+	fieldSelector := "reason=" + reason
+	eventList, err := c.listEvents(ctx, namespace, fieldSelector, fieldSelector)
+	if err != nil {
+		if k8serrors.IsForbidden(err) || k8serrors.IsUnauthorized(err) {
+			return nil, fmt.Errorf("insufficient permissions to list events in namespace '%s': %w", namespace, err)
+		}
+		return nil, fmt.Errorf("failed to list %s events in namespace '%s': %w", reason, namespace, err)
+	}
+
+	// Not every client honors field selectors; filter again
+	filtered := eventList.Items[:0]
+	for _, event := range eventList.Items {
+		if event.Reason == reason {
+			filtered = append(filtered, event)
+		}
+	}
+	eventList.Items = filtered
+
+	sortEvents(eventList.Items)
+	return eventList, nil
+}
+
+// listEvents lists events, preferring events.k8s.io/v1 and falling back to core/v1
+// The fallback also applies when RBAC only grants access to core events.
+func (c *Client) listEvents(ctx context.Context, namespace, fieldSelectorCore, fieldSelectorV1 string) (*corev1.EventList, error) {
+	if c.eventsV1Available() {
+		list, err := c.listEventsV1(ctx, namespace, fieldSelectorV1)
+		if err == nil {
+			return list, nil
+		}
+		if !k8serrors.IsForbidden(err) && !k8serrors.IsUnauthorized(err) && !k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	return c.Clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fieldSelectorCore,
+	})
+}
+
+// listEventsV1 lists events from the events.k8s.io/v1 API, converted to core/v1
+func (c *Client) listEventsV1(ctx context.Context, namespace, fieldSelector string) (*corev1.EventList, error) {
+	list, err := c.Clientset.EventsV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fieldSelector,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	eventList := &corev1.EventList{
+		ListMeta: list.ListMeta,
+		Items:    make([]corev1.Event, 0, len(list.Items)),
+	}
+	for i := range list.Items {
+		eventList.Items = append(eventList.Items, EventFromEventsV1(&list.Items[i]))
+	}
 
 	return eventList, nil
 }
 
+// sortEvents sorts events oldest first using normalized timestamps
+func sortEvents(events []corev1.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return EventFirstSeen(&events[i]).Before(EventFirstSeen(&events[j]))
+	})
+}
+
+// eventsV1Available reports whether the cluster serves events.k8s.io/v1
+// The discovery lookup is done once per client.
+func (c *Client) eventsV1Available() bool {
+	c.eventsAPIOnce.Do(func() {
+		resources, err := c.Clientset.Discovery().ServerResourcesForGroupVersion(eventsV1GroupVersion)
+		if err != nil || resources == nil {
+			return
+		}
+		for _, r := range resources.APIResources {
+			if r.Name == "events" {
+				c.eventsV1 = true
+				return
+			}
+		}
+	})
+	return c.eventsV1
+}
+
+// EventFromEventsV1 converts an events.k8s.io/v1 Event to its core/v1 form
+// The two APIs share storage; this mirrors the apiserver's own field mapping.
+func EventFromEventsV1(event *eventsv1.Event) corev1.Event {
+	converted := corev1.Event{
+		ObjectMeta:          event.ObjectMeta,
+		InvolvedObject:      event.Regarding,
+		Related:             event.Related,
+		Reason:              event.Reason,
+		Message:             event.Note,
+		Type:                event.Type,
+		Action:              event.Action,
+		Source:              event.DeprecatedSource,
+		FirstTimestamp:      event.DeprecatedFirstTimestamp,
+		LastTimestamp:       event.DeprecatedLastTimestamp,
+		Count:               event.DeprecatedCount,
+		EventTime:           event.EventTime,
+		ReportingController: event.ReportingController,
+		ReportingInstance:   event.ReportingInstance,
+	}
+
+	if event.Series != nil {
+		converted.Series = &corev1.EventSeries{
+			Count:            event.Series.Count,
+			LastObservedTime: event.Series.LastObservedTime,
+		}
+	}
+
+	return converted
+}
+
+// EventFirstSeen returns when an event was first observed
+// Legacy events carry FirstTimestamp; events emitted through events.k8s.io/v1
+// only carry EventTime, so both forms are normalized here.
+func EventFirstSeen(event *corev1.Event) time.Time {
+	switch {
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// EventLastSeen returns when an event was last observed
+// For event series this is the series' LastObservedTime.
+func EventLastSeen(event *corev1.Event) time.Time {
+	last := event.LastTimestamp.Time
+	if event.Series != nil && event.Series.LastObservedTime.Time.After(last) {
+		last = event.Series.LastObservedTime.Time
+	}
+	if last.IsZero() {
+		return EventFirstSeen(event)
+	}
+	return last
+}
+
+// EventCount returns how many times an event occurred
+// Series events report occurrences in Series.Count rather than Count; an
+// event without either occurred once.
+func EventCount(event *corev1.Event) int {
+	count := int(event.Count)
+	if event.Series != nil && int(event.Series.Count) > count {
+		count = int(event.Series.Count)
+	}
+	if count < 1 {
+		count = 1
+	}
+	return count
+}
+
 // FilterImagePullEvents filters events related to image pulling failures
 func FilterImagePullEvents(events []corev1.Event) []corev1.Event {
 	var filtered []corev1.Event
@@ -56,7 +223,8 @@ func FilterImagePullEvents(events []corev1.Event) []corev1.Event {
 		   reason == "ErrImagePull" ||
 		   reason == "ImagePullBackOff" ||
 		   reason == "FailedPull" ||
-		   reason == "InspectFailed" {
+		   reason == "InspectFailed" ||
+		   reason == "ErrImageNeverPull" {
 			filtered = append(filtered, event)
 		}
 	}
@@ -78,12 +246,13 @@ func ConvertToEventSummary(events []corev1.Event, redact bool) []types.EventSumm
 		}
 
 		summary := types.EventSummary{
-			Timestamp: event.LastTimestamp.Time,
+			Timestamp: EventLastSeen(&event),
 			Reason:    event.Reason,
 			Message:   message,
-			Count:     int(event.Count),
-			FirstSeen: event.FirstTimestamp.Time,
-			LastSeen:  event.LastTimestamp.Time,
+			Count:     EventCount(&event),
+			FirstSeen: EventFirstSeen(&event),
+			LastSeen:  EventLastSeen(&event),
+			Container: EventContainer(&event),
 		}
 
 		summaries = append(summaries, summary)
@@ -91,3 +260,15 @@ func ConvertToEventSummary(events []corev1.Event, redact bool) []types.EventSumm
 
 	return summaries
 }
+
+// EventContainer returns the container an event refers to, if any
+// The kubelet sets involvedObject.fieldPath to spec.containers{name} (or
+// spec.initContainers{name}) on events about a specific container.
+func EventContainer(event *corev1.Event) string {
+	fieldPath := event.InvolvedObject.FieldPath
+	start := strings.Index(fieldPath, "{")
+	if start < 0 || !strings.HasSuffix(fieldPath, "}") {
+		return ""
+	}
+	return fieldPath[start+1 : len(fieldPath)-1]
+}
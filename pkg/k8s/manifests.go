@@ -0,0 +1,137 @@
+package k8s
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// templateSpecPaths locates the pod spec of each kind that creates pods
+var templateSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"PodTemplate":           {"template", "spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// ManifestTemplate is the pod spec of an object read from a manifest
+type ManifestTemplate struct {
+	Source    string // File and number of the non-empty document, e.g. deploy.yaml#2
+	Kind      string
+	Namespace string
+	Name      string
+	Spec      corev1.PodSpec
+}
+
+// Manifests holds the objects of manifests that matter for image pulls
+// Secrets and ServiceAccounts are kept since a bundle may create the pull
+// secrets its own workloads reference.
+type Manifests struct {
+	Templates       []ManifestTemplate
+	Secrets         []corev1.Secret
+	ServiceAccounts []corev1.ServiceAccount
+}
+
+// ParseManifests reads multi-document YAML or JSON, as written by helm template or
+// kustomize build. Objects without a namespace are placed in namespace.
+func ParseManifests(r io.Reader, source, namespace string) (*Manifests, error) {
+	manifests := &Manifests{}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for doc := 1; ; doc++ {
+		// The reader skips empty documents, so doc counts objects, not separators
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return manifests, nil
+			}
+			return nil, fmt.Errorf("%s#%d: %w", source, doc, err)
+		}
+		// Empty documents, e.g. between two --- separators
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+
+		// Unstructured keeps integers as int64, as the converter expects
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw); err != nil {
+			return nil, fmt.Errorf("%s#%d: %w", source, doc, err)
+		}
+		if err := manifests.add(obj, fmt.Sprintf("%s#%d", source, doc), namespace); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// add records an object, or the items of a List
+func (m *Manifests) add(obj *unstructured.Unstructured, source, namespace string) error {
+	if obj.IsList() {
+		return obj.EachListItem(func(item runtime.Object) error {
+			return m.add(item.(*unstructured.Unstructured), source, namespace)
+		})
+	}
+
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(namespace)
+	}
+	switch obj.GetKind() {
+	case "Secret":
+		var secret corev1.Secret
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &secret); err != nil {
+			return fmt.Errorf("%s: Secret %s: %w", source, obj.GetName(), err)
+		}
+		m.Secrets = append(m.Secrets, secret)
+		return nil
+	case "ServiceAccount":
+		var sa corev1.ServiceAccount
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &sa); err != nil {
+			return fmt.Errorf("%s: ServiceAccount %s: %w", source, obj.GetName(), err)
+		}
+		m.ServiceAccounts = append(m.ServiceAccounts, sa)
+		return nil
+	}
+
+	template, ok, err := TemplateFromObject(obj, source)
+	if err != nil || !ok {
+		return err
+	}
+	m.Templates = append(m.Templates, template)
+	return nil
+}
+
+// TemplateFromObject returns the pod spec of a Pod, PodTemplate or workload
+// ok is false for kinds that do not create pods.
+func TemplateFromObject(obj *unstructured.Unstructured, source string) (template ManifestTemplate, ok bool, err error) {
+	kind := obj.GetKind()
+	path, ok := templateSpecPaths[kind]
+	if !ok {
+		return ManifestTemplate{}, false, nil
+	}
+	raw, found, err := unstructured.NestedMap(obj.Object, path...)
+	if err != nil || !found {
+		return ManifestTemplate{}, false, fmt.Errorf("%s: %s %s has no %s", source, kind, obj.GetName(), strings.Join(path, "."))
+	}
+	template = ManifestTemplate{Source: source, Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &template.Spec); err != nil {
+		return ManifestTemplate{}, false, fmt.Errorf("%s: %s %s: %w", source, kind, obj.GetName(), err)
+	}
+	return template, true, nil
+}
+
+// Merge appends the objects of other manifests
+func (m *Manifests) Merge(other *Manifests) {
+	m.Templates = append(m.Templates, other.Templates...)
+	m.Secrets = append(m.Secrets, other.Secrets...)
+	m.ServiceAccounts = append(m.ServiceAccounts, other.ServiceAccounts...)
+}
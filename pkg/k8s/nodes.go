@@ -0,0 +1,185 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// Kubelet defaults applied when the configuration does not set image GC thresholds
+const (
+	DefaultImageGCHighThresholdPercent int32 = 85
+	DefaultImageGCLowThresholdPercent  int32 = 80
+)
+
+// KubeletDiskConfig holds the kubelet settings that decide when images are garbage collected
+type KubeletDiskConfig struct {
+	ImageGCHighThresholdPercent *int32            `json:"imageGCHighThresholdPercent,omitempty"`
+	ImageGCLowThresholdPercent  *int32            `json:"imageGCLowThresholdPercent,omitempty"`
+	EvictionHard                map[string]string `json:"evictionHard,omitempty"`
+}
+
+// GetNode retrieves a node by name
+func (c *Client) GetNode(ctx context.Context, name string) (*corev1.Node, error) {
+	node, err := c.Clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+	return node, nil
+}
+
+// ListNodes lists the nodes of the cluster
+func (c *Client) ListNodes(ctx context.Context) ([]corev1.Node, error) {
+	list, err := c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	return list.Items, nil
+}
+
+// NodesWithImage returns the names of the nodes whose status lists an image
+// The kubelet reports at most 50 images per node (nodeStatusMaxImages), largest
+// first, so an image missing from the list may still be cached.
+func NodesWithImage(nodes []corev1.Node, image string) []string {
+	want := CanonicalImage(image)
+	var names []string
+	for _, node := range nodes {
+	images:
+		for _, cached := range node.Status.Images {
+			for _, name := range cached.Names {
+				if CanonicalImage(name) == want {
+					names = append(names, node.Name)
+					break images
+				}
+			}
+		}
+	}
+	return names
+}
+
+// NodeImageCache returns the nodes whose status lists an image, by tag or by digest
+// A reference with a digest is pulled by digest, so only the digest matches it;
+// a tag reference matches the tag, and the entry's digest is reported.
+func NodeImageCache(nodes []corev1.Node, image string) []types.CachedImage {
+	ref, err := types.ParseImageReference("", image)
+	if err != nil {
+		return nil
+	}
+	name := ref.Registry + "/" + ref.Repository
+
+	var cached []types.CachedImage
+	for _, node := range nodes {
+		for _, entry := range node.Status.Images {
+			match := types.CachedImage{Node: node.Name, SizeBytes: entry.SizeBytes}
+			for _, entryName := range entry.Names {
+				entryRef, err := types.ParseImageReference("", entryName)
+				if err != nil || entryRef.Registry+"/"+entryRef.Repository != name {
+					continue
+				}
+				if entryRef.IsDigest {
+					match.Digest = entryRef.Digest
+					match.ByDigest = match.ByDigest || (ref.IsDigest && entryRef.Digest == ref.Digest)
+				} else if !ref.IsDigest && entryRef.Tag == ref.Tag {
+					match.ByTag = true
+				}
+			}
+			if match.ByTag || match.ByDigest {
+				cached = append(cached, match)
+				break
+			}
+		}
+	}
+	return cached
+}
+
+// SchedulableNodes returns the nodes new pods can land on: Ready and not cordoned
+// Taints are not considered.
+func SchedulableNodes(nodes []corev1.Node) []corev1.Node {
+	var schedulable []corev1.Node
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		if cond := NodeCondition(&node, corev1.NodeReady); cond == nil || cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		schedulable = append(schedulable, node)
+	}
+	return schedulable
+}
+
+// CanonicalImage expands an image reference to registry/repository:tag (or @digest)
+// so short Docker Hub names match the fully qualified names nodes report.
+func CanonicalImage(image string) string {
+	ref, err := types.ParseImageReference("", image)
+	if err != nil {
+		return image
+	}
+	if ref.IsDigest {
+		return ref.Registry + "/" + ref.Repository + "@" + ref.Digest
+	}
+	return ref.Registry + "/" + ref.Repository + ":" + ref.Tag
+}
+
+// GetKubeletDiskConfig reads the image GC and eviction settings of a node's kubelet
+// The node status does not carry them, so they come from the kubelet /configz
+// endpoint through the apiserver node proxy (requires get on nodes/proxy).
+func (c *Client) GetKubeletDiskConfig(ctx context.Context, nodeName string) (*KubeletDiskConfig, error) {
+	restClient := c.Clientset.CoreV1().RESTClient()
+	if rc, ok := restClient.(*rest.RESTClient); !ok || rc == nil {
+		return nil, fmt.Errorf("kubelet configuration is not available through this client")
+	}
+
+	raw, err := restClient.Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource("proxy", "configz").
+		DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubelet configuration of node %s: %w", nodeName, err)
+	}
+
+	return ParseKubeletConfigz(raw)
+}
+
+// ParseKubeletConfigz extracts the disk settings from a kubelet /configz response
+func ParseKubeletConfigz(raw []byte) (*KubeletDiskConfig, error) {
+	var configz struct {
+		KubeletConfig KubeletDiskConfig `json:"kubeletconfig"`
+	}
+	if err := json.Unmarshal(raw, &configz); err != nil {
+		return nil, fmt.Errorf("failed to parse kubelet configuration: %w", err)
+	}
+	return &configz.KubeletConfig, nil
+}
+
+// ParseContainerRuntime identifies the runtime from a containerRuntimeVersion
+// such as containerd://1.7.13, cri-o://1.28.1 or docker://24.0.7 (cri-dockerd)
+func ParseContainerRuntime(version string) types.ContainerRuntime {
+	scheme, _, found := strings.Cut(version, "://")
+	if !found {
+		return types.RuntimeUnknown
+	}
+	switch runtime := types.ContainerRuntime(strings.ToLower(scheme)); runtime {
+	case types.RuntimeContainerd, types.RuntimeCRIO, types.RuntimeDocker:
+		return runtime
+	default:
+		return types.RuntimeUnknown
+	}
+}
+
+// NodeCondition returns the condition of the given type, if reported
+func NodeCondition(node *corev1.Node, conditionType corev1.NodeConditionType) *corev1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == conditionType {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/types"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// ResourceName returns the API resource of a patchable kind, e.g. deployments
+func ResourceName(kind string) string {
+	return strings.ToLower(kind) + "s"
+}
+
+// PodTemplateOwner returns the object whose pod template produced a pod
+// Pods of a Deployment resolve through their ReplicaSet to the Deployment;
+// pods without a controller resolve to themselves. Job and CronJob pod
+// templates are immutable or short-lived and are reported as unsupported.
+func (c *Client) PodTemplateOwner(ctx context.Context, pod *corev1.Pod) (types.ObjectReference, error) {
+	ref := types.ObjectReference{Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name}
+
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return ref, nil
+	}
+
+	switch owner.Kind {
+	case "ReplicaSet":
+		rs, err := c.Clientset.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return ref, fmt.Errorf("failed to get ReplicaSet %s: %w", owner.Name, err)
+		}
+		if deploy := metav1.GetControllerOf(rs); deploy != nil && deploy.Kind == "Deployment" {
+			return types.ObjectReference{Kind: "Deployment", Namespace: pod.Namespace, Name: deploy.Name}, nil
+		}
+		return types.ObjectReference{Kind: "ReplicaSet", Namespace: pod.Namespace, Name: owner.Name}, nil
+	case "StatefulSet", "DaemonSet":
+		return types.ObjectReference{Kind: owner.Kind, Namespace: pod.Namespace, Name: owner.Name}, nil
+	default:
+		return ref, fmt.Errorf("pods owned by a %s cannot be patched through their template; update the %s manifest instead", owner.Kind, owner.Kind)
+	}
+}
+
+// GetServiceAccount retrieves a service account
+func (c *Client) GetServiceAccount(ctx context.Context, namespace, name string) (*corev1.ServiceAccount, error) {
+	sa, err := c.Clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service account %s: %w", name, err)
+	}
+	return sa, nil
+}
+
+// GetConfigMap retrieves a ConfigMap by name
+func (c *Client) GetConfigMap(ctx context.Context, namespace, name string) (*corev1.ConfigMap, error) {
+	cm, err := c.Clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get configmap %s/%s: %w", namespace, name, err)
+	}
+	return cm, nil
+}
+
+// ListPullSecrets lists the registry credential secrets of a namespace
+func (c *Client) ListPullSecrets(ctx context.Context, namespace string) ([]corev1.Secret, error) {
+	list, err := c.Clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets in namespace %s: %w", namespace, err)
+	}
+
+	var secrets []corev1.Secret
+	for _, secret := range list.Items {
+		if secret.Type == corev1.SecretTypeDockerConfigJson || secret.Type == corev1.SecretTypeDockercfg {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets, nil
+}
+
+// PullSecretRegistries returns the registry hosts a pull secret has credentials for
+// Only the keys of the auths map are read; credentials are never returned.
+func PullSecretRegistries(secret *corev1.Secret) []string {
+	var auths map[string]json.RawMessage
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		var config struct {
+			Auths map[string]json.RawMessage `json:"auths"`
+		}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil
+		}
+		auths = config.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+			return nil
+		}
+	}
+
+	registries := make([]string, 0, len(auths))
+	for key := range auths {
+		registries = append(registries, normalizeRegistryHost(key))
+	}
+	return registries
+}
+
+// normalizeRegistryHost strips the scheme and path of a docker config auths key
+// Docker Hub credentials are stored under https://index.docker.io/v1/.
+func normalizeRegistryHost(key string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return host
+}
+
+// GetObject retrieves a patchable object
+func (c *Client) GetObject(ctx context.Context, ref types.ObjectReference) (runtime.Object, error) {
+	opts := metav1.GetOptions{}
+	var obj runtime.Object
+	var err error
+
+	switch ref.Kind {
+	case "Pod":
+		obj, err = c.Clientset.CoreV1().Pods(ref.Namespace).Get(ctx, ref.Name, opts)
+	case "ServiceAccount":
+		obj, err = c.Clientset.CoreV1().ServiceAccounts(ref.Namespace).Get(ctx, ref.Name, opts)
+	case "Deployment":
+		obj, err = c.Clientset.AppsV1().Deployments(ref.Namespace).Get(ctx, ref.Name, opts)
+	case "StatefulSet":
+		obj, err = c.Clientset.AppsV1().StatefulSets(ref.Namespace).Get(ctx, ref.Name, opts)
+	case "DaemonSet":
+		obj, err = c.Clientset.AppsV1().DaemonSets(ref.Namespace).Get(ctx, ref.Name, opts)
+	case "ReplicaSet":
+		obj, err = c.Clientset.AppsV1().ReplicaSets(ref.Namespace).Get(ctx, ref.Name, opts)
+	default:
+		return nil, fmt.Errorf("unsupported object kind %s", ref.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s: %w", ref.Kind, ref.Name, err)
+	}
+	return obj, nil
+}
+
+// PatchObject patches an object, only validating it server-side when dryRun is set
+func (c *Client) PatchObject(ctx context.Context, ref types.ObjectReference, patchType string, patch []byte, dryRun bool) (runtime.Object, error) {
+	pt := k8stypes.PatchType(patchType)
+	opts := metav1.PatchOptions{FieldManager: "k8t"}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	var obj runtime.Object
+	var err error
+
+	switch ref.Kind {
+	case "Pod":
+		obj, err = c.Clientset.CoreV1().Pods(ref.Namespace).Patch(ctx, ref.Name, pt, patch, opts)
+	case "ServiceAccount":
+		obj, err = c.Clientset.CoreV1().ServiceAccounts(ref.Namespace).Patch(ctx, ref.Name, pt, patch, opts)
+	case "Deployment":
+		obj, err = c.Clientset.AppsV1().Deployments(ref.Namespace).Patch(ctx, ref.Name, pt, patch, opts)
+	case "StatefulSet":
+		obj, err = c.Clientset.AppsV1().StatefulSets(ref.Namespace).Patch(ctx, ref.Name, pt, patch, opts)
+	case "DaemonSet":
+		obj, err = c.Clientset.AppsV1().DaemonSets(ref.Namespace).Patch(ctx, ref.Name, pt, patch, opts)
+	case "ReplicaSet":
+		obj, err = c.Clientset.AppsV1().ReplicaSets(ref.Namespace).Patch(ctx, ref.Name, pt, patch, opts)
+	default:
+		return nil, fmt.Errorf("unsupported object kind %s", ref.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch %s %s: %w", ref.Kind, ref.Name, err)
+	}
+	return obj, nil
+}
+
+// ObjectYAML renders an object's metadata and spec for diffing
+// Server-managed fields (status, managedFields, resourceVersion, ...) are
+// dropped so a diff only shows what a patch changes.
+func ObjectYAML(obj runtime.Object) (string, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return "", err
+	}
+
+	delete(content, "status")
+	if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+		for _, field := range []string{"managedFields", "resourceVersion", "generation", "uid", "creationTimestamp"} {
+			delete(metadata, field)
+		}
+	}
+
+	out, err := yaml.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -18,6 +18,26 @@ type PodInfo struct {
 	ContainerStatuses []corev1.ContainerStatus
 }
 
+// ImagePullWaitingReasons lists the kubelet container waiting reasons caused by image pulling
+var ImagePullWaitingReasons = []string{
+	"ImagePullBackOff",          // Back-off after repeated pull failures
+	"ErrImagePull",              // Generic pull failure
+	"ErrImageNeverPull",         // imagePullPolicy: Never and image not present on node
+	"InvalidImageName",          // Image reference cannot be parsed
+	"RegistryUnavailable",       // Registry could not be reached
+	"SignatureValidationFailed", // Runtime signature policy rejected the image
+}
+
+// IsImagePullWaitingReason reports whether a container waiting reason is caused by image pulling
+func IsImagePullWaitingReason(reason string) bool {
+	for _, r := range ImagePullWaitingReasons {
+		if reason == r {
+			return true
+		}
+	}
+	return false
+}
+
 // GetPod fetches a single pod by name in a namespace
 func (c *Client) GetPod(ctx context.Context, namespace, podName string) (*corev1.Pod, error) {
 	// Validate inputs using existing validation functions
@@ -32,7 +52,7 @@ func (c *Client) GetPod(ctx context.Context, namespace, podName string) (*corev1
 	pod, err := c.Clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			return nil, fmt.Errorf("pod '%s' not found in namespace '%s'", podName, namespace)
+			return nil, &apiError{message: fmt.Sprintf("pod '%s' not found in namespace '%s'", podName, namespace), err: err}
 		}
 		if k8serrors.IsForbidden(err) || k8serrors.IsUnauthorized(err) {
 			return nil, fmt.Errorf("insufficient permissions to get pod '%s' in namespace '%s': %w", podName, namespace, err)
@@ -43,6 +63,15 @@ func (c *Client) GetPod(ctx context.Context, namespace, podName string) (*corev1
 	return pod, nil
 }
 
+// apiError replaces the message of an API error while keeping it for k8serrors checks
+type apiError struct {
+	message string
+	err     error
+}
+
+func (e *apiError) Error() string { return e.message }
+func (e *apiError) Unwrap() error { return e.err }
+
 // ListPods lists all pods in a namespace
 func (c *Client) ListPods(ctx context.Context, namespace string) (*corev1.PodList, error) {
 	// Validate namespace
@@ -63,6 +92,7 @@ func (c *Client) ListPods(ctx context.Context, namespace string) (*corev1.PodLis
 }
 
 // FilterPodsWithImagePullBackOff filters pods with ImagePullBackOff status
+// Any image pull related waiting reason counts (see ImagePullWaitingReasons)
 func FilterPodsWithImagePullBackOff(pods []corev1.Pod) []corev1.Pod {
 	var filtered []corev1.Pod
 
@@ -74,10 +104,9 @@ func FilterPodsWithImagePullBackOff(pods []corev1.Pod) []corev1.Pod {
 		allStatuses = append(allStatuses, pod.Status.InitContainerStatuses...)
 
 		for _, containerStatus := range allStatuses {
-			// Check waiting state for ImagePullBackOff or ErrImagePull
+			// Check waiting state for any image pull related reason
 			if containerStatus.State.Waiting != nil {
-				reason := containerStatus.State.Waiting.Reason
-				if reason == "ImagePullBackOff" || reason == "ErrImagePull" {
+				if IsImagePullWaitingReason(containerStatus.State.Waiting.Reason) {
 					hasImagePullBackOff = true
 					break
 				}
@@ -85,8 +114,7 @@ func FilterPodsWithImagePullBackOff(pods []corev1.Pod) []corev1.Pod {
 
 			// Also check last terminated state (could have failed before)
 			if containerStatus.LastTerminationState.Waiting != nil {
-				reason := containerStatus.LastTerminationState.Waiting.Reason
-				if reason == "ImagePullBackOff" || reason == "ErrImagePull" {
+				if IsImagePullWaitingReason(containerStatus.LastTerminationState.Waiting.Reason) {
 					hasImagePullBackOff = true
 					break
 				}
@@ -107,37 +135,46 @@ func GetContainerImages(pod *corev1.Pod) []types.ImageReference {
 
 	// Extract images from regular containers
 	for _, container := range pod.Spec.Containers {
-		imgRef, err := types.ParseImageReference(container.Name, container.Image)
-		if err != nil {
-			// Log error but continue with other containers
-			// In production, this would use the audit logger
-			continue
-		}
-		imageRefs = append(imageRefs, *imgRef)
+		imageRefs = append(imageRefs, containerImage(container))
 	}
 
 	// Extract images from init containers
 	for _, container := range pod.Spec.InitContainers {
-		imgRef, err := types.ParseImageReference(container.Name, container.Image)
-		if err != nil {
-			// Log error but continue with other containers
-			continue
-		}
-		imageRefs = append(imageRefs, *imgRef)
+		imageRefs = append(imageRefs, containerImage(container))
 	}
 
 	return imageRefs
 }
 
+// GetEphemeralContainerImages extracts the image references of ephemeral
+// containers, added by kubectl debug; they are not part of the workload
+func GetEphemeralContainerImages(pod *corev1.Pod) []types.ImageReference {
+	var imageRefs []types.ImageReference
+	for _, container := range pod.Spec.EphemeralContainers {
+		imageRefs = append(imageRefs, containerImage(corev1.Container(container.EphemeralContainerCommon)))
+	}
+	return imageRefs
+}
+
+// containerImage parses a container's image; invalid references are kept with
+// their parse error so INVALID_IMAGE_NAME can report what is wrong
+func containerImage(container corev1.Container) types.ImageReference {
+	imgRef, err := types.ParseImageReference(container.Name, container.Image)
+	if err != nil {
+		return types.ImageReference{ContainerName: container.Name, FullReference: container.Image, ParseError: err.Error()}
+	}
+	return *imgRef
+}
+
 // GetAffectedContainers returns names of containers with ImagePullBackOff
+// or any other image pull related waiting reason (see ImagePullWaitingReasons)
 func GetAffectedContainers(pod *corev1.Pod) []string {
 	var affectedContainers []string
 
 	// Check all container statuses
 	for _, containerStatus := range pod.Status.ContainerStatuses {
 		if containerStatus.State.Waiting != nil {
-			reason := containerStatus.State.Waiting.Reason
-			if reason == "ImagePullBackOff" || reason == "ErrImagePull" {
+			if IsImagePullWaitingReason(containerStatus.State.Waiting.Reason) {
 				affectedContainers = append(affectedContainers, containerStatus.Name)
 			}
 		}
@@ -146,8 +183,7 @@ func GetAffectedContainers(pod *corev1.Pod) []string {
 	// Check init container statuses
 	for _, containerStatus := range pod.Status.InitContainerStatuses {
 		if containerStatus.State.Waiting != nil {
-			reason := containerStatus.State.Waiting.Reason
-			if reason == "ImagePullBackOff" || reason == "ErrImagePull" {
+			if IsImagePullWaitingReason(containerStatus.State.Waiting.Reason) {
 				affectedContainers = append(affectedContainers, containerStatus.Name)
 			}
 		}
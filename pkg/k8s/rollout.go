@@ -0,0 +1,62 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RevisionAnnotation is set by the Deployment controller on each ReplicaSet it owns
+const RevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// GetReplicaSet retrieves a ReplicaSet
+func (c *Client) GetReplicaSet(ctx context.Context, namespace, name string) (*appsv1.ReplicaSet, error) {
+	rs, err := c.Clientset.AppsV1().ReplicaSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ReplicaSet %s: %w", name, err)
+	}
+	return rs, nil
+}
+
+// ListDeploymentReplicaSets lists the ReplicaSets controlled by a Deployment
+// ReplicaSets are matched by owner reference rather than labels, since
+// pod-template-hash labels differ per revision.
+func (c *Client) ListDeploymentReplicaSets(ctx context.Context, namespace, deployment string) ([]appsv1.ReplicaSet, error) {
+	list, err := c.Clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ReplicaSets in namespace %s: %w", namespace, err)
+	}
+
+	var owned []appsv1.ReplicaSet
+	for _, rs := range list.Items {
+		if owner := metav1.GetControllerOf(&rs); owner != nil && owner.Kind == "Deployment" && owner.Name == deployment {
+			owned = append(owned, rs)
+		}
+	}
+	return owned, nil
+}
+
+// ReplicaSetRevision returns the Deployment revision of a ReplicaSet, or 0 when unset
+func ReplicaSetRevision(rs *appsv1.ReplicaSet) int64 {
+	revision, err := strconv.ParseInt(rs.Annotations[RevisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+// TemplateImages maps container and init container names to their images
+func TemplateImages(spec *corev1.PodSpec) map[string]string {
+	images := make(map[string]string, len(spec.InitContainers)+len(spec.Containers))
+	for _, c := range spec.InitContainers {
+		images[c.Name] = c.Image
+	}
+	for _, c := range spec.Containers {
+		images[c.Name] = c.Image
+	}
+	return images
+}
@@ -3,6 +3,7 @@ package output
 import (
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/aboigues/k8t/pkg/types"
@@ -11,9 +12,12 @@ import (
 )
 
 // AuditLogger provides structured logging for cluster access audit trail (SR-004)
+// It is safe for concurrent use by multiple analysis workers
 type AuditLogger struct {
 	logger  *zap.Logger
+	mu      sync.Mutex
 	entries []types.AuditEntry
+	discard bool // Log entries without keeping them, for long-running servers
 }
 
 // NewAuditLogger creates a new audit logger
@@ -65,7 +69,11 @@ func (a *AuditLogger) LogResourceAccess(resourceType, resourceName, namespace, o
 		Operation:    operation,
 	}
 
-	a.entries = append(a.entries, entry)
+	a.mu.Lock()
+	if !a.discard {
+		a.entries = append(a.entries, entry)
+	}
+	a.mu.Unlock()
 
 	// Log to stderr
 	a.logger.Info("cluster_access",
@@ -76,6 +84,15 @@ func (a *AuditLogger) LogResourceAccess(resourceType, resourceName, namespace, o
 	)
 }
 
+// DiscardEntries stops keeping entries for GetAuditEntries; they are still logged
+// A server running indefinitely would otherwise grow its audit log without bound.
+func (a *AuditLogger) DiscardEntries() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.discard = true
+	a.entries = nil
+}
+
 // LogPodGet logs pod retrieval
 func (a *AuditLogger) LogPodGet(podName, namespace string) {
 	a.LogResourceAccess("pods", podName, namespace, "get")
@@ -91,11 +108,60 @@ func (a *AuditLogger) LogEventList(namespace string) {
 	a.LogResourceAccess("events", "", namespace, "list")
 }
 
+// LogNodeGet logs node retrieval (for node disk diagnostics)
+func (a *AuditLogger) LogNodeGet(nodeName string) {
+	a.LogResourceAccess("nodes", nodeName, "", "get")
+}
+
+// LogNodeList logs node listing (image cache lookups)
+func (a *AuditLogger) LogNodeList() {
+	a.LogResourceAccess("nodes", "", "", "list")
+}
+
+// LogReplicaSetList logs ReplicaSet listing (rollout history)
+func (a *AuditLogger) LogReplicaSetList(namespace string) {
+	a.LogResourceAccess("replicasets", "", namespace, "list")
+}
+
+// LogKubeletConfigGet logs kubelet configuration retrieval through the node proxy
+func (a *AuditLogger) LogKubeletConfigGet(nodeName string) {
+	a.LogResourceAccess("nodes/proxy", nodeName, "", "get")
+}
+
 // LogSecretGet logs secret retrieval (for imagePullSecrets validation)
 func (a *AuditLogger) LogSecretGet(secretName, namespace string) {
 	a.LogResourceAccess("secrets", secretName, namespace, "get")
 }
 
+// LogSecretList logs listing of pull secrets (names and registries only are reported)
+func (a *AuditLogger) LogSecretList(namespace string) {
+	a.LogResourceAccess("secrets", "", namespace, "list")
+}
+
+// LogServiceAccountGet logs service account retrieval
+func (a *AuditLogger) LogServiceAccountGet(name, namespace string) {
+	a.LogResourceAccess("serviceaccounts", name, namespace, "get")
+}
+
+// LogConfigMapGet logs ConfigMap retrieval (mirror configuration)
+func (a *AuditLogger) LogConfigMapGet(name, namespace string) {
+	a.LogResourceAccess("configmaps", name, namespace, "get")
+}
+
+// LogObjectGet logs retrieval of a pod owner or remediation target
+func (a *AuditLogger) LogObjectGet(resourceType, name, namespace string) {
+	a.LogResourceAccess(resourceType, name, namespace, "get")
+}
+
+// LogPatch logs a write: every remediation patch, including server-side dry-runs
+func (a *AuditLogger) LogPatch(resourceType, name, namespace string, dryRun bool) {
+	operation := "patch"
+	if dryRun {
+		operation = "patch (dry-run)"
+	}
+	a.LogResourceAccess(resourceType, name, namespace, operation)
+}
+
 // LogAnalysisStart logs the beginning of analysis
 func (a *AuditLogger) LogAnalysisStart(targetType types.TargetType, targetName, namespace string) {
 	a.logger.Info("analysis_start",
@@ -132,7 +198,9 @@ func (a *AuditLogger) LogDebug(message string, fields ...zap.Field) {
 
 // GetAuditEntries returns all recorded audit entries
 func (a *AuditLogger) GetAuditEntries() []types.AuditEntry {
-	return a.entries
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]types.AuditEntry(nil), a.entries...)
 }
 
 // Close flushes and closes the logger
@@ -0,0 +1,92 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// FormatImageDrift writes the image drift of workloads in the specified format
+func FormatImageDrift(drifts []types.ImageDrift, format OutputFormat, noColor bool, w io.Writer) error {
+	switch format {
+	case FormatTypeText:
+		return formatDriftText(drifts, noColor, w)
+	case FormatTypeJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(drifts)
+	case FormatTypeYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(drifts)
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// formatDriftText renders each drifting container with its digests and pin patch
+func formatDriftText(drifts []types.ImageDrift, noColor bool, w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString(formatHeader("IMAGE DRIFT REPORT", noColor))
+	if len(drifts) == 0 {
+		b.WriteString(colorize("✓ Every workload runs a single digest of each image", colorGreen, noColor))
+		b.WriteString("\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	for i, drift := range drifts {
+		kinds := make([]string, 0, len(drift.Kinds))
+		for _, kind := range drift.Kinds {
+			kinds = append(kinds, string(kind))
+		}
+		b.WriteString(formatSection(fmt.Sprintf("[%d] %s %s/%s, container %s", i+1, drift.Kind, drift.Namespace, drift.Name, drift.Container), noColor))
+		b.WriteString(formatField("Severity", colorize(string(drift.Severity), getSeverityColor(drift.Severity), noColor), noColor))
+		b.WriteString(formatField("Drift", strings.Join(kinds, ", "), noColor))
+		b.WriteString(formatField("Image", fmt.Sprintf("%s (%d replicas)", drift.Image, drift.Replicas), noColor))
+		for _, detail := range drift.Details {
+			b.WriteString(fmt.Sprintf("  • %s\n", detail))
+		}
+
+		if len(drift.Digests) > 0 {
+			b.WriteString("  Running digests:\n")
+			for _, digest := range drift.Digests {
+				b.WriteString(fmt.Sprintf("    %s: %s\n", digest.Digest, strings.Join(digest.Pods, ", ")))
+			}
+		}
+		if drift.RegistryDigest != "" {
+			b.WriteString(fmt.Sprintf("  Registry digest: %s\n", drift.RegistryDigest))
+		} else if drift.RegistryError != "" {
+			b.WriteString(fmt.Sprintf("  Registry digest: unknown (%s)\n", drift.RegistryError))
+		}
+
+		if drift.PinnedImage != "" {
+			b.WriteString(formatField("Pin", drift.PinnedImage, noColor))
+		}
+		if action := drift.Action; action != nil {
+			target := action.Target
+			b.WriteString(fmt.Sprintf("  kubectl patch %s/%s -n %s --type strategic -p '%s'\n",
+				strings.ToLower(target.Kind), target.Name, target.Namespace, action.Patch))
+		}
+		b.WriteString("\n")
+	}
+
+	counts := make(map[types.DriftKind]int)
+	for _, drift := range drifts {
+		for _, kind := range drift.Kinds {
+			counts[kind]++
+		}
+	}
+	b.WriteString(formatDivider(noColor))
+	b.WriteString(fmt.Sprintf("Drifting containers: %d (%d with replicas on different digests, %d with a moved tag, %d on :latest)\n",
+		len(drifts), counts[types.DriftReplicasDiffer], counts[types.DriftTagMoved], counts[types.DriftLatestTag]))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
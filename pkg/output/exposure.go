@@ -0,0 +1,73 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// FormatImageExposure writes the registry outage exposure of workloads in the specified format
+func FormatImageExposure(exposures []types.WorkloadExposure, cache bool, format OutputFormat, noColor bool, w io.Writer) error {
+	switch format {
+	case FormatTypeText:
+		return formatExposureText(exposures, cache, noColor, w)
+	case FormatTypeJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(exposures)
+	case FormatTypeYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(exposures)
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// formatExposureText renders one line per workload, followed by its images
+func formatExposureText(exposures []types.WorkloadExposure, cache bool, noColor bool, w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString(formatHeader("REGISTRY OUTAGE EXPOSURE", noColor))
+	if len(exposures) == 0 {
+		b.WriteString("No pods found.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	counts := make(map[types.Severity]int)
+	for _, exposure := range exposures {
+		counts[exposure.Exposure]++
+
+		level := string(exposure.Exposure)
+		if level == "" {
+			level = "UNRATED"
+		}
+		b.WriteString(fmt.Sprintf("%s %s %s/%s (%d pods): %s\n",
+			colorize(fmt.Sprintf("%-7s", level), getSeverityColor(exposure.Exposure), noColor),
+			exposure.Kind, exposure.Namespace, exposure.Name, exposure.Pods, exposure.Reason))
+		for _, image := range exposure.Images {
+			b.WriteString(fmt.Sprintf("        %s: %s (imagePullPolicy %s)\n", image.Container, image.Image, image.PullPolicy))
+			if cache {
+				b.WriteString(fmt.Sprintf("          %s\n", image.Prediction))
+			}
+		}
+	}
+
+	b.WriteString("\n")
+	b.WriteString(formatDivider(noColor))
+	b.WriteString(fmt.Sprintf("Workloads: %d (%d HIGH, %d MEDIUM, %d LOW",
+		len(exposures), counts[types.SeverityHigh], counts[types.SeverityMedium], counts[types.SeverityLow]))
+	if unrated := counts[""]; unrated > 0 {
+		b.WriteString(fmt.Sprintf(", %d unrated without --cache", unrated))
+	}
+	b.WriteString(")\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,85 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// FormatFixResults writes the outcome of k8t fix in the specified format
+func FormatFixResults(results []types.ActionResult, apply bool, format OutputFormat, noColor bool, w io.Writer) error {
+	switch format {
+	case FormatTypeText:
+		return formatFixText(results, apply, noColor, w)
+	case FormatTypeJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	case FormatTypeYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(results)
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// formatFixText renders each action with its diff and validation outcome
+func formatFixText(results []types.ActionResult, apply bool, noColor bool, w io.Writer) error {
+	var b strings.Builder
+
+	for i, result := range results {
+		action := result.Action
+		b.WriteString(formatSection(fmt.Sprintf("[%d] %s", i+1, action.Kind), noColor))
+		b.WriteString(formatField("Target", fmt.Sprintf("%s %s/%s", action.Target.Kind, action.Target.Namespace, action.Target.Name), noColor))
+		b.WriteString(formatField("Root Cause", string(action.RootCause), noColor))
+		b.WriteString(formatField("Change", action.Description, noColor))
+
+		if result.Diff != "" {
+			b.WriteString("\n")
+			for _, line := range strings.Split(strings.TrimSuffix(result.Diff, "\n"), "\n") {
+				b.WriteString(colorizeDiffLine(line, noColor))
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString("\n")
+
+		switch {
+		case result.Error != "":
+			b.WriteString(colorize("✗ "+result.Error, colorRed, noColor))
+		case result.Applied:
+			b.WriteString(colorize("✓ Applied", colorGreen, noColor))
+		case result.Validated:
+			b.WriteString(colorize("✓ Validated by server-side dry-run (not applied)", colorGreen, noColor))
+		}
+		b.WriteString("\n\n")
+	}
+
+	if !apply && len(results) > 0 {
+		b.WriteString("Re-run with --apply to apply the validated changes.\n")
+	}
+
+	_, err := w.Write([]byte(b.String()))
+	return err
+}
+
+// colorizeDiffLine colors added lines green, removed lines red and hunk headers blue
+func colorizeDiffLine(line string, noColor bool) string {
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		return colorize(line, colorBold, noColor)
+	case strings.HasPrefix(line, "@@"):
+		return colorize(line, colorBlue, noColor)
+	case strings.HasPrefix(line, "+"):
+		return colorize(line, colorGreen, noColor)
+	case strings.HasPrefix(line, "-"):
+		return colorize(line, colorRed, noColor)
+	default:
+		return line
+	}
+}
@@ -14,6 +14,7 @@ const (
 	FormatTypeText OutputFormat = "text"
 	FormatTypeJSON OutputFormat = "json"
 	FormatTypeYAML OutputFormat = "yaml"
+	FormatTypeCSV  OutputFormat = "csv" // Tabular listings only (k8t images)
 )
 
 // Format writes the analysis report in the specified format
@@ -0,0 +1,144 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aboigues/k8t/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// inventoryColumns are the columns of the table and CSV image inventories
+var inventoryColumns = []string{"REGISTRY", "REPOSITORY", "TAG/DIGEST", "IMAGE ID", "WORKLOADS", "PODS", "PULL SECRETS"}
+
+// ParseInventoryFormat converts a string to the OutputFormat of a tabular listing:
+// table (text), json, yaml or csv
+func ParseInventoryFormat(s string) (OutputFormat, error) {
+	switch s {
+	case "table", "text", "":
+		return FormatTypeText, nil
+	case "csv":
+		return FormatTypeCSV, nil
+	case "json", "yaml", "yml":
+		return ParseFormat(s)
+	default:
+		return "", fmt.Errorf("unsupported format '%s': must be one of: table, json, yaml, csv", s)
+	}
+}
+
+// FormatImageInventory writes the image inventory in the specified format
+func FormatImageInventory(inventory []types.ImageInventoryEntry, format OutputFormat, w io.Writer) error {
+	switch format {
+	case FormatTypeText:
+		return formatInventoryTable(inventory, w)
+	case FormatTypeCSV:
+		return formatInventoryCSV(inventory, w)
+	case FormatTypeJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(inventory)
+	case FormatTypeYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(inventory)
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// formatInventoryTable renders one row per image, abbreviating image IDs and workload lists
+func formatInventoryTable(inventory []types.ImageInventoryEntry, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(inventoryColumns, "\t"))
+	for _, entry := range inventory {
+		imageID := "-"
+		if len(entry.ImageIDs) > 0 {
+			imageID = shortImageID(entry.ImageIDs[0])
+			if len(entry.ImageIDs) > 1 {
+				imageID += fmt.Sprintf(" (+%d)", len(entry.ImageIDs)-1)
+			}
+		}
+		workloads := workloadNames(entry.Workloads)
+		if len(workloads) > 2 {
+			workloads = append(workloads[:2], fmt.Sprintf("+%d more", len(entry.Workloads)-2))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			orDash(entry.Registry), orDash(entry.Repository), inventoryVersion(entry), imageID,
+			strings.Join(workloads, ", "), entry.Pods, orDash(strings.Join(entry.PullSecrets, ", ")))
+	}
+	return tw.Flush()
+}
+
+// formatInventoryCSV renders one record per image with complete values; lists are joined by ";"
+func formatInventoryCSV(inventory []types.ImageInventoryEntry, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := append([]string{"IMAGE"}, inventoryColumns...)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, entry := range inventory {
+		record := []string{
+			entry.Image,
+			entry.Registry,
+			entry.Repository,
+			inventoryVersion(entry),
+			strings.Join(entry.ImageIDs, ";"),
+			strings.Join(workloadNames(entry.Workloads), ";"),
+			strconv.Itoa(entry.Pods),
+			strings.Join(entry.PullSecrets, ";"),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// inventoryVersion renders the tag and digest of an entry, or its parse error
+func inventoryVersion(entry types.ImageInventoryEntry) string {
+	switch {
+	case entry.ParseError != "":
+		return "invalid: " + entry.Image
+	case entry.Tag != "" && entry.Digest != "":
+		return entry.Tag + "@" + entry.Digest
+	case entry.Digest != "":
+		return "@" + entry.Digest
+	default:
+		return entry.Tag
+	}
+}
+
+// workloadNames renders workloads as Kind/namespace/name(pods)
+func workloadNames(workloads []types.AffectedWorkload) []string {
+	names := make([]string, 0, len(workloads))
+	for _, workload := range workloads {
+		names = append(names, fmt.Sprintf("%s/%s/%s(%d)", workload.Kind, workload.Namespace, workload.Name, workload.Pods))
+	}
+	return names
+}
+
+// shortImageID abbreviates an ImageID to its digest's first 12 hex characters
+func shortImageID(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		imageID = imageID[i+1:]
+	}
+	imageID = strings.TrimPrefix(strings.TrimPrefix(imageID, "docker-pullable://"), "docker://")
+	if algorithm, encoded, ok := strings.Cut(imageID, ":"); ok && len(encoded) > 12 {
+		return algorithm + ":" + encoded[:12]
+	}
+	return imageID
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aboigues/k8t/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// FormatLintFindings writes the findings of k8t lint in the specified format
+func FormatLintFindings(findings []types.LintFinding, templates int, format OutputFormat, noColor bool, w io.Writer) error {
+	switch format {
+	case FormatTypeText:
+		return formatLintText(findings, templates, noColor, w)
+	case FormatTypeJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(findings)
+	case FormatTypeYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(findings)
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// formatLintText renders each finding with its manifest source, message and remediation
+func formatLintText(findings []types.LintFinding, templates int, noColor bool, w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString(formatHeader("MANIFEST LINT", noColor))
+	if len(findings) == 0 {
+		b.WriteString(colorize(fmt.Sprintf("✓ No image pull failure predicted for %d pod templates", templates), colorGreen, noColor))
+		b.WriteString("\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	counts := make(map[types.Severity]int)
+	for i, finding := range findings {
+		counts[finding.Severity]++
+		b.WriteString(formatSection(fmt.Sprintf("[%d] %s", i+1, string(finding.RootCause)), noColor))
+		b.WriteString(formatField("Severity", colorize(string(finding.Severity), getSeverityColor(finding.Severity), noColor), noColor))
+		b.WriteString(formatField("Source", finding.Source, noColor))
+		b.WriteString(formatField("Workload", fmt.Sprintf("%s %s/%s", finding.Kind, finding.Namespace, finding.Name), noColor))
+		if finding.Container != "" {
+			b.WriteString(formatField("Container", fmt.Sprintf("%s (%s)", finding.Container, finding.Image), noColor))
+		}
+		b.WriteString(formatField("Problem", finding.Message, noColor))
+		for _, step := range finding.Remediation {
+			b.WriteString(fmt.Sprintf("  • %s\n", step))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString(formatDivider(noColor))
+	b.WriteString(fmt.Sprintf("Findings: %d in %d pod templates (%d HIGH, %d MEDIUM, %d LOW)\n",
+		len(findings), templates, counts[types.SeverityHigh], counts[types.SeverityMedium], counts[types.SeverityLow]))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
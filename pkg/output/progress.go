@@ -0,0 +1,70 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Progress renders a single-line "label: done/total" indicator
+// Updates are rewritten in place with a carriage return, so the indicator
+// should only be enabled when writing to an interactive terminal
+type Progress struct {
+	mu      sync.Mutex
+	w       io.Writer
+	label   string
+	enabled bool
+	written bool
+}
+
+// NewProgress creates a progress indicator writing to w
+// A disabled indicator accepts updates but never writes anything
+func NewProgress(w io.Writer, label string, enabled bool) *Progress {
+	return &Progress{
+		w:       w,
+		label:   label,
+		enabled: enabled && w != nil,
+	}
+}
+
+// Update reports that done out of total items have completed
+// Safe to call from multiple goroutines
+func (p *Progress) Update(done, total int) {
+	if p == nil || !p.enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fmt.Fprintf(p.w, "\r%s: %d/%d", p.label, done, total)
+	p.written = true
+}
+
+// Finish terminates the progress line so subsequent output starts cleanly
+func (p *Progress) Finish() {
+	if p == nil || !p.enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.written {
+		fmt.Fprintln(p.w)
+		p.written = false
+	}
+}
+
+// IsTerminal reports whether f refers to a character device (an interactive terminal)
+func IsTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/aboigues/k8t/pkg/types"
 )
@@ -39,6 +40,10 @@ func formatTextOutput(report *types.AnalysisReport, noColor bool, w io.Writer) e
 	b.WriteString(formatSection("SUMMARY", noColor))
 	b.WriteString(formatField("Pods Analyzed", fmt.Sprintf("%d", report.Summary.TotalPodsAnalyzed), noColor))
 	b.WriteString(formatField("Pods with Issues", fmt.Sprintf("%d", report.Summary.PodsWithIssues), noColor))
+	if report.Summary.InferredPods > 0 {
+		b.WriteString(formatField("Analyzed Directly", fmt.Sprintf("%d", report.Summary.DirectlyAnalyzedPods), noColor))
+		b.WriteString(formatField("Inferred (sampled)", fmt.Sprintf("%d", report.Summary.InferredPods), noColor))
+	}
 
 	if report.Summary.PodsWithIssues == 0 {
 		b.WriteString("\n")
@@ -81,7 +86,25 @@ func formatTextOutput(report *types.AnalysisReport, noColor bool, w io.Writer) e
 		severityColor := getSeverityColor(finding.Severity)
 		b.WriteString(formatField("Root Cause", string(finding.RootCause), noColor))
 		b.WriteString(formatField("Severity", colorize(string(finding.Severity), severityColor, noColor), noColor))
-		b.WriteString(formatField("Pod", fmt.Sprintf("%s/%s", finding.PodNamespace, finding.PodName), noColor))
+		if finding.Confidence > 0 {
+			b.WriteString(formatField("Confidence", fmt.Sprintf("%.0f%%", finding.Confidence*100), noColor))
+		}
+		switch finding.Scope {
+		case types.FindingScopeWorkload:
+			b.WriteString(formatField("Workload", fmt.Sprintf("%s/%s", finding.PodNamespace, finding.Subject), noColor))
+		case types.FindingScopeNode:
+			b.WriteString(formatField("Node", strings.TrimPrefix(finding.Subject, "Node/"), noColor))
+		case types.FindingScopeCluster:
+			b.WriteString(formatField("Cluster", finding.Subject, noColor))
+		default:
+			b.WriteString(formatField("Pod", fmt.Sprintf("%s/%s", finding.PodNamespace, finding.PodName), noColor))
+		}
+		if finding.Inferred {
+			b.WriteString(formatField("Inferred From", finding.InferredFrom, noColor))
+		}
+		if finding.ContainerRuntime != "" {
+			b.WriteString(formatField("Container Runtime", finding.ContainerRuntime, noColor))
+		}
 
 		// Affected Containers
 		if len(finding.AffectedContainers) > 0 {
@@ -115,6 +138,10 @@ func formatTextOutput(report *types.AnalysisReport, noColor bool, w io.Writer) e
 			for _, img := range finding.ImageReferences {
 				b.WriteString(fmt.Sprintf("  Container: %s\n", img.ContainerName))
 				b.WriteString(fmt.Sprintf("    Image: %s\n", img.FullReference))
+				if img.ParseError != "" {
+					b.WriteString(fmt.Sprintf("    Invalid: %s\n", colorize(img.ParseError, colorRed, noColor)))
+					continue
+				}
 				b.WriteString(fmt.Sprintf("    Registry: %s\n", img.Registry))
 				b.WriteString(fmt.Sprintf("    Repository: %s\n", img.Repository))
 				if img.Tag != "" {
@@ -123,6 +150,208 @@ func formatTextOutput(report *types.AnalysisReport, noColor bool, w io.Writer) e
 				if img.Digest != "" {
 					b.WriteString(fmt.Sprintf("    Digest: %s\n", img.Digest))
 				}
+				for i, endpoint := range img.PullEndpoints {
+					label := "Pulled via"
+					if i > 0 {
+						label = "Fallback"
+					}
+					scheme := ""
+					if endpoint.PlainHTTP {
+						scheme = "http://"
+					}
+					b.WriteString(fmt.Sprintf("    %s: %s%s/%s\n", label, scheme, endpoint.Host, endpoint.Repository))
+				}
+			}
+		}
+
+		// Evidence supporting the root cause, with its source
+		if len(finding.Evidence) > 0 {
+			b.WriteString("\n")
+			b.WriteString(colorize("EVIDENCE:", colorBold, noColor))
+			b.WriteString("\n")
+			for _, e := range finding.Evidence {
+				source := string(e.Source)
+				if e.Container != "" {
+					source += " " + e.Container
+				}
+				b.WriteString(fmt.Sprintf("  [%s] %s\n", source, truncate(e.Message, 120)))
+			}
+		}
+
+		// Blocking policy (POLICY_REJECTION)
+		if policy := finding.Policy; policy != nil {
+			b.WriteString("\n")
+			b.WriteString(colorize("BLOCKING POLICY:", colorBold, noColor))
+			b.WriteString("\n")
+			b.WriteString(fmt.Sprintf("  Controller: %s\n", policy.Controller))
+			if policy.Webhook != "" {
+				b.WriteString(fmt.Sprintf("  Webhook: %s\n", policy.Webhook))
+			}
+			if len(policy.Policies) > 0 {
+				b.WriteString(fmt.Sprintf("  Policies: %s\n", strings.Join(policy.Policies, ", ")))
+			}
+			if len(policy.Rules) > 0 {
+				b.WriteString(fmt.Sprintf("  Rules: %s\n", strings.Join(policy.Rules, ", ")))
+			}
+			if policy.Signature {
+				b.WriteString("  Verifies image signatures: yes\n")
+			}
+		}
+
+		// Revision that introduced the failing image
+		if rollout := finding.Rollout; rollout != nil {
+			b.WriteString("\n")
+			b.WriteString(colorize("ROLLOUT HISTORY:", colorBold, noColor))
+			b.WriteString(fmt.Sprintf(" (deployment/%s)\n", rollout.Deployment))
+			b.WriteString(fmt.Sprintf("  Introduced In: revision %d (pod runs revision %d)\n", rollout.IntroducedRevision, rollout.Revision))
+			b.WriteString(fmt.Sprintf("  Previous Revision: %d (%s, %d ready)\n", rollout.PreviousRevision, rollout.PreviousReplicaSet, rollout.PreviousReady))
+			for _, change := range rollout.ImageChanges {
+				b.WriteString(fmt.Sprintf("  Container %s: %s -> %s\n", change.Container, change.PreviousImage, change.CurrentImage))
+				pullable := "unknown"
+				if change.Pullable != nil {
+					pullable = checkMark(*change.Pullable, noColor)
+				}
+				b.WriteString(fmt.Sprintf("    Previous image pullable: %s (%s)\n", pullable, change.PullableReason))
+				if len(change.CachedOnNodes) > 0 {
+					b.WriteString(fmt.Sprintf("    Cached on: %s\n", strings.Join(change.CachedOnNodes, ", ")))
+				} else {
+					b.WriteString("    Cached on: no node reports it\n")
+				}
+			}
+		}
+
+		// Nodes caching the failing images
+		if len(finding.ImageCache) > 0 {
+			b.WriteString("\n")
+			b.WriteString(colorize("NODE IMAGE CACHE:", colorBold, noColor))
+			b.WriteString("\n")
+			for _, cache := range finding.ImageCache {
+				b.WriteString(fmt.Sprintf("  Container %s: %s (imagePullPolicy %s)\n", cache.Container, cache.Image, cache.PullPolicy))
+				if len(cache.CachedOn) == 0 {
+					b.WriteString("    Cached on: no node reports it\n")
+				}
+				for _, cached := range cache.CachedOn {
+					var by []string
+					if cached.ByTag {
+						by = append(by, "tag")
+					}
+					if cached.ByDigest {
+						by = append(by, "digest")
+					}
+					line := fmt.Sprintf("    Cached on %s", cached.Node)
+					if cached.Digest != "" {
+						line += " " + truncate(cached.Digest, 19)
+					}
+					b.WriteString(fmt.Sprintf("%s (by %s)\n", line, strings.Join(by, ", ")))
+				}
+				b.WriteString(fmt.Sprintf("    Prediction: %s\n", cache.Prediction))
+			}
+		}
+
+		// Registry failing pulls across the cluster
+		if outage := finding.RegistryOutage; outage != nil {
+			b.WriteString("\n")
+			b.WriteString(colorize("REGISTRY OUTAGE:", colorBold, noColor))
+			b.WriteString(fmt.Sprintf(" (%s)\n", outage.Registry))
+			if outage.MirrorOf != "" {
+				b.WriteString(fmt.Sprintf("  Mirror Of: %s\n", outage.MirrorOf))
+			}
+			if !outage.Since.IsZero() {
+				b.WriteString(fmt.Sprintf("  Since: %s (last failure %s)\n", outage.Since.Format(time.RFC3339), outage.LastFailure.Format(time.RFC3339)))
+			}
+			b.WriteString(fmt.Sprintf("  Affected Pods: %d (%.0f%% of failing pods)\n", outage.AffectedPods, outage.ShareOfFailures*100))
+			b.WriteString("  Workloads:\n")
+			for _, workload := range outage.Workloads {
+				b.WriteString(fmt.Sprintf("    %s %s/%s (%d pods)\n", workload.Kind, workload.Namespace, workload.Name, workload.Pods))
+			}
+		}
+
+		// Failures concentrated on a node (NODE_SPECIFIC_FAILURE)
+		if corr := finding.NodeCorrelation; corr != nil {
+			b.WriteString("\n")
+			b.WriteString(colorize("NODE CORRELATION:", colorBold, noColor))
+			b.WriteString(fmt.Sprintf(" (%s)\n", corr.NodeName))
+			if corr.Zone != "" {
+				b.WriteString(fmt.Sprintf("  Zone: %s\n", corr.Zone))
+			}
+			if corr.NodePool != "" {
+				b.WriteString(fmt.Sprintf("  Node Pool: %s\n", corr.NodePool))
+			}
+			b.WriteString(fmt.Sprintf("  Images: %s\n", strings.Join(corr.Images, ", ")))
+			b.WriteString(fmt.Sprintf("  Failure Rate: %.0f%% on the node, %.0f%% elsewhere (%d pods pulled them on other nodes)\n",
+				corr.FailureRate*100, corr.ElsewhereFailureRate*100, corr.HealthyElsewhere))
+			b.WriteString(fmt.Sprintf("  Failing Pods: %s\n", strings.Join(corr.FailedPods, ", ")))
+			for _, group := range corr.Groups {
+				b.WriteString(fmt.Sprintf("  %s %s: %d failing, %d pulled across %d nodes\n",
+					strings.ToUpper(group.Dimension[:1])+group.Dimension[1:], group.Name, group.FailedPods, group.HealthyPods, group.Nodes))
+			}
+			if len(corr.Conditions) > 0 {
+				b.WriteString("  Conditions:\n")
+				for _, cond := range corr.Conditions {
+					status := colorize(cond.Status, colorGreen, noColor)
+					if !cond.Healthy {
+						status = colorize(cond.Status, colorRed, noColor)
+					}
+					line := fmt.Sprintf("    %s: %s", cond.Type, status)
+					if !cond.Healthy && cond.Message != "" {
+						line += fmt.Sprintf(" (%s)", cond.Message)
+					}
+					b.WriteString(line + "\n")
+				}
+			}
+		}
+
+		// Node disk state (NODE_DISK_PRESSURE)
+		if node := finding.NodeDiagnostics; node != nil {
+			b.WriteString("\n")
+			b.WriteString(colorize("NODE DIAGNOSTICS:", colorBold, noColor))
+			b.WriteString(fmt.Sprintf(" (%s)\n", node.NodeName))
+			if node.ErrorMessage != "" {
+				b.WriteString(fmt.Sprintf("  Unavailable: %s\n", node.ErrorMessage))
+			} else {
+				pressure := colorize("False", colorGreen, noColor)
+				if node.DiskPressure {
+					pressure = colorize("True", colorRed, noColor)
+				}
+				b.WriteString(fmt.Sprintf("  DiskPressure: %s\n", pressure))
+				if node.EphemeralStorageCapacity != "" {
+					b.WriteString(fmt.Sprintf("  Ephemeral Storage: %s capacity, %s allocatable\n", node.EphemeralStorageCapacity, node.EphemeralStorageAllocatable))
+				}
+				b.WriteString(fmt.Sprintf("  Image GC: high %d%%, low %d%% (%s)\n", node.ImageGCHighThresholdPercent, node.ImageGCLowThresholdPercent, node.ThresholdsSource))
+				if node.ImageFSEvictionThreshold != "" || node.NodeFSEvictionThreshold != "" {
+					b.WriteString(fmt.Sprintf("  Eviction: imagefs.available<%s, nodefs.available<%s\n", node.ImageFSEvictionThreshold, node.NodeFSEvictionThreshold))
+				}
+			}
+		}
+
+		// Registry probes (--detailed)
+		if finding.NetworkDiagnostics != nil {
+			b.WriteString("\n")
+			b.WriteString(colorize("REGISTRY DIAGNOSTICS:", colorBold, noColor))
+			if finding.NetworkDiagnostics.MirrorOf != "" {
+				b.WriteString(fmt.Sprintf(" (%s, mirror of %s)\n", finding.NetworkDiagnostics.RegistryHost, finding.NetworkDiagnostics.MirrorOf))
+			} else {
+				b.WriteString(fmt.Sprintf(" (%s)\n", finding.NetworkDiagnostics.RegistryHost))
+			}
+			b.WriteString(formatNetworkDiagnostics(finding.NetworkDiagnostics, noColor))
+		}
+
+		// Decision trace (--explain)
+		if len(finding.DecisionTrace) > 0 {
+			b.WriteString("\n")
+			b.WriteString(colorize("DECISION TRACE:", colorBold, noColor))
+			b.WriteString("\n")
+			for _, line := range finding.DecisionTrace {
+				b.WriteString(fmt.Sprintf("  %s\n", line))
+			}
+			for _, m := range finding.Matches {
+				b.WriteString(fmt.Sprintf("  match %q (%.2f): %s\n", m.Pattern, m.Weight, m.Fragment))
+			}
+			if len(finding.Alternatives) > 0 {
+				b.WriteString("  Alternatives:\n")
+				for _, alt := range finding.Alternatives {
+					b.WriteString(fmt.Sprintf("    - %s (confidence %.0f%%)\n", alt.RootCause, alt.Confidence*100))
+				}
 			}
 		}
 
@@ -163,6 +392,67 @@ func formatTextOutput(report *types.AnalysisReport, noColor bool, w io.Writer) e
 	return err
 }
 
+// formatNetworkDiagnostics renders DNS, TCP, TLS and HTTP probe results
+func formatNetworkDiagnostics(diag *types.NetworkDiagnostics, noColor bool) string {
+	var b strings.Builder
+
+	if dns := diag.DNSResolution; dns != nil {
+		if dns.Success {
+			b.WriteString(fmt.Sprintf("  DNS:  %s %s\n", checkMark(true, noColor), strings.Join(dns.ResolvedIPs, ", ")))
+		} else {
+			b.WriteString(fmt.Sprintf("  DNS:  %s %s\n", checkMark(false, noColor), dns.ErrorMessage))
+		}
+	}
+	if tcp := diag.TCPConnection; tcp != nil {
+		if tcp.Success {
+			b.WriteString(fmt.Sprintf("  TCP:  %s port %d (%dms)\n", checkMark(true, noColor), tcp.Port, tcp.DurationMs))
+		} else {
+			b.WriteString(fmt.Sprintf("  TCP:  %s port %d: %s\n", checkMark(false, noColor), tcp.Port, tcp.ErrorMessage))
+		}
+	}
+	if t := diag.TLSCheck; t != nil {
+		switch {
+		case t.PlainHTTP:
+			b.WriteString(fmt.Sprintf("  TLS:  %s endpoint speaks plain HTTP, not HTTPS\n", checkMark(false, noColor)))
+		case t.ErrorMessage != "":
+			b.WriteString(fmt.Sprintf("  TLS:  %s %s\n", checkMark(false, noColor), t.ErrorMessage))
+		default:
+			trust := "trusted by system pool"
+			if !t.Trusted {
+				trust = "NOT trusted: " + t.VerifyError
+			}
+			b.WriteString(fmt.Sprintf("  TLS:  %s %s\n", checkMark(t.Success, noColor), trust))
+			b.WriteString(fmt.Sprintf("    Subject: %s\n", t.Subject))
+			b.WriteString(fmt.Sprintf("    Issuer: %s\n", t.Issuer))
+			if len(t.SANs) > 0 {
+				b.WriteString(fmt.Sprintf("    SANs: %s\n", strings.Join(t.SANs, ", ")))
+			}
+			expiry := t.NotAfter.Format("2006-01-02 15:04:05 MST")
+			if t.Expired {
+				expiry = colorize(expiry+" (EXPIRED)", colorRed, noColor)
+			}
+			b.WriteString(fmt.Sprintf("    Expires: %s\n", expiry))
+		}
+	}
+	if h := diag.HTTPCheck; h != nil {
+		if h.ErrorMessage != "" {
+			b.WriteString(fmt.Sprintf("  HTTP: %s %s\n", checkMark(false, noColor), h.ErrorMessage))
+		} else {
+			b.WriteString(fmt.Sprintf("  HTTP: %s HEAD /v2/ returned %d\n", checkMark(h.Success, noColor), h.StatusCode))
+		}
+	}
+
+	return b.String()
+}
+
+// checkMark renders a probe outcome
+func checkMark(ok bool, noColor bool) string {
+	if ok {
+		return colorize("OK", colorGreen, noColor)
+	}
+	return colorize("FAIL", colorRed, noColor)
+}
+
 // Helper functions
 
 func formatHeader(title string, noColor bool) string {
@@ -0,0 +1,38 @@
+package types
+
+// ImageCacheStatus reports which nodes hold a container's image and where a
+// pod using it would start if rescheduled now
+type ImageCacheStatus struct {
+	Container  string        `json:"container" yaml:"container"`
+	Image      string        `json:"image" yaml:"image"`
+	PullPolicy string        `json:"pull_policy" yaml:"pull_policy"` // Effective policy, defaulted like the API server
+	CachedOn   []CachedImage `json:"cached_on,omitempty" yaml:"cached_on,omitempty"`
+
+	// Prediction over the schedulable nodes (Ready and not cordoned)
+	SchedulableNodes int      `json:"schedulable_nodes" yaml:"schedulable_nodes"`
+	StartsOn         []string `json:"starts_on,omitempty" yaml:"starts_on,omitempty"` // Nodes where the pod starts without pulling
+	MustPull         int      `json:"must_pull" yaml:"must_pull"`                     // Nodes where the pod needs the registry (or fails with Never)
+	Prediction       string   `json:"prediction" yaml:"prediction"`
+}
+
+// CachedImage is an image entry in a node's status.images
+// The kubelet reports at most 50 images per node, largest first, so an image
+// missing from the list may still be cached.
+type CachedImage struct {
+	Node      string `json:"node" yaml:"node"`
+	Digest    string `json:"digest,omitempty" yaml:"digest,omitempty"` // Digest of the cached image, when reported
+	ByTag     bool   `json:"by_tag" yaml:"by_tag"`                     // Cached under the reference's tag
+	ByDigest  bool   `json:"by_digest" yaml:"by_digest"`               // Cached under the reference's digest
+	SizeBytes int64  `json:"size_bytes,omitempty" yaml:"size_bytes,omitempty"`
+}
+
+// WorkloadExposure rates how a workload would fare if its registries became unavailable
+type WorkloadExposure struct {
+	Namespace string             `json:"namespace" yaml:"namespace"`
+	Kind      string             `json:"kind" yaml:"kind"`
+	Name      string             `json:"name" yaml:"name"`
+	Pods      int                `json:"pods" yaml:"pods"`
+	Images    []ImageCacheStatus `json:"images" yaml:"images"`
+	Exposure  Severity           `json:"exposure" yaml:"exposure"` // HIGH: new pods need the registry on most nodes
+	Reason    string             `json:"reason" yaml:"reason"`
+}
@@ -0,0 +1,39 @@
+package types
+
+// DriftKind identifies why replicas of a workload may not run the same image
+type DriftKind string
+
+const (
+	DriftReplicasDiffer DriftKind = "REPLICAS_DIFFER" // Replicas run different digests of the same reference
+	DriftTagMoved       DriftKind = "TAG_MOVED"       // The registry now serves another digest for the tag
+	DriftLatestTag      DriftKind = "LATEST_TAG"      // The image uses :latest, explicitly or by default
+)
+
+// ImageDrift reports a container of a workload whose image is not pinned to one digest
+type ImageDrift struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Kind      string `json:"kind" yaml:"kind"` // Workload kind, as in AffectedWorkload
+	Name      string `json:"name" yaml:"name"`
+	Container string `json:"container" yaml:"container"`
+	Image     string `json:"image" yaml:"image"`
+	Replicas  int    `json:"replicas" yaml:"replicas"` // Pods of the workload using the image
+
+	Kinds    []DriftKind     `json:"kinds" yaml:"kinds"`
+	Severity Severity        `json:"severity" yaml:"severity"`
+	Details  []string        `json:"details" yaml:"details"`
+	Digests  []ReplicaDigest `json:"digests,omitempty" yaml:"digests,omitempty"` // Running digests, most replicas first
+
+	// Digest the tag resolves to in the registry now (--detailed)
+	RegistryDigest string `json:"registry_digest,omitempty" yaml:"registry_digest,omitempty"`
+	RegistryError  string `json:"registry_error,omitempty" yaml:"registry_error,omitempty"`
+
+	// Pinning the image by digest
+	PinnedImage string             `json:"pinned_image,omitempty" yaml:"pinned_image,omitempty"`
+	Action      *RemediationAction `json:"action,omitempty" yaml:"action,omitempty"`
+}
+
+// ReplicaDigest is a digest running in some replicas, from ContainerStatus.ImageID
+type ReplicaDigest struct {
+	Digest string   `json:"digest" yaml:"digest"`
+	Pods   []string `json:"pods" yaml:"pods"`
+}
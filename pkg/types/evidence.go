@@ -0,0 +1,35 @@
+package types
+
+// EvidenceSource identifies where a piece of diagnostic evidence was read from
+type EvidenceSource string
+
+const (
+	EvidenceSourceEvent                EvidenceSource = "event"                  // Kubernetes Event (expires after ~1h)
+	EvidenceSourceContainerStatus      EvidenceSource = "container_status"       // status.containerStatuses[].state.waiting
+	EvidenceSourceLastTerminationState EvidenceSource = "last_termination_state" // status.containerStatuses[].lastState
+)
+
+// Evidence is a single message used to reach a diagnostic conclusion
+type Evidence struct {
+	Source    EvidenceSource `json:"source" yaml:"source"`
+	Container string         `json:"container,omitempty" yaml:"container,omitempty"` // Empty for pod-level events
+	Reason    string         `json:"reason,omitempty" yaml:"reason,omitempty"`       // Event reason or waiting/termination reason
+	Message   string         `json:"message" yaml:"message"`                         // SR-007: credentials redacted
+}
+
+// PatternMatch is a message fragment that matched a root cause pattern
+type PatternMatch struct {
+	Pattern   string         `json:"pattern" yaml:"pattern"`
+	Fragment  string         `json:"fragment" yaml:"fragment"` // Matched text with surrounding context
+	Weight    float64        `json:"weight" yaml:"weight"`
+	Source    EvidenceSource `json:"source" yaml:"source"`
+	Container string         `json:"container,omitempty" yaml:"container,omitempty"`
+}
+
+// RootCauseCandidate is a scored root cause hypothesis
+type RootCauseCandidate struct {
+	RootCause  RootCause      `json:"root_cause" yaml:"root_cause"`
+	Confidence float64        `json:"confidence" yaml:"confidence"` // 0-1
+	Score      float64        `json:"score" yaml:"score"`           // Sum of the strongest match weight per evidence item
+	Matches    []PatternMatch `json:"matches,omitempty" yaml:"matches,omitempty"`
+}
@@ -3,7 +3,6 @@ package types
 import (
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 )
 
@@ -16,94 +15,69 @@ type ImageReference struct {
 	Tag           string `json:"tag,omitempty" yaml:"tag,omitempty"`   // e.g., "1.21"
 	Digest        string `json:"digest,omitempty" yaml:"digest,omitempty"` // e.g., "sha256:abc123..."
 	IsDigest      bool   `json:"is_digest" yaml:"is_digest"`           // FR-014: tag-based vs digest-based
-}
 
-// ParseImageReference parses image reference string into components
-// Handles formats:
-//   - nginx
-//   - nginx:1.21
-//   - docker.io/library/nginx:1.21
-//   - gcr.io/project/image@sha256:abc123...
-func ParseImageReference(containerName, imageRef string) (*ImageReference, error) {
-	if imageRef == "" {
-		return nil, errors.New("image reference cannot be empty")
-	}
+	// Why the reference could not be parsed; only FullReference is set then
+	ParseError string `json:"parse_error,omitempty" yaml:"parse_error,omitempty"`
 
-	ref := &ImageReference{
-		ContainerName: containerName,
-		FullReference: imageRef,
-	}
+	// Where the runtime actually pulls from when mirrors are configured, in the
+	// order they are tried; empty when the image is pulled from Registry
+	PullEndpoints []RegistryEndpoint `json:"pull_endpoints,omitempty" yaml:"pull_endpoints,omitempty"`
+}
 
-	// Split by @ for digest-based references
-	parts := strings.Split(imageRef, "@")
-	if len(parts) == 2 {
-		ref.IsDigest = true
-		ref.Digest = parts[1]
-		imageRef = parts[0] // Process the rest as normal
-	}
+// RegistryEndpoint is a host an image is pulled from, with the repository path on that host
+type RegistryEndpoint struct {
+	Host       string `json:"host" yaml:"host"` // host[:port]
+	Repository string `json:"repository" yaml:"repository"`
+	PlainHTTP  bool   `json:"plain_http,omitempty" yaml:"plain_http,omitempty"`
+	Mirror     bool   `json:"mirror" yaml:"mirror"` // False for the upstream registry fallback
+}
 
-	// Split by : for tag or port
-	tagParts := strings.Split(imageRef, ":")
-
-	// Check if we have registry/repo:tag or just repo:tag
-	var registryAndRepo string
-	if len(tagParts) >= 2 && !ref.IsDigest {
-		// Last part might be a tag
-		possibleTag := tagParts[len(tagParts)-1]
-		// Check if it looks like a tag (no slashes) and not a port number
-		if !strings.Contains(possibleTag, "/") {
-			ref.Tag = possibleTag
-			registryAndRepo = strings.Join(tagParts[:len(tagParts)-1], ":")
-		} else {
-			registryAndRepo = imageRef
-		}
-	} else {
-		registryAndRepo = imageRef
+// EffectiveEndpoint returns the first endpoint the runtime pulls from, or nil without mirrors
+func (r *ImageReference) EffectiveEndpoint() *RegistryEndpoint {
+	if len(r.PullEndpoints) == 0 {
+		return nil
 	}
+	return &r.PullEndpoints[0]
+}
 
-	// Parse registry and repository
-	repoParts := strings.Split(registryAndRepo, "/")
-
-	switch len(repoParts) {
-	case 1:
-		// Just image name (e.g., "nginx")
-		ref.Registry = "docker.io"
-		ref.Repository = "library/" + repoParts[0]
-		if ref.Tag == "" && !ref.IsDigest {
-			ref.Tag = "latest"
-		}
-	case 2:
-		// Two parts: could be registry/image or user/image
-		if strings.Contains(repoParts[0], ".") || strings.Contains(repoParts[0], ":") {
-			// Contains dot or colon, treat as registry
-			ref.Registry = repoParts[0]
-			ref.Repository = repoParts[1]
-		} else {
-			// No dot, treat as docker.io/user/image
-			ref.Registry = "docker.io"
-			ref.Repository = strings.Join(repoParts, "/")
-		}
-		if ref.Tag == "" && !ref.IsDigest {
-			ref.Tag = "latest"
-		}
-	default:
-		// Three or more parts: registry/path/to/image
-		ref.Registry = repoParts[0]
-		ref.Repository = strings.Join(repoParts[1:], "/")
-		if ref.Tag == "" && !ref.IsDigest {
-			ref.Tag = "latest"
-		}
+// ParseImageReference parses image reference string into components
+// References are validated against the distribution reference grammar and
+// normalized like the container runtimes do:
+//   - nginx                              -> docker.io/library/nginx:latest
+//   - localhost:5000/app                 -> registry localhost:5000
+//   - registry:5000/ns/app:v1@sha256:... -> tag and digest (the digest is pulled)
+//   - index.docker.io/user/app           -> docker.io/user/app
+// Invalid references return an *ImageReferenceError naming the component and
+// the offending character.
+func ParseImageReference(containerName, imageRef string) (*ImageReference, error) {
+	ref, err := parseImageReference(imageRef)
+	if err != nil {
+		return nil, err
 	}
-
+	ref.ContainerName = containerName
 	return ref, nil
 }
 
+// FindingScope identifies the kind of object a finding is about
+type FindingScope string
+
+const (
+	FindingScopePod      FindingScope = "pod"      // A pod failing to pull (default)
+	FindingScopeWorkload FindingScope = "workload" // A controller whose pods could not be created
+	FindingScopeNode     FindingScope = "node"     // A node where pulls fail while they succeed elsewhere
+	FindingScopeCluster  FindingScope = "cluster"  // A problem shared by workloads across namespaces
+)
+
 // DiagnosticFinding represents analysis results for container image pull issues
 type DiagnosticFinding struct {
 	// Core identification
 	RootCause RootCause `json:"root_cause" yaml:"root_cause"`
 	Severity  Severity  `json:"severity" yaml:"severity"`
 
+	// Scope: empty means pod. Non-pod findings name their object in Subject (Kind/name)
+	Scope   FindingScope `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Subject string       `json:"subject,omitempty" yaml:"subject,omitempty"`
+
 	// Affected resources
 	PodName            string   `json:"pod_name" yaml:"pod_name"`
 	PodNamespace       string   `json:"pod_namespace" yaml:"pod_namespace"`
@@ -114,9 +88,23 @@ type DiagnosticFinding struct {
 	Details          string   `json:"details" yaml:"details"`
 	RemediationSteps []string `json:"remediation_steps" yaml:"remediation_steps"`
 
+	// Machine-applicable remediation (set by k8t fix)
+	Actions []RemediationAction `json:"actions,omitempty" yaml:"actions,omitempty"`
+
 	// Context
 	ImageReferences []ImageReference `json:"image_references" yaml:"image_references"`
 	Events          []EventSummary   `json:"events,omitempty" yaml:"events,omitempty"`
+	Evidence        []Evidence       `json:"evidence,omitempty" yaml:"evidence,omitempty"` // Messages supporting the root cause, with their source
+
+	// Container runtime of the pod's node, e.g. containerd://1.7.13 (node.status.nodeInfo.containerRuntimeVersion)
+	ContainerRuntime string `json:"container_runtime,omitempty" yaml:"container_runtime,omitempty"`
+
+	// Detection scoring: how strongly the evidence supports the root cause,
+	// which patterns matched, and the runner-up candidates
+	Confidence    float64              `json:"confidence,omitempty" yaml:"confidence,omitempty"`
+	Matches       []PatternMatch       `json:"matches,omitempty" yaml:"matches,omitempty"`
+	Alternatives  []RootCauseCandidate `json:"alternatives,omitempty" yaml:"alternatives,omitempty"`
+	DecisionTrace []string             `json:"decision_trace,omitempty" yaml:"decision_trace,omitempty"` // Set with --explain
 
 	// Failure analysis
 	IsTransient      bool       `json:"is_transient" yaml:"is_transient"`
@@ -125,12 +113,45 @@ type DiagnosticFinding struct {
 	LastFailureTime  *time.Time `json:"last_failure_time,omitempty" yaml:"last_failure_time,omitempty"`
 	FailureDuration  string     `json:"failure_duration,omitempty" yaml:"failure_duration,omitempty"` // Human-readable
 
+	// Sampling (multi-pod scans): set when the finding was projected from a
+	// representative pod instead of being analyzed directly
+	Inferred     bool   `json:"inferred,omitempty" yaml:"inferred,omitempty"`
+	InferredFrom string `json:"inferred_from,omitempty" yaml:"inferred_from,omitempty"` // namespace/name of the representative pod
+
 	// Network diagnostics (when RootCause = NETWORK_ISSUE)
 	NetworkDiagnostics *NetworkDiagnostics `json:"network_diagnostics,omitempty" yaml:"network_diagnostics,omitempty"`
+
+	// Node disk diagnostics (when RootCause = NODE_DISK_PRESSURE)
+	NodeDiagnostics *NodeDiagnostics `json:"node_diagnostics,omitempty" yaml:"node_diagnostics,omitempty"`
+
+	// Blocking policy (when RootCause = POLICY_REJECTION)
+	Policy *PolicyRejection `json:"policy,omitempty" yaml:"policy,omitempty"`
+
+	// Nodes caching the failing images, and where a rescheduled pod would start
+	ImageCache []ImageCacheStatus `json:"image_cache,omitempty" yaml:"image_cache,omitempty"`
+
+	// Deployment revision that introduced the failing image (image-related root causes)
+	Rollout *RolloutCorrelation `json:"rollout,omitempty" yaml:"rollout,omitempty"`
+
+	// Failure concentration on a node (when Scope = node)
+	NodeCorrelation *NodeCorrelation `json:"node_correlation,omitempty" yaml:"node_correlation,omitempty"`
+
+	// Registry failing most pulls across the cluster (when Scope = cluster)
+	RegistryOutage *RegistryOutage `json:"registry_outage,omitempty" yaml:"registry_outage,omitempty"`
 }
 
 // Validate checks if finding is well-formed
 func (f *DiagnosticFinding) Validate() error {
+	if f.Scope != "" && f.Scope != FindingScopePod {
+		// Non-pod findings have no pod or containers; they name their object instead
+		if f.Subject == "" {
+			return fmt.Errorf("subject is required for %s findings", f.Scope)
+		}
+		if len(f.RemediationSteps) == 0 {
+			return errors.New("remediation_steps required (FR-002, SC-003)")
+		}
+		return nil
+	}
 	if f.PodName == "" {
 		return errors.New("pod_name is required")
 	}
@@ -158,6 +179,7 @@ type EventSummary struct {
 	Count     int       `json:"count" yaml:"count"`           // Event count (repeated events)
 	FirstSeen time.Time `json:"first_seen" yaml:"first_seen"`
 	LastSeen  time.Time `json:"last_seen" yaml:"last_seen"`
+	Container string    `json:"container,omitempty" yaml:"container,omitempty"` // From involvedObject.fieldPath, when set
 }
 
 // NetworkDiagnostics contains results of network connectivity tests
@@ -166,6 +188,8 @@ type NetworkDiagnostics struct {
 	DNSResolution *DNSResult  `json:"dns_resolution" yaml:"dns_resolution"`
 	TCPConnection *TCPResult  `json:"tcp_connection" yaml:"tcp_connection"`
 	HTTPCheck     *HTTPResult `json:"http_check" yaml:"http_check"`
+	TLSCheck      *TLSResult  `json:"tls_check,omitempty" yaml:"tls_check,omitempty"`
+	MirrorOf      string      `json:"mirror_of,omitempty" yaml:"mirror_of,omitempty"` // Upstream registry when RegistryHost is a mirror
 }
 
 // DNSResult represents DNS lookup results
@@ -191,3 +215,34 @@ type HTTPResult struct {
 	ErrorMessage string `json:"error_message,omitempty" yaml:"error_message,omitempty"`
 	DurationMs   int64  `json:"duration_ms" yaml:"duration_ms"`
 }
+
+// TLSResult represents the inspection of the registry's certificate chain
+type TLSResult struct {
+	Success      bool      `json:"success" yaml:"success"`                                 // Handshake succeeded and chain is trusted
+	Trusted      bool      `json:"trusted" yaml:"trusted"`                                 // Chain verifies against the system pool
+	PlainHTTP    bool      `json:"plain_http,omitempty" yaml:"plain_http,omitempty"`       // Endpoint answered with plain HTTP
+	Subject      string    `json:"subject,omitempty" yaml:"subject,omitempty"`             // Leaf certificate subject
+	Issuer       string    `json:"issuer,omitempty" yaml:"issuer,omitempty"`               // Leaf certificate issuer
+	SANs         []string  `json:"sans,omitempty" yaml:"sans,omitempty"`                   // DNS names and IPs the leaf is valid for
+	NotAfter     time.Time `json:"not_after,omitempty" yaml:"not_after,omitempty"`         // Leaf expiry
+	Expired      bool      `json:"expired,omitempty" yaml:"expired,omitempty"`             // Leaf is outside its validity window
+	Chain        []string  `json:"chain,omitempty" yaml:"chain,omitempty"`                 // Subjects presented by the server, leaf first
+	VerifyError  string    `json:"verify_error,omitempty" yaml:"verify_error,omitempty"`   // Why the chain is not trusted
+	ErrorMessage string    `json:"error_message,omitempty" yaml:"error_message,omitempty"` // Connection or handshake failure
+	DurationMs   int64     `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// NodeDiagnostics describes the disk state of the node the pod is scheduled on
+type NodeDiagnostics struct {
+	NodeName                    string `json:"node_name" yaml:"node_name"`
+	DiskPressure                bool   `json:"disk_pressure" yaml:"disk_pressure"`                                                         // DiskPressure condition is True
+	DiskPressureMessage         string `json:"disk_pressure_message,omitempty" yaml:"disk_pressure_message,omitempty"`                     // Condition message from the kubelet
+	EphemeralStorageCapacity    string `json:"ephemeral_storage_capacity,omitempty" yaml:"ephemeral_storage_capacity,omitempty"`           // status.capacity
+	EphemeralStorageAllocatable string `json:"ephemeral_storage_allocatable,omitempty" yaml:"ephemeral_storage_allocatable,omitempty"`     // status.allocatable
+	ImageGCHighThresholdPercent int32  `json:"image_gc_high_threshold_percent,omitempty" yaml:"image_gc_high_threshold_percent,omitempty"` // Disk usage that triggers image GC
+	ImageGCLowThresholdPercent  int32  `json:"image_gc_low_threshold_percent,omitempty" yaml:"image_gc_low_threshold_percent,omitempty"`   // Disk usage image GC frees down to
+	ImageFSEvictionThreshold    string `json:"imagefs_eviction_threshold,omitempty" yaml:"imagefs_eviction_threshold,omitempty"`           // evictionHard imagefs.available
+	NodeFSEvictionThreshold     string `json:"nodefs_eviction_threshold,omitempty" yaml:"nodefs_eviction_threshold,omitempty"`             // evictionHard nodefs.available
+	ThresholdsSource            string `json:"thresholds_source,omitempty" yaml:"thresholds_source,omitempty"`                             // "kubelet configz" or "kubelet defaults"
+	ErrorMessage                string `json:"error_message,omitempty" yaml:"error_message,omitempty"`                                     // Node could not be read
+}
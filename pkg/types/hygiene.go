@@ -0,0 +1,12 @@
+package types
+
+// Rules of the image hygiene policy (k8t audit images), reported as the root
+// cause of workload-scoped findings
+const (
+	HygieneInvalidReference   RootCause = "INVALID_IMAGE_REFERENCE"       // The reference cannot be pulled as written
+	HygieneDisallowedRegistry RootCause = "DISALLOWED_REGISTRY"           // Registry not in the allowlist
+	HygieneLatestTag          RootCause = "LATEST_TAG"                    // :latest, explicitly or by omitting the tag
+	HygieneDigestRequired     RootCause = "DIGEST_REQUIRED"               // Not pinned by digest in a namespace that requires it
+	HygienePullPolicyMismatch RootCause = "PULL_POLICY_MISMATCH"          // imagePullPolicy inconsistent with the tag's mutability
+	HygieneDockerHubNoSecret  RootCause = "DOCKERHUB_WITHOUT_PULL_SECRET" // Anonymous Docker Hub pulls, subject to rate limits
+)
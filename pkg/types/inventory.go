@@ -0,0 +1,18 @@
+package types
+
+// ImageInventoryEntry is an image reference in use, with the workloads and pods using it
+type ImageInventoryEntry struct {
+	Image      string `json:"image" yaml:"image"` // As written in pod specs
+	Registry   string `json:"registry" yaml:"registry"`
+	Repository string `json:"repository" yaml:"repository"`
+	Tag        string `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	ParseError string `json:"parse_error,omitempty" yaml:"parse_error,omitempty"`
+
+	// Images resolved by the runtimes (ContainerStatus.ImageID), distinct
+	ImageIDs []string `json:"image_ids,omitempty" yaml:"image_ids,omitempty"`
+
+	Workloads   []AffectedWorkload `json:"workloads" yaml:"workloads"`
+	Pods        int                `json:"pods" yaml:"pods"`
+	PullSecrets []string           `json:"pull_secrets,omitempty" yaml:"pull_secrets,omitempty"` // namespace/name of the pods' imagePullSecrets
+}
@@ -0,0 +1,17 @@
+package types
+
+// LintFinding is an image pull failure predicted from a manifest (k8t lint)
+// Container and Image are empty for problems of the pod template as a whole,
+// such as a missing pull secret.
+type LintFinding struct {
+	RootCause   RootCause `json:"root_cause" yaml:"root_cause"`
+	Severity    Severity  `json:"severity" yaml:"severity"`
+	Source      string    `json:"source" yaml:"source"` // File and number of the non-empty document, e.g. deploy.yaml#2
+	Namespace   string    `json:"namespace" yaml:"namespace"`
+	Kind        string    `json:"kind" yaml:"kind"`
+	Name        string    `json:"name" yaml:"name"`
+	Container   string    `json:"container,omitempty" yaml:"container,omitempty"`
+	Image       string    `json:"image,omitempty" yaml:"image,omitempty"`
+	Message     string    `json:"message" yaml:"message"`
+	Remediation []string  `json:"remediation" yaml:"remediation"`
+}
@@ -0,0 +1,37 @@
+package types
+
+// NodeCorrelation describes image pull failures concentrated on one node
+// while pods pulling the same images on other nodes are healthy
+type NodeCorrelation struct {
+	NodeName   string   `json:"node_name" yaml:"node_name"`
+	Zone       string   `json:"zone,omitempty" yaml:"zone,omitempty"`           // topology.kubernetes.io/zone
+	NodePool   string   `json:"node_pool,omitempty" yaml:"node_pool,omitempty"` // Cloud provider or Karpenter node pool label
+	FailedPods []string `json:"failed_pods" yaml:"failed_pods"`                 // namespace/name of the failing pods on the node
+	Images     []string `json:"images" yaml:"images"`                           // Images failing on the node that pull elsewhere
+
+	// Failing share of the pods using these images, on the node and on the other nodes
+	FailureRate          float64 `json:"failure_rate" yaml:"failure_rate"`
+	ElsewhereFailureRate float64 `json:"elsewhere_failure_rate" yaml:"elsewhere_failure_rate"`
+	HealthyElsewhere     int     `json:"healthy_elsewhere" yaml:"healthy_elsewhere"` // Pods with these images pulled on other nodes
+
+	Conditions []NodeConditionStatus `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+	Groups     []FailureGroup        `json:"groups,omitempty" yaml:"groups,omitempty"` // The node's zone and node pool, for comparison
+}
+
+// NodeConditionStatus is a node condition as reported by the kubelet
+type NodeConditionStatus struct {
+	Type    string `json:"type" yaml:"type"`
+	Status  string `json:"status" yaml:"status"`
+	Reason  string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	Healthy bool   `json:"healthy" yaml:"healthy"` // Ready=True, or any other condition False
+}
+
+// FailureGroup counts pods failing and pulling the correlated images in a node, zone or node pool
+type FailureGroup struct {
+	Dimension   string `json:"dimension" yaml:"dimension"` // "zone" or "node pool"
+	Name        string `json:"name" yaml:"name"`
+	Nodes       int    `json:"nodes" yaml:"nodes"`
+	FailedPods  int    `json:"failed_pods" yaml:"failed_pods"`
+	HealthyPods int    `json:"healthy_pods" yaml:"healthy_pods"`
+}
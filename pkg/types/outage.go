@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// RegistryOutage describes a registry that accounts for most image pull failures across the cluster
+type RegistryOutage struct {
+	Registry string `json:"registry" yaml:"registry"`                       // Host pulls fail against (the mirror, when one is configured)
+	MirrorOf string `json:"mirror_of,omitempty" yaml:"mirror_of,omitempty"` // Upstream registry when Registry is a mirror
+
+	Since       time.Time `json:"since" yaml:"since"`               // Earliest failure against the registry
+	LastFailure time.Time `json:"last_failure" yaml:"last_failure"` // Most recent failure
+
+	AffectedPods    int                `json:"affected_pods" yaml:"affected_pods"`
+	ShareOfFailures float64            `json:"share_of_failures" yaml:"share_of_failures"` // Fraction of all failing pods in the scan
+	RootCauses      map[RootCause]int  `json:"root_causes" yaml:"root_causes"`             // Failing pods per root cause
+	Workloads       []AffectedWorkload `json:"workloads" yaml:"workloads"`
+}
+
+// AffectedWorkload is a controller, or a bare pod, and the number of its pods involved
+// (failing to pull, or using an image)
+type AffectedWorkload struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Kind      string `json:"kind" yaml:"kind"`
+	Name      string `json:"name" yaml:"name"`
+	Pods      int    `json:"pods" yaml:"pods"`
+}
@@ -0,0 +1,21 @@
+package types
+
+// PolicyRejection describes an admission or image policy that blocked a workload
+type PolicyRejection struct {
+	Controller string   `json:"controller" yaml:"controller"`                   // kyverno, gatekeeper, sigstore-policy-controller, ...
+	Webhook    string   `json:"webhook,omitempty" yaml:"webhook,omitempty"`     // Admission webhook that denied the request
+	Policies   []string `json:"policies,omitempty" yaml:"policies,omitempty"`   // Blocking policies or constraints
+	Rules      []string `json:"rules,omitempty" yaml:"rules,omitempty"`         // Failing rules within the policies, when reported
+	Message    string   `json:"message" yaml:"message"`                         // Denial message (SR-007: credentials redacted)
+	Signature  bool     `json:"signature,omitempty" yaml:"signature,omitempty"` // The policy verifies image signatures or attestations
+}
+
+// Policy controllers recognized in admission denial messages
+const (
+	PolicyControllerKyverno            = "kyverno"
+	PolicyControllerGatekeeper         = "gatekeeper"
+	PolicyControllerSigstore           = "sigstore-policy-controller"
+	PolicyControllerValidatingPolicy   = "validating-admission-policy"
+	PolicyControllerImagePolicyWebhook = "image-policy-webhook"
+	PolicyControllerWebhook            = "admission-webhook" // Unrecognized webhook
+)
@@ -0,0 +1,338 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Image reference grammar (github.com/distribution/reference):
+//
+//	reference      := name [ ":" tag ] [ "@" digest ]
+//	name           := [domain '/'] remote-name
+//	domain         := host [':' port-number]
+//	host           := domain-name | IPv4address | \[ IPv6address \]
+//	domain-name    := domain-component ['.' domain-component]*
+//	domain-component := /([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])/
+//	remote-name    := path-component ['/' path-component]*
+//	path-component := alpha-numeric [separator alpha-numeric]*
+//	alpha-numeric  := /[a-z0-9]+/
+//	separator      := /[_.]|__|[-]+/
+//	tag            := /[\w][\w.-]{0,127}/
+//	digest         := algorithm ":" encoded
+//
+// Docker normalization: a name without a domain is on docker.io, a docker.io
+// name with a single path component is in library/, and index.docker.io is
+// an alias of docker.io.
+const (
+	DefaultRegistry      = "docker.io"
+	legacyDefaultDomain  = "index.docker.io"
+	officialRepoPrefix   = "library/"
+	defaultTag           = "latest"
+	maxNameLength        = 255
+	maxTagLength         = 128
+	minDigestEncodingLen = 32
+)
+
+// digestLengths is the hex length of the digest algorithms runtimes verify
+var digestLengths = map[string]int{
+	"sha256": 64,
+	"sha384": 96,
+	"sha512": 128,
+}
+
+// Image reference components named by ImageReferenceError
+const (
+	ComponentReference  = "reference"
+	ComponentRegistry   = "registry"
+	ComponentRepository = "repository"
+	ComponentTag        = "tag"
+	ComponentDigest     = "digest"
+)
+
+// ImageReferenceError describes why an image reference is invalid
+// Position is the byte offset of the offending character in the reference,
+// or -1 when the component as a whole is wrong (e.g. too long).
+type ImageReferenceError struct {
+	Reference string
+	Component string // One of the Component* constants
+	Value     string // The offending component, e.g. the tag
+	Position  int
+	Reason    string
+}
+
+// Error implements error
+func (e *ImageReferenceError) Error() string {
+	msg := fmt.Sprintf("invalid image reference %q: %s", e.Reference, e.Component)
+	if e.Value != "" {
+		msg += fmt.Sprintf(" %q", e.Value)
+	}
+	msg += " " + e.Reason
+	if e.Position >= 0 && e.Position < len(e.Reference) {
+		msg += fmt.Sprintf(" (character %q at position %d)", e.Reference[e.Position], e.Position)
+	}
+	return msg
+}
+
+// parseImageReference parses a reference against the distribution grammar and normalizes it
+func parseImageReference(imageRef string) (*ImageReference, error) {
+	fail := func(component, value string, pos int, format string, args ...interface{}) error {
+		return &ImageReferenceError{Reference: imageRef, Component: component, Value: value, Position: pos, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	if imageRef == "" {
+		return nil, fail(ComponentReference, "", -1, "is empty")
+	}
+	if i := strings.Index(imageRef, "://"); i >= 0 {
+		return nil, fail(ComponentReference, "", i, "must not include a URL scheme")
+	}
+	if i := strings.IndexAny(imageRef, " \t\r\n\"'"); i >= 0 {
+		return nil, fail(ComponentReference, "", i, "must not contain whitespace or quotes")
+	}
+
+	ref := &ImageReference{FullReference: imageRef}
+	name := imageRef
+
+	// Digest
+	if i := strings.Index(name, "@"); i >= 0 {
+		if err := validateDigest(name[i+1:], i+1, fail); err != nil {
+			return nil, err
+		}
+		ref.Digest = name[i+1:]
+		ref.IsDigest = true
+		name = name[:i]
+	}
+
+	// Tag: a colon after the last slash (a colon before it is a registry port)
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		if err := validateTag(name[i+1:], i+1, fail); err != nil {
+			return nil, err
+		}
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	if name == "" {
+		return nil, fail(ComponentRepository, "", -1, "is empty")
+	}
+	if len(name) > maxNameLength {
+		return nil, fail(ComponentRepository, "", -1, "must not exceed %d characters (has %d)", maxNameLength, len(name))
+	}
+
+	// Domain: the first component when it looks like a host
+	domain, remote, remoteOffset := "", name, 0
+	if i := strings.Index(name, "/"); i >= 0 {
+		first := name[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" || strings.ToLower(first) != first {
+			if err := validateDomain(first, fail); err != nil {
+				return nil, err
+			}
+			domain, remote, remoteOffset = first, name[i+1:], i+1
+		}
+	}
+
+	if domain == "" && isHex64(remote) {
+		return nil, fail(ComponentRepository, remote, -1, "cannot be a 64-character hexadecimal string (it looks like an image ID)")
+	}
+	if err := validateRemoteName(remote, remoteOffset, fail); err != nil {
+		return nil, err
+	}
+
+	// Docker Hub normalization
+	switch domain {
+	case "", legacyDefaultDomain:
+		domain = DefaultRegistry
+	}
+	if domain == DefaultRegistry && !strings.Contains(remote, "/") {
+		remote = officialRepoPrefix + remote
+	}
+
+	ref.Registry = domain
+	ref.Repository = remote
+	if ref.Tag == "" && !ref.IsDigest {
+		ref.Tag = defaultTag
+	}
+	return ref, nil
+}
+
+// failFunc builds an ImageReferenceError for the reference being parsed
+type failFunc func(component, value string, pos int, format string, args ...interface{}) error
+
+// validateDigest checks algorithm ":" encoded; offset is the digest's position in the reference
+func validateDigest(digest string, offset int, fail failFunc) error {
+	algorithm, encoded, ok := strings.Cut(digest, ":")
+	if !ok {
+		return fail(ComponentDigest, digest, -1, "must be <algorithm>:<hex>, e.g. sha256:<64 hex characters>")
+	}
+	if algorithm == "" {
+		return fail(ComponentDigest, digest, offset, "has an empty algorithm")
+	}
+	for i := 0; i < len(algorithm); i++ {
+		if c := algorithm[i]; !isLowerAlnum(c) && !(i > 0 && strings.IndexByte("+._-", c) >= 0) {
+			return fail(ComponentDigest, digest, offset+i, "algorithm may only contain lowercase letters, digits and separators")
+		}
+	}
+
+	encodedOffset := offset + len(algorithm) + 1
+	for i := 0; i < len(encoded); i++ {
+		if !isHex(encoded[i]) {
+			return fail(ComponentDigest, digest, encodedOffset+i, "must be hexadecimal")
+		}
+	}
+	if want, known := digestLengths[algorithm]; known {
+		if len(encoded) != want {
+			return fail(ComponentDigest, digest, -1, "must have %d hex characters for %s (has %d)", want, algorithm, len(encoded))
+		}
+		if i := strings.IndexAny(encoded, "ABCDEF"); i >= 0 {
+			return fail(ComponentDigest, digest, encodedOffset+i, "must be lowercase hexadecimal")
+		}
+	} else if len(encoded) < minDigestEncodingLen {
+		return fail(ComponentDigest, digest, -1, "must have at least %d hex characters (has %d)", minDigestEncodingLen, len(encoded))
+	}
+	return nil
+}
+
+// validateTag checks [\w][\w.-]{0,127}; offset is the tag's position in the reference
+func validateTag(tag string, offset int, fail failFunc) error {
+	if tag == "" {
+		return fail(ComponentTag, "", offset-1, "is empty after ':'")
+	}
+	if len(tag) > maxTagLength {
+		return fail(ComponentTag, tag, -1, "must not exceed %d characters (has %d)", maxTagLength, len(tag))
+	}
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		if isWord(c) || (i > 0 && (c == '.' || c == '-')) {
+			continue
+		}
+		if i == 0 && (c == '.' || c == '-') {
+			return fail(ComponentTag, tag, offset, "must start with a letter, digit or '_'")
+		}
+		return fail(ComponentTag, tag, offset+i, "may only contain letters, digits, '_', '.' and '-'")
+	}
+	return nil
+}
+
+// validateDomain checks host[:port], where host is a DNS name, IPv4 or [IPv6] address
+func validateDomain(domain string, fail failFunc) error {
+	host, port := domain, ""
+	if strings.HasPrefix(domain, "[") {
+		end := strings.Index(domain, "]")
+		if end < 0 {
+			return fail(ComponentRegistry, domain, -1, "has an unterminated IPv6 address")
+		}
+		for i := 1; i < end; i++ {
+			if c := domain[i]; !isHex(c) && c != ':' && c != '.' {
+				return fail(ComponentRegistry, domain, i, "has an invalid IPv6 address")
+			}
+		}
+		host, port = domain[:end+1], strings.TrimPrefix(domain[end+1:], ":")
+		if end+1 < len(domain) && domain[end+1] != ':' {
+			return fail(ComponentRegistry, domain, end+1, "must be followed by ':' and a port")
+		}
+	} else if i := strings.Index(domain, ":"); i >= 0 {
+		host, port = domain[:i], domain[i+1:]
+		if port == "" {
+			return fail(ComponentRegistry, domain, i, "has an empty port")
+		}
+	}
+
+	if !strings.HasPrefix(host, "[") {
+		start := 0
+		for _, label := range strings.Split(host, ".") {
+			if label == "" {
+				return fail(ComponentRegistry, domain, start, "has an empty DNS label")
+			}
+			for i := 0; i < len(label); i++ {
+				c := label[i]
+				switch {
+				case isLowerAlnum(c) || (c >= 'A' && c <= 'Z'):
+				case c == '-' && i > 0 && i < len(label)-1:
+				case c == '-':
+					return fail(ComponentRegistry, domain, start+i, "DNS labels must not start or end with '-'")
+				default:
+					return fail(ComponentRegistry, domain, start+i, "may only contain letters, digits, '-' and '.'")
+				}
+			}
+			start += len(label) + 1
+		}
+	}
+
+	portOffset := len(domain) - len(port)
+	for i := 0; i < len(port); i++ {
+		if port[i] < '0' || port[i] > '9' {
+			return fail(ComponentRegistry, domain, portOffset+i, "port must be numeric")
+		}
+	}
+	return nil
+}
+
+// validateRemoteName checks the repository path; offset is its position in the reference
+func validateRemoteName(remote string, offset int, fail failFunc) error {
+	start := 0
+	for _, component := range strings.Split(remote, "/") {
+		pos := offset + start
+		if component == "" {
+			return fail(ComponentRepository, remote, pos, "has an empty path component")
+		}
+		for i := 0; i < len(component); i++ {
+			c := component[i]
+			switch {
+			case isLowerAlnum(c) || c == '.' || c == '_' || c == '-':
+			case c >= 'A' && c <= 'Z':
+				return fail(ComponentRepository, remote, pos+i, "must be lowercase")
+			default:
+				return fail(ComponentRepository, remote, pos+i, "may only contain lowercase letters, digits, '.', '_', '__' and '-'")
+			}
+		}
+		if i := invalidSeparator(component); i >= 0 {
+			return fail(ComponentRepository, remote, pos+i, "separators ('.', '_', '__', runs of '-') must sit between letters or digits")
+		}
+		start += len(component) + 1
+	}
+	return nil
+}
+
+// invalidSeparator returns the offset of the first misplaced separator in a path component, or -1
+func invalidSeparator(component string) int {
+	for i := 0; i < len(component); {
+		if isLowerAlnum(component[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(component) && !isLowerAlnum(component[j]) {
+			j++
+		}
+		run := component[i:j]
+		valid := run == "." || run == "_" || run == "__" || strings.Trim(run, "-") == ""
+		if i == 0 || j == len(component) || !valid {
+			return i
+		}
+		i = j
+	}
+	return -1
+}
+
+func isLowerAlnum(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
+
+func isWord(c byte) bool {
+	return isLowerAlnum(c) || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isHex64(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isHex(s[i]) || (s[i] >= 'A' && s[i] <= 'F') {
+			return false
+		}
+	}
+	return true
+}
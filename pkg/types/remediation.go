@@ -0,0 +1,42 @@
+package types
+
+// RemediationActionKind identifies a machine-applicable remediation
+type RemediationActionKind string
+
+const (
+	ActionAddImagePullSecret RemediationActionKind = "AddImagePullSecret" // Reference a pull secret from the ServiceAccount or pod template
+	ActionSetImage           RemediationActionKind = "SetImage"           // Change a container image (tag, digest or normalized reference)
+	ActionSetImagePullPolicy RemediationActionKind = "SetImagePullPolicy" // Change a container imagePullPolicy
+)
+
+// Patch types accepted by the apiserver (Content-Type of the PATCH request)
+const (
+	PatchTypeStrategicMerge = "application/strategic-merge-patch+json"
+	PatchTypeJSON           = "application/json-patch+json"
+)
+
+// ObjectReference identifies the Kubernetes object a remediation action patches
+type ObjectReference struct {
+	Kind      string `json:"kind" yaml:"kind"` // Pod, ServiceAccount, Deployment, StatefulSet, DaemonSet, ReplicaSet
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Name      string `json:"name" yaml:"name"`
+}
+
+// RemediationAction is a remediation step expressed as a patch to a single object
+type RemediationAction struct {
+	Kind        RemediationActionKind `json:"kind" yaml:"kind"`
+	Description string                `json:"description" yaml:"description"`
+	RootCause   RootCause             `json:"root_cause" yaml:"root_cause"`
+	Target      ObjectReference       `json:"target" yaml:"target"`
+	PatchType   string                `json:"patch_type" yaml:"patch_type"`
+	Patch       string                `json:"patch" yaml:"patch"` // JSON document
+}
+
+// ActionResult records the outcome of validating and applying a remediation action
+type ActionResult struct {
+	Action    RemediationAction `json:"action" yaml:"action"`
+	Diff      string            `json:"diff,omitempty" yaml:"diff,omitempty"`   // Unified diff of the object before and after the patch
+	Validated bool              `json:"validated" yaml:"validated"`             // Server-side dry-run succeeded
+	Applied   bool              `json:"applied" yaml:"applied"`                 // Patch was persisted (--apply)
+	Error     string            `json:"error,omitempty" yaml:"error,omitempty"` // Dry-run or apply failure
+}
@@ -39,6 +39,10 @@ type ReportSummary struct {
 	TotalContainers      int `json:"total_containers" yaml:"total_containers"`
 	ContainersWithIssues int `json:"containers_with_issues" yaml:"containers_with_issues"`
 
+	// Sampling breakdown (multi-pod scans with sampling enabled)
+	DirectlyAnalyzedPods int `json:"directly_analyzed_pods,omitempty" yaml:"directly_analyzed_pods,omitempty"`
+	InferredPods         int `json:"inferred_pods,omitempty" yaml:"inferred_pods,omitempty"`
+
 	// Root cause breakdown
 	RootCauseBreakdown map[RootCause]int `json:"root_cause_breakdown" yaml:"root_cause_breakdown"`
 
@@ -54,5 +58,5 @@ type AuditEntry struct {
 	ResourceType string    `json:"resource_type" yaml:"resource_type"` // "pods", "events", "secrets"
 	ResourceName string    `json:"resource_name" yaml:"resource_name"`
 	Namespace    string    `json:"namespace" yaml:"namespace"`
-	Operation    string    `json:"operation" yaml:"operation"` // "get", "list"
+	Operation    string    `json:"operation" yaml:"operation"` // "get", "list", "patch", "patch (dry-run)"
 }
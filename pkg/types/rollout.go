@@ -0,0 +1,24 @@
+package types
+
+// RolloutCorrelation identifies the Deployment revision that introduced a failing image
+type RolloutCorrelation struct {
+	Deployment         string        `json:"deployment" yaml:"deployment"`
+	Revision           int64         `json:"revision" yaml:"revision"`                       // Revision of the failing pod's ReplicaSet
+	IntroducedRevision int64         `json:"introduced_revision" yaml:"introduced_revision"` // First revision running the failing images
+	PreviousRevision   int64         `json:"previous_revision" yaml:"previous_revision"`     // Last revision before the image change (rollback target)
+	PreviousReplicaSet string        `json:"previous_replica_set" yaml:"previous_replica_set"`
+	PreviousReady      int32         `json:"previous_ready" yaml:"previous_ready"` // Ready pods still running the previous revision
+	ImageChanges       []ImageChange `json:"image_changes" yaml:"image_changes"`
+}
+
+// ImageChange is a container image changed between two revisions
+type ImageChange struct {
+	Container     string   `json:"container" yaml:"container"`
+	PreviousImage string   `json:"previous_image" yaml:"previous_image"`
+	CurrentImage  string   `json:"current_image" yaml:"current_image"`
+	CachedOnNodes []string `json:"cached_on_nodes,omitempty" yaml:"cached_on_nodes,omitempty"` // Nodes reporting the previous image in status.images
+
+	// Whether the previous image can still be pulled; nil when undetermined
+	Pullable       *bool  `json:"pullable,omitempty" yaml:"pullable,omitempty"`
+	PullableReason string `json:"pullable_reason,omitempty" yaml:"pullable_reason,omitempty"`
+}
@@ -1,5 +1,10 @@
 package types
 
+import (
+	"fmt"
+	"sync"
+)
+
 // RootCause represents the category of ImagePullBackOff failure
 type RootCause string
 
@@ -10,12 +15,40 @@ const (
 	RootCauseRateLimit        RootCause = "RATE_LIMIT_EXCEEDED"
 	RootCausePermissionDenied RootCause = "PERMISSION_DENIED"
 	RootCauseManifestError    RootCause = "MANIFEST_ERROR"
+	RootCauseTLSCertificate   RootCause = "TLS_CERTIFICATE_ERROR"
+	RootCauseNodeDiskPressure RootCause = "NODE_DISK_PRESSURE"
+	RootCausePolicyRejection  RootCause = "POLICY_REJECTION"
+	RootCauseNodeSpecific     RootCause = "NODE_SPECIFIC_FAILURE"
 	RootCauseTransient        RootCause = "TRANSIENT_FAILURE"
 	RootCauseUnknown          RootCause = "UNKNOWN"
+
+	// Root causes reported directly by kubelet container waiting reasons
+	RootCauseImageNeverPull      RootCause = "IMAGE_NEVER_PULL"            // ErrImageNeverPull
+	RootCauseInvalidImageName    RootCause = "INVALID_IMAGE_NAME"          // InvalidImageName
+	RootCauseRegistryUnavailable RootCause = "REGISTRY_UNAVAILABLE"        // RegistryUnavailable
+	RootCauseSignatureValidation RootCause = "SIGNATURE_VALIDATION_FAILED" // SignatureValidationFailed
 )
 
 // String returns human-readable description
 func (r RootCause) String() string {
+	if description := r.builtinDescription(); description != "" {
+		return description
+	}
+	customRootCausesMu.RLock()
+	defer customRootCausesMu.RUnlock()
+	if custom, ok := customRootCauses[r]; ok {
+		return custom.description
+	}
+	return "Unknown failure reason"
+}
+
+// IsBuiltin reports whether a root cause is detected or decided by k8t itself
+func (r RootCause) IsBuiltin() bool {
+	return r == RootCauseUnknown || r.builtinDescription() != ""
+}
+
+// builtinDescription returns the description of a built-in root cause, or "" for others
+func (r RootCause) builtinDescription() string {
 	switch r {
 	case RootCauseImageNotFound:
 		return "Image does not exist in registry"
@@ -29,27 +62,88 @@ func (r RootCause) String() string {
 		return "Insufficient permissions to pull image"
 	case RootCauseManifestError:
 		return "Image manifest is invalid or corrupted"
+	case RootCauseTLSCertificate:
+		return "Registry TLS certificate is not trusted or invalid"
+	case RootCauseNodeDiskPressure:
+		return "Node ran out of disk space while pulling the image"
+	case RootCausePolicyRejection:
+		return "Image blocked by an admission or image policy"
+	case RootCauseNodeSpecific:
+		return "Image pulls fail on specific nodes but succeed elsewhere"
 	case RootCauseTransient:
 		return "Transient failure (may resolve automatically)"
+	case RootCauseImageNeverPull:
+		return "Image not present on node and pull policy is Never"
+	case RootCauseInvalidImageName:
+		return "Image reference is invalid"
+	case RootCauseRegistryUnavailable:
+		return "Registry is unavailable"
+	case RootCauseSignatureValidation:
+		return "Image signature validation failed"
+	case HygieneInvalidReference:
+		return "Image reference cannot be pulled as written"
+	case HygieneDisallowedRegistry:
+		return "Image registry is not allowed by the image policy"
+	case HygieneLatestTag:
+		return "Image uses the mutable :latest tag"
+	case HygieneDigestRequired:
+		return "Image must be pinned by digest in this namespace"
+	case HygienePullPolicyMismatch:
+		return "imagePullPolicy is inconsistent with the tag's mutability"
+	case HygieneDockerHubNoSecret:
+		return "Docker Hub image is pulled anonymously and rate-limited"
 	default:
-		return "Unknown failure reason"
+		return ""
 	}
 }
 
 // Severity returns the urgency level
 func (r RootCause) Severity() Severity {
 	switch r {
-	case RootCauseImageNotFound, RootCauseAuthFailure, RootCausePermissionDenied:
+	case RootCauseImageNotFound, RootCauseAuthFailure, RootCausePermissionDenied,
+		RootCauseImageNeverPull, RootCauseInvalidImageName, RootCauseSignatureValidation,
+		RootCauseTLSCertificate, RootCauseNodeDiskPressure, RootCausePolicyRejection, RootCauseNodeSpecific,
+		HygieneInvalidReference, HygieneDisallowedRegistry, HygieneDigestRequired:
 		return SeverityHigh // Requires immediate action
-	case RootCauseNetworkIssue, RootCauseRateLimit, RootCauseManifestError:
+	case RootCauseNetworkIssue, RootCauseRateLimit, RootCauseManifestError, RootCauseRegistryUnavailable,
+		HygieneLatestTag, HygienePullPolicyMismatch, HygieneDockerHubNoSecret:
 		return SeverityMedium // Needs investigation
 	case RootCauseTransient:
 		return SeverityLow // May self-resolve
 	default:
+		customRootCausesMu.RLock()
+		defer customRootCausesMu.RUnlock()
+		if custom, ok := customRootCauses[r]; ok {
+			return custom.severity
+		}
 		return SeverityMedium
 	}
 }
 
+// customRootCause describes a root cause defined by a user rule
+type customRootCause struct {
+	description string
+	severity    Severity
+}
+
+// customRootCauses holds the root causes registered with RegisterRootCause
+var (
+	customRootCausesMu sync.RWMutex
+	customRootCauses   = map[RootCause]customRootCause{}
+)
+
+// RegisterRootCause makes String and Severity aware of a user-defined root cause
+// Registration happens while loading rules; built-in root causes cannot be redefined.
+func RegisterRootCause(r RootCause, description string, severity Severity) error {
+	if r.IsBuiltin() {
+		return fmt.Errorf("%s is a built-in root cause", string(r))
+	}
+	customRootCausesMu.Lock()
+	defer customRootCausesMu.Unlock()
+	customRootCauses[r] = customRootCause{description: description, severity: severity}
+	return nil
+}
+
 // Severity indicates urgency of diagnostic finding
 type Severity string
 
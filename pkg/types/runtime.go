@@ -0,0 +1,12 @@
+package types
+
+// ContainerRuntime identifies the CRI implementation running on a node
+// Pull error wording and node-side commands differ between runtimes.
+type ContainerRuntime string
+
+const (
+	RuntimeContainerd ContainerRuntime = "containerd"
+	RuntimeCRIO       ContainerRuntime = "cri-o"
+	RuntimeDocker     ContainerRuntime = "docker" // Docker Engine through cri-dockerd
+	RuntimeUnknown    ContainerRuntime = ""
+)
@@ -0,0 +1,253 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxReviewBytes bounds the AdmissionReview bodies read; the API server caps objects at 3 MiB
+const maxReviewBytes = 4 << 20
+
+// DefaultTimeout leaves room within the API server's default webhook timeout of 10s
+const DefaultTimeout = 3 * time.Second
+
+// Mode decides what happens to requests with HIGH findings
+type Mode string
+
+const (
+	ModeWarn Mode = "warn" // Admit, with the findings as admission warnings
+	ModeDeny Mode = "deny" // Reject HIGH findings; warn about the others
+)
+
+// Config tunes the admission checks
+type Config struct {
+	Mode     Mode
+	Timeout  time.Duration // Requests still being checked after it are admitted (fail open)
+	CacheTTL time.Duration // How long a pod template that passed all checks is not checked again
+	Probe    bool          // Ask registries whether each manifest exists
+}
+
+// Handler is a ValidatingAdmissionWebhook for Pods and pod-template workloads
+// It runs the checks of k8t lint on each pod template: image references,
+// pull secrets, and (with Probe) the registry manifests.
+type Handler struct {
+	az     *analyzer.Analyzer
+	logger *output.AuditLogger
+	cfg    Config
+	now    func() time.Time
+
+	mu     sync.Mutex
+	passed map[string]time.Time // Pod templates without findings, until the cache TTL expires
+}
+
+// NewHandler creates an admission handler using an analyzer's cluster client and mirrors
+func NewHandler(az *analyzer.Analyzer, logger *output.AuditLogger, cfg Config) *Handler {
+	if cfg.Mode == "" {
+		cfg.Mode = ModeWarn
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &Handler{
+		az:     az,
+		logger: logger,
+		cfg:    cfg,
+		now:    time.Now,
+		passed: make(map[string]time.Time),
+	}
+}
+
+// ServeHTTP answers an admission.k8s.io/v1 AdmissionReview
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxReviewBytes)).Decode(&review); err != nil {
+		http.Error(w, fmt.Sprintf("invalid AdmissionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "AdmissionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	response := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: admissionv1.SchemeGroupVersion.String(), Kind: "AdmissionReview"},
+		Response: h.Review(r.Context(), review.Request),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.LogError("Failed to write AdmissionReview response", err)
+	}
+}
+
+// Review decides on an admission request
+// Anything k8t cannot check in time, or cannot read, is admitted.
+func (h *Handler) Review(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: req.UID, Allowed: true}
+	if req.SubResource != "" || (req.Operation != admissionv1.Create && req.Operation != admissionv1.Update) {
+		return response
+	}
+
+	tmpl, ok, err := requestTemplate(req.Object.Raw, req.Namespace)
+	if err != nil {
+		response.Warnings = []string{fmt.Sprintf("k8t: images not checked: %v", err)}
+		return response
+	}
+	if !ok {
+		return response
+	}
+	if req.Operation == admissionv1.Update {
+		// Scaling or relabeling must not be blocked by an image that already runs
+		old, ok, err := requestTemplate(req.OldObject.Raw, req.Namespace)
+		if err == nil && ok && templateKey(old) == templateKey(tmpl) {
+			return response
+		}
+	}
+
+	findings, checked := h.check(ctx, tmpl)
+	if !checked {
+		response.Warnings = []string{fmt.Sprintf("k8t: images not checked within %s; admitted", h.cfg.Timeout)}
+		return response
+	}
+
+	var denied []string
+	for _, finding := range findings {
+		message := findingMessage(finding)
+		if h.cfg.Mode == ModeDeny && finding.Severity == types.SeverityHigh {
+			denied = append(denied, message)
+			continue
+		}
+		response.Warnings = append(response.Warnings, "k8t: "+message)
+	}
+	if len(denied) > 0 {
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: fmt.Sprintf("k8t: %s %s/%s would fail to pull images: %s", tmpl.Kind, tmpl.Namespace, tmpl.Name, strings.Join(denied, "; ")),
+		}
+	}
+	return response
+}
+
+// check returns the findings of a template, skipping templates that recently passed
+// checked is false when the checks did not complete within the timeout.
+func (h *Handler) check(ctx context.Context, tmpl k8s.ManifestTemplate) (findings []types.LintFinding, checked bool) {
+	key := templateKey(tmpl)
+	h.mu.Lock()
+	expires, ok := h.passed[key]
+	h.mu.Unlock()
+	if ok && h.now().Before(expires) {
+		return nil, true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+	defer cancel()
+
+	// The checks run apart so that a slow registry cannot hold the API server past the timeout
+	done := make(chan []types.LintFinding, 1)
+	go func() {
+		manifests := &k8s.Manifests{Templates: []k8s.ManifestTemplate{tmpl}}
+		done <- h.az.LintManifests(ctx, manifests, analyzer.LintOptions{Probe: h.cfg.Probe})
+	}()
+
+	select {
+	case findings = <-done:
+	case <-ctx.Done():
+		return nil, false
+	}
+	if ctx.Err() != nil {
+		// Probes cut short by the deadline are missing from the findings
+		return nil, false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.now()
+	for k, expires := range h.passed {
+		if !now.Before(expires) {
+			delete(h.passed, k)
+		}
+	}
+	// Only clean results are reused: a missing tag may be pushed, a secret created or
+	// an unreachable registry come back right after a denial or warning
+	if len(findings) == 0 {
+		h.passed[key] = now.Add(h.cfg.CacheTTL)
+	}
+	return findings, true
+}
+
+// requestTemplate reads the pod template of an admitted object
+// Pods created by a controller are skipped: their template was checked.
+func requestTemplate(raw []byte, namespace string) (k8s.ManifestTemplate, bool, error) {
+	if len(raw) == 0 {
+		return k8s.ManifestTemplate{}, false, nil
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(raw); err != nil {
+		return k8s.ManifestTemplate{}, false, err
+	}
+	if obj.GetKind() == "Pod" {
+		for _, owner := range obj.GetOwnerReferences() {
+			if owner.Controller != nil && *owner.Controller {
+				return k8s.ManifestTemplate{}, false, nil
+			}
+		}
+	}
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(namespace)
+	}
+	if obj.GetName() == "" {
+		obj.SetName(obj.GetGenerateName())
+	}
+	return k8s.TemplateFromObject(obj, "admission")
+}
+
+// templateKey identifies what the findings of a template depend on: its
+// namespace, ServiceAccount, pull secrets and images
+func templateKey(tmpl k8s.ManifestTemplate) string {
+	spec := tmpl.Spec
+	parts := []string{tmpl.Namespace, spec.ServiceAccountName}
+	var secrets []string
+	for _, ref := range spec.ImagePullSecrets {
+		secrets = append(secrets, ref.Name)
+	}
+	sort.Strings(secrets)
+	parts = append(parts, strings.Join(secrets, ","))
+	for _, container := range spec.InitContainers {
+		parts = append(parts, container.Name+"="+container.Image)
+	}
+	for _, container := range spec.Containers {
+		parts = append(parts, container.Name+"="+container.Image)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// findingMessage summarizes a finding on one line, as admission warnings require
+func findingMessage(finding types.LintFinding) string {
+	message := fmt.Sprintf("%s: %s", string(finding.RootCause), finding.Message)
+	if finding.Container != "" {
+		message = fmt.Sprintf("container %s: %s", finding.Container, message)
+	}
+	return message
+}
@@ -0,0 +1,213 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// cachingNode returns a Ready node whose status lists images by tag and digest
+func cachingNode(name string, images ...[]string) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+		}},
+	}
+	for _, names := range images {
+		node.Status.Images = append(node.Status.Images, corev1.ContainerImage{Names: names, SizeBytes: 1 << 20})
+	}
+	return node
+}
+
+var cachedApp = []string{
+	"registry.example.com/app@sha256:" + "ab12000000000000000000000000000000000000000000000000000000000000",
+	"registry.example.com/app:v1",
+}
+
+func TestNodeImageCache_TagAndDigest(t *testing.T) {
+	nodes := []corev1.Node{
+		*cachingNode("node-a", cachedApp),
+		*cachingNode("node-b", []string{"registry.example.com/app:v2"}),
+	}
+
+	cached := k8s.NodeImageCache(nodes, "registry.example.com/app:v1")
+	if len(cached) != 1 || cached[0].Node != "node-a" || !cached[0].ByTag || cached[0].ByDigest {
+		t.Fatalf("Cached = %+v, want node-a by tag", cached)
+	}
+	if cached[0].Digest != "sha256:ab12000000000000000000000000000000000000000000000000000000000000" {
+		t.Errorf("Digest = %q, want the entry's digest", cached[0].Digest)
+	}
+
+	// A digest reference is matched by digest only
+	cached = k8s.NodeImageCache(nodes, cachedApp[0])
+	if len(cached) != 1 || !cached[0].ByDigest {
+		t.Errorf("Cached = %+v, want node-a by digest", cached)
+	}
+}
+
+func TestImageCacheFor_Prediction(t *testing.T) {
+	cordoned := cachingNode("node-c", cachedApp)
+	cordoned.Spec.Unschedulable = true
+	nodes := []corev1.Node{*cachingNode("node-a", cachedApp), *cachingNode("node-b"), *cordoned}
+
+	tests := []struct {
+		name      string
+		container corev1.Container
+		startsOn  int
+		mustPull  int
+		contains  string
+	}{
+		{"IfNotPresent", corev1.Container{Name: "app", Image: "registry.example.com/app:v1"}, 1, 1, "starts on 1 of 2"},
+		{"Always", corev1.Container{Name: "app", Image: "registry.example.com/app:v1", ImagePullPolicy: corev1.PullAlways}, 0, 2, "pulls on all 2"},
+		{"Never", corev1.Container{Name: "app", Image: "registry.example.com/app:v1", ImagePullPolicy: corev1.PullNever}, 1, 1, "ErrImageNeverPull on the other 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := analyzer.ImageCacheFor(tt.container, nodes)
+			if status.SchedulableNodes != 2 || len(status.StartsOn) != tt.startsOn || status.MustPull != tt.mustPull {
+				t.Errorf("Status = %+v, want %d starting, %d pulling of 2", status, tt.startsOn, tt.mustPull)
+			}
+			if len(status.CachedOn) != 2 {
+				t.Errorf("CachedOn = %+v, want node-a and the cordoned node-c", status.CachedOn)
+			}
+			if !contains(status.Prediction, tt.contains) {
+				t.Errorf("Prediction = %q, want %q", status.Prediction, tt.contains)
+			}
+		})
+	}
+}
+
+func TestEffectivePullPolicy_Defaults(t *testing.T) {
+	tests := map[string]corev1.PullPolicy{
+		"nginx":        corev1.PullAlways,
+		"nginx:latest": corev1.PullAlways,
+		"nginx:1.25":   corev1.PullIfNotPresent,
+		"nginx@sha256:" + "ab12000000000000000000000000000000000000000000000000000000000000": corev1.PullIfNotPresent,
+	}
+	for image, want := range tests {
+		if got := analyzer.EffectivePullPolicy(corev1.Container{Name: "app", Image: image}); got != want {
+			t.Errorf("EffectivePullPolicy(%s) = %s, want %s", image, got, want)
+		}
+	}
+}
+
+func TestImageExposure_Cache(t *testing.T) {
+	cached := imagePullPod("shop", "cached")
+	partial := imagePullPod("shop", "partial")
+	partial.Spec.Containers[0].Image = "registry.example.com/app:v2"
+	always := imagePullPod("shop", "always")
+	always.Spec.Containers[0].ImagePullPolicy = corev1.PullAlways
+
+	nodes := []runtime.Object{
+		cachingNode("node-a", cachedApp, []string{"registry.example.com/app:v2"}),
+		cachingNode("node-b", cachedApp),
+	}
+	az, _, _ := newFixAnalyzer(t, nodes...)
+
+	exposures, err := az.ImageExposure(context.Background(), []corev1.Pod{cached, partial, always}, true)
+	if err != nil {
+		t.Fatalf("ImageExposure: %v", err)
+	}
+	want := map[string]types.Severity{
+		"always":  types.SeverityHigh,
+		"cached":  types.SeverityLow,
+		"partial": types.SeverityMedium,
+	}
+	if len(exposures) != len(want) {
+		t.Fatalf("Exposures = %+v, want %d workloads", exposures, len(want))
+	}
+	for _, exposure := range exposures {
+		if exposure.Exposure != want[exposure.Name] {
+			t.Errorf("%s exposure = %s (%s), want %s", exposure.Name, exposure.Exposure, exposure.Reason, want[exposure.Name])
+		}
+	}
+
+	// Without --cache only the pull policy is rated
+	exposures, err = az.ImageExposure(context.Background(), []corev1.Pod{cached, always}, false)
+	if err != nil {
+		t.Fatalf("ImageExposure: %v", err)
+	}
+	if exposures[0].Exposure != types.SeverityHigh || exposures[1].Exposure != "" {
+		t.Errorf("Exposures = %+v, want always HIGH and cached unrated", exposures)
+	}
+}
+
+// nodeLists counts the node list calls a fake cluster received
+func nodeLists(clientset *fake.Clientset) int {
+	count := 0
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "nodes" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestListNodes_RetriesAfterFailure(t *testing.T) {
+	pod := imagePullPod("shop", "cached")
+	az, _, clientset := newFixAnalyzer(t, cachingNode("node-a", cachedApp))
+	failures := 1
+	clientset.PrependReactor("list", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			failures--
+			return true, nil, context.DeadlineExceeded
+		}
+		return false, nil, nil
+	})
+
+	if _, err := az.ImageExposure(context.Background(), []corev1.Pod{pod}, true); err == nil {
+		t.Fatal("Expected the first node list to fail")
+	}
+	// A failure, e.g. one pod's deadline, must not disable node data for the rest of a scan
+	exposures, err := az.ImageExposure(context.Background(), []corev1.Pod{pod}, true)
+	if err != nil {
+		t.Fatalf("ImageExposure after a failed list: %v", err)
+	}
+	if len(exposures) != 1 || exposures[0].Exposure != types.SeverityLow {
+		t.Errorf("Exposures = %+v, want the cached image rated LOW", exposures)
+	}
+	if _, err := az.ImageExposure(context.Background(), []corev1.Pod{pod}, true); err != nil || nodeLists(clientset) != 2 {
+		t.Errorf("Node lists = %d (%v), want the successful list cached", nodeLists(clientset), err)
+	}
+}
+
+func TestListNodes_DeniedOnce(t *testing.T) {
+	pod := imagePullPod("shop", "cached")
+	az, _, clientset := newFixAnalyzer(t, cachingNode("node-a", cachedApp))
+	clientset.PrependReactor("list", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, k8serrors.NewForbidden(schema.GroupResource{Resource: "nodes"}, "", nil)
+	})
+
+	// Without permission to list nodes, retrying per pod only repeats the warning
+	for i := 0; i < 3; i++ {
+		if _, err := az.ImageExposure(context.Background(), []corev1.Pod{pod}, true); !k8serrors.IsForbidden(err) {
+			t.Fatalf("ImageExposure = %v, want the forbidden error", err)
+		}
+	}
+	if n := nodeLists(clientset); n != 1 {
+		t.Errorf("Node lists = %d, want 1 when forbidden", n)
+	}
+}
+
+func TestAnalyzePods_ListsNodesOnce(t *testing.T) {
+	pods := []corev1.Pod{imagePullPod("shop", "a"), imagePullPod("shop", "b"), imagePullPod("shop", "c")}
+	az, _, clientset := newFixAnalyzer(t, cachingNode("node-a", cachedApp))
+
+	if _, err := az.AnalyzePods(context.Background(), pods, analyzer.ScanOptions{Concurrency: 3}); err != nil {
+		t.Fatalf("AnalyzePods: %v", err)
+	}
+	if n := nodeLists(clientset); n != 1 {
+		t.Errorf("Node lists = %d, want 1 per scan", n)
+	}
+}
@@ -0,0 +1,167 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// celInputs wraps pods as policy inputs without nodes or findings
+func celInputs(pods ...corev1.Pod) []analyzer.CELInput {
+	inputs := make([]analyzer.CELInput, 0, len(pods))
+	for i := range pods {
+		inputs = append(inputs, analyzer.CELInput{Pod: &pods[i]})
+	}
+	return inputs
+}
+
+func TestCELPolicies_ImageScope(t *testing.T) {
+	policies, err := analyzer.ParseCELPolicies([]byte(`
+celPolicies:
+  - id: CEL_PROD_TAG_REFERENCE
+    description: Production images must be pinned by digest
+    expression: pod.metadata.namespace.startsWith('prod-') && !image.isDigest
+    messageExpression: "'container ' + image.containerName + ' uses ' + image.fullReference"
+    severity: high
+`))
+	if err != nil {
+		t.Fatalf("ParseCELPolicies: %v", err)
+	}
+
+	var pods []corev1.Pod
+	for _, name := range []string{"web-7c4d-a", "web-7c4d-b"} {
+		pod := runningReplica(name, "registry.example.com/app:v1", digestOld)
+		pod.Namespace = "prod-eu"
+		pods = append(pods, pod)
+	}
+	pods = append(pods,
+		auditedPod("prod-eu", "pinned", "registry.example.com/app@"+digestOld, corev1.PullIfNotPresent),
+		auditedPod("default", "dev", "registry.example.com/app:v1", corev1.PullIfNotPresent))
+
+	findings, errs := policies.Evaluate(celInputs(pods...))
+	if len(errs) != 0 {
+		t.Fatalf("Evaluation errors: %v", errs)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("Findings = %d, want 1 for the replicas of Deployment web", len(findings))
+	}
+	finding := findings[0]
+	if finding.RootCause != "CEL_PROD_TAG_REFERENCE" || finding.Severity != types.SeverityHigh {
+		t.Errorf("Finding = %s (%s), want CEL_PROD_TAG_REFERENCE (HIGH)", string(finding.RootCause), finding.Severity)
+	}
+	if finding.Scope != types.FindingScopeWorkload || finding.Subject != "Deployment/web" {
+		t.Errorf("Scope = %s %s, want workload Deployment/web", finding.Scope, finding.Subject)
+	}
+	if finding.Summary != "CEL_PROD_TAG_REFERENCE: container app uses registry.example.com/app:v1" {
+		t.Errorf("Summary = %q, want the message expression", finding.Summary)
+	}
+	if !strings.Contains(finding.Details, "2 pods") {
+		t.Errorf("Details = %q, want the pod count", finding.Details)
+	}
+	if len(finding.RemediationSteps) == 0 {
+		t.Error("Expected a default remediation step")
+	}
+	if policy, ok := policies.Policy(finding.RootCause); !ok || policy.Description != "Production images must be pinned by digest" {
+		t.Errorf("Policy(%s) = %+v, want the policy and its description", string(finding.RootCause), policy)
+	}
+	// Policy IDs stay in their set: loading policies does not redefine root causes globally
+	if got := finding.RootCause.String(); got != types.RootCauseUnknown.String() {
+		t.Errorf("Global description = %q, want none registered", got)
+	}
+}
+
+func TestCELPolicies_PodScopeSeesNodeAndFindings(t *testing.T) {
+	policies, err := analyzer.ParseCELPolicies([]byte(`
+celPolicies:
+  - id: CEL_ZONE_PULL_FAILURE
+    description: Image pull failures in zone-1
+    scope: pod
+    expression: >
+      node.metadata.labels['topology.kubernetes.io/zone'] == 'zone-1' &&
+      findings.exists(f, f.rootCause == 'IMAGE_NOT_FOUND')
+    message: Pull failure in zone-1
+    remediation: [Check the zone-1 registry cache]
+`))
+	if err != nil {
+		t.Fatalf("ParseCELPolicies: %v", err)
+	}
+
+	failing := scheduledPod("failing", "node-a", true)
+	healthy := scheduledPod("healthy", "node-a", false)
+	other := scheduledPod("other-zone", "node-b", true)
+	nodeA := zonedNode("node-a", "zone-1", "default-pool")
+	nodeB := zonedNode("node-b", "zone-2", "default-pool")
+	notFound := []types.DiagnosticFinding{{RootCause: types.RootCauseImageNotFound, AffectedContainers: []string{"app"}}}
+
+	findings, errs := policies.Evaluate([]analyzer.CELInput{
+		{Pod: &failing, Node: &nodeA, Findings: notFound},
+		{Pod: &healthy, Node: &nodeA},
+		{Pod: &other, Node: &nodeB, Findings: notFound},
+	})
+	if len(errs) != 0 {
+		t.Fatalf("Evaluation errors: %v", errs)
+	}
+	if len(findings) != 1 || findings[0].PodName != "failing" {
+		t.Fatalf("Findings = %+v, want one for pod failing", findings)
+	}
+	if findings[0].Severity != types.SeverityMedium || findings[0].RemediationSteps[0] != "Check the zone-1 registry cache" {
+		t.Errorf("Finding = %+v, want MEDIUM with the policy remediation", findings[0])
+	}
+	if len(findings[0].AffectedContainers) != 0 {
+		t.Errorf("AffectedContainers = %v, want none for a pod-scoped policy", findings[0].AffectedContainers)
+	}
+}
+
+func TestCELPolicies_MissingFields(t *testing.T) {
+	policies, err := analyzer.ParseCELPolicies([]byte(`
+celPolicies:
+  - id: CEL_TEAM_LABEL
+    description: Pods must belong to the platform team
+    scope: pod
+    expression: pod.metadata.labels.team != 'platform'
+  - id: CEL_TEAM_LABEL_OPTIONAL
+    description: Pods must belong to the platform team
+    scope: pod
+    expression: pod.metadata.?labels.?team.orValue('') != 'platform'
+`))
+	if err != nil {
+		t.Fatalf("ParseCELPolicies: %v", err)
+	}
+
+	pod := auditedPod("default", "unlabeled", "registry.example.com/app:v1", corev1.PullIfNotPresent)
+	findings, errs := policies.Evaluate(celInputs(pod))
+	if len(findings) != 1 || findings[0].RootCause != "CEL_TEAM_LABEL_OPTIONAL" {
+		t.Errorf("Findings = %+v, want only the policy using optional selection", findings)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "CEL_TEAM_LABEL") {
+		t.Errorf("Errors = %v, want one naming the policy", errs)
+	}
+}
+
+func TestParseCELPolicies_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{"builtin id", "id: IMAGE_NOT_FOUND\n    description: x\n    expression: 'true'", "built-in"},
+		{"hygiene rule id", "id: LATEST_TAG\n    description: x\n    expression: 'true'", "built-in"},
+		{"lowercase id", "id: custom\n    description: x\n    expression: 'true'", "upper case"},
+		{"not bool", "id: CEL_INVALID\n    description: x\n    expression: \"'yes'\"", "must evaluate to bool"},
+		{"syntax", "id: CEL_INVALID\n    description: x\n    expression: 'pod.'", "expression"},
+		{"unknown variable", "id: CEL_INVALID\n    description: x\n    expression: 'container.name == \"x\"'", "undeclared reference"},
+		{"severity", "id: CEL_INVALID\n    description: x\n    expression: 'true'\n    severity: URGENT", "severity"},
+		{"scope", "id: CEL_INVALID\n    description: x\n    expression: 'true'\n    scope: node", "scope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := analyzer.ParseCELPolicies([]byte("celPolicies:\n  - " + tt.policy + "\n"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package unit
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunConcurrently_BoundsWorkers(t *testing.T) {
+	const total = 50
+	const concurrency = 4
+
+	var running, peak int32
+	results := make([]int, total)
+	progressCalls := int32(0)
+
+	err := analyzer.RunConcurrently(context.Background(), concurrency, total, func(ctx context.Context, i int) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		results[i] = i * i
+		atomic.AddInt32(&running, -1)
+	}, func(done, total int) {
+		atomic.AddInt32(&progressCalls, 1)
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if peak > concurrency {
+		t.Errorf("Peak concurrency = %d, want <= %d", peak, concurrency)
+	}
+	if progressCalls != total {
+		t.Errorf("Progress calls = %d, want %d", progressCalls, total)
+	}
+	for i, r := range results {
+		if r != i*i {
+			t.Errorf("results[%d] = %d, want %d", i, r, i*i)
+		}
+	}
+}
+
+func TestRunConcurrently_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var calls int32
+	err := analyzer.RunConcurrently(ctx, 2, 100, func(ctx context.Context, i int) {
+		atomic.AddInt32(&calls, 1)
+	}, nil)
+
+	if err == nil {
+		t.Fatal("Expected context error but got none")
+	}
+	if calls == 100 {
+		t.Errorf("Expected dispatch to stop after cancellation, all %d items ran", calls)
+	}
+}
+
+func TestAnalyzePods_DeterministicOrder(t *testing.T) {
+	var objects []corev1.Pod
+	// Deliberately unsorted input across two namespaces
+	for _, id := range [][2]string{{"ns-b", "pod-2"}, {"ns-a", "pod-3"}, {"ns-b", "pod-1"}, {"ns-a", "pod-1"}} {
+		objects = append(objects, imagePullPod(id[0], id[1]))
+	}
+
+	clientset := fake.NewSimpleClientset()
+	for i := range objects {
+		if _, err := clientset.CoreV1().Pods(objects[i].Namespace).Create(context.Background(), &objects[i], metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Failed to create pod: %v", err)
+		}
+	}
+
+	logger, err := output.NewAuditLogger(false)
+	if err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+	az := analyzer.NewAnalyzer(&k8s.Client{Clientset: clientset}, logger, 5*time.Second)
+
+	results, err := az.AnalyzePods(context.Background(), objects, analyzer.ScanOptions{Concurrency: 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []string{"ns-a/pod-1", "ns-a/pod-3", "ns-b/pod-1", "ns-b/pod-2"}
+	if len(results) != len(want) {
+		t.Fatalf("Got %d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		got := result.Pod.Namespace + "/" + result.Pod.Name
+		if got != want[i] {
+			t.Errorf("results[%d] = %s, want %s", i, got, want[i])
+		}
+		if result.Err != nil {
+			t.Errorf("results[%d] unexpected error: %v", i, result.Err)
+		}
+	}
+
+	report := analyzer.MergeReports(types.TargetTypeNamespace, "cluster", "", results)
+	if report.Summary.TotalPodsAnalyzed != 4 {
+		t.Errorf("TotalPodsAnalyzed = %d, want 4", report.Summary.TotalPodsAnalyzed)
+	}
+	if len(report.Findings) != 4 {
+		t.Fatalf("Findings = %d, want 4", len(report.Findings))
+	}
+	for i, finding := range report.Findings {
+		got := finding.PodNamespace + "/" + finding.PodName
+		if got != want[i] {
+			t.Errorf("Findings[%d] = %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+// imagePullPod builds a pod whose single container is stuck in ImagePullBackOff
+func imagePullPod(namespace, name string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "registry.example.com/app:v1"}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "app",
+				Image: "registry.example.com/app:v1",
+				State: corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"},
+				},
+			}},
+		},
+	}
+}
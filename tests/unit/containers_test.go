@@ -0,0 +1,138 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// pullEvent creates a Failed event for the pod, optionally scoped to a container
+func pullEvent(pod *corev1.Pod, name, fieldPath, message string) *corev1.Event {
+	now := metav1.NewTime(time.Now())
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: pod.Namespace},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "Pod",
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			FieldPath: fieldPath,
+		},
+		Reason:         "Failed",
+		Message:        message,
+		Count:          5,
+		FirstTimestamp: metav1.NewTime(now.Add(-10 * time.Minute)),
+		LastTimestamp:  now,
+	}
+}
+
+func TestAnalyzePodObject_FindingPerRootCause(t *testing.T) {
+	waiting := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "fetch-config", Image: "registry.example.com/config:v1"}},
+			Containers: []corev1.Container{
+				{Name: "app", Image: "nginx:1.25"},
+				{Name: "proxy", Image: "envoyproxy/envoy:v1.29"},
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase:                 corev1.PodPending,
+			InitContainerStatuses: []corev1.ContainerStatus{{Name: "fetch-config", State: waiting}},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", State: waiting},
+				{Name: "proxy", State: waiting},
+			},
+		},
+	}
+
+	client := fake.NewSimpleClientset(
+		// Attributed through involvedObject.fieldPath
+		pullEvent(pod, "e1", "spec.initContainers{fetch-config}",
+			`Failed to pull image "registry.example.com/config:v1": rpc error: code = Unknown desc = failed to authorize: 401 Unauthorized`),
+		// Attributed through the quoted image reference
+		pullEvent(pod, "e2", "",
+			`Failed to pull image "nginx:1.25": toomanyrequests: You have reached your pull rate limit`),
+		pullEvent(pod, "e3", "",
+			`Failed to pull image "envoyproxy/envoy:v1.29": toomanyrequests: You have reached your pull rate limit`),
+	)
+
+	logger, err := output.NewAuditLogger(false)
+	if err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+	az := analyzer.NewAnalyzer(&k8s.Client{Clientset: client}, logger, 5*time.Second)
+
+	report, err := az.AnalyzePodObject(context.Background(), pod)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(report.Findings) != 2 {
+		t.Fatalf("Got %d findings, want 2: %+v", len(report.Findings), report.Findings)
+	}
+
+	want := map[types.RootCause][]string{
+		types.RootCauseAuthFailure: {"fetch-config"},
+		types.RootCauseRateLimit:   {"app", "proxy"},
+	}
+	for _, finding := range report.Findings {
+		containers, ok := want[finding.RootCause]
+		if !ok {
+			t.Errorf("Unexpected root cause %s for %v", finding.RootCause, finding.AffectedContainers)
+			continue
+		}
+		if len(finding.AffectedContainers) != len(containers) {
+			t.Errorf("%s: containers = %v, want %v", finding.RootCause, finding.AffectedContainers, containers)
+			continue
+		}
+		for i := range containers {
+			if finding.AffectedContainers[i] != containers[i] {
+				t.Errorf("%s: containers = %v, want %v", finding.RootCause, finding.AffectedContainers, containers)
+				break
+			}
+		}
+		// Each finding only carries the images and events of its own containers
+		if len(finding.ImageReferences) != len(containers) {
+			t.Errorf("%s: %d image references, want %d", finding.RootCause, len(finding.ImageReferences), len(containers))
+		}
+		if len(finding.Events) != len(containers) {
+			t.Errorf("%s: %d events, want %d", finding.RootCause, len(finding.Events), len(containers))
+		}
+	}
+
+	if report.Summary.RootCauseBreakdown[types.RootCauseAuthFailure] != 1 ||
+		report.Summary.RootCauseBreakdown[types.RootCauseRateLimit] != 1 {
+		t.Errorf("RootCauseBreakdown = %v, want one pod for each cause", report.Summary.RootCauseBreakdown)
+	}
+	if report.Summary.ContainersWithIssues != 3 {
+		t.Errorf("ContainersWithIssues = %d, want 3", report.Summary.ContainersWithIssues)
+	}
+}
+
+func TestEventContainer(t *testing.T) {
+	tests := []struct {
+		fieldPath string
+		want      string
+	}{
+		{"spec.containers{app}", "app"},
+		{"spec.initContainers{fetch-config}", "fetch-config"},
+		{"", ""},
+		{"spec.containers", ""},
+	}
+
+	for _, tt := range tests {
+		event := &corev1.Event{InvolvedObject: corev1.ObjectReference{FieldPath: tt.fieldPath}}
+		if got := k8s.EventContainer(event); got != tt.want {
+			t.Errorf("EventContainer(%q) = %q, want %q", tt.fieldPath, got, tt.want)
+		}
+	}
+}
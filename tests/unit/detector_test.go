@@ -0,0 +1,83 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/types"
+)
+
+func eventEvidence(messages ...string) []types.Evidence {
+	evidence := make([]types.Evidence, 0, len(messages))
+	for _, message := range messages {
+		evidence = append(evidence, types.Evidence{Source: types.EvidenceSourceEvent, Reason: "Failed", Message: message})
+	}
+	return evidence
+}
+
+func TestScoreRootCauses_SpecificOutweighsGeneric(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    types.RootCause
+	}{
+		{
+			// "failed" used to select NETWORK_ISSUE for every kubelet message
+			name:    "Generic failure wording is not a network issue",
+			message: `Failed to pull image "registry.example.com/app:v1": rpc error: code = Unknown desc = failed to pull and unpack image: manifest unknown`,
+			want:    types.RootCauseImageNotFound,
+		},
+		{
+			// A 404 in a URL path used to win over the actual auth error
+			name:    "Status codes in paths do not override explicit errors",
+			message: `Failed to pull image "registry.example.com/team404/app:v1": unexpected status from HEAD request: 401 Unauthorized: authentication required`,
+			want:    types.RootCauseAuthFailure,
+		},
+		{
+			name:    "Only generic fragments is unknown",
+			message: `Failed to pull image "registry.example.com/app:v1": rpc error: code = Unknown desc = 404`,
+			want:    types.RootCauseUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detection := analyzer.ScoreRootCauses(eventEvidence(tt.message), analyzer.ParseEvents(nil))
+			if detection.RootCause != tt.want {
+				t.Errorf("Root cause = %s, want %s (candidates %+v)", detection.RootCause, tt.want, detection.Candidates)
+			}
+		})
+	}
+}
+
+func TestScoreRootCauses_RankedCandidates(t *testing.T) {
+	// Docker Hub reports missing and private repositories with the same message
+	detection := analyzer.ScoreRootCauses(eventEvidence(
+		`Failed to pull image "acme/app:v1": pull access denied for acme/app, repository does not exist or may require 'docker login'`,
+	), analyzer.ParseEvents(nil))
+
+	if detection.RootCause != types.RootCauseImageNotFound {
+		t.Fatalf("Root cause = %s, want %s", detection.RootCause, types.RootCauseImageNotFound)
+	}
+	if detection.Confidence <= 0 || detection.Confidence >= 1 {
+		t.Errorf("Confidence = %.2f, want between 0 and 1 for an ambiguous message", detection.Confidence)
+	}
+
+	alternatives := detection.Alternatives()
+	if len(alternatives) == 0 || alternatives[0].RootCause != types.RootCauseAuthFailure {
+		t.Fatalf("Alternatives = %+v, want AUTHENTICATION_FAILURE first", alternatives)
+	}
+	if len(alternatives[0].Matches) == 0 || alternatives[0].Matches[0].Pattern != "pull access denied" {
+		t.Errorf("Alternative matches = %+v, want pull access denied", alternatives[0].Matches)
+	}
+
+	if len(detection.Matches) == 0 || !strings.Contains(detection.Matches[0].Fragment, "repository does not exist") {
+		t.Errorf("Matches = %+v, want fragment containing the matched text", detection.Matches)
+	}
+
+	last := detection.Trace[len(detection.Trace)-1]
+	if !strings.HasPrefix(last, "selected IMAGE_NOT_FOUND") {
+		t.Errorf("Last trace line = %q, want selection of IMAGE_NOT_FOUND", last)
+	}
+}
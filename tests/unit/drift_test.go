@@ -0,0 +1,140 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	digestOld = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	digestNew = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+// runningReplica returns a running pod of Deployment web reporting the digest it runs
+func runningReplica(name, image, digest string) corev1.Pod {
+	controller := true
+	pod := scheduledPod(name, "node-a", false)
+	pod.Labels = map[string]string{"pod-template-hash": "7c4d"}
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7c4d", Controller: &controller}}
+	pod.Spec.Containers[0].Image = image
+	pod.Status.ContainerStatuses[0].Image = image
+	pod.Status.ContainerStatuses[0].ImageID = "registry.example.com/app@" + digest
+	return pod
+}
+
+func TestDetectImageDrift_ReplicasDiffer(t *testing.T) {
+	pods := []corev1.Pod{
+		runningReplica("web-7c4d-a", "registry.example.com/app:v1", digestOld),
+		runningReplica("web-7c4d-b", "registry.example.com/app:v1", digestOld),
+		runningReplica("web-7c4d-c", "registry.example.com/app:v1", digestNew),
+	}
+
+	drifts := analyzer.DetectImageDrift(pods, nil)
+	if len(drifts) != 1 {
+		t.Fatalf("Drifts = %d, want 1", len(drifts))
+	}
+	drift := drifts[0]
+	if drift.Kind != "Deployment" || drift.Name != "web" || drift.Replicas != 3 {
+		t.Errorf("Workload = %s/%s with %d replicas, want Deployment/web with 3", drift.Kind, drift.Name, drift.Replicas)
+	}
+	if len(drift.Kinds) != 1 || drift.Kinds[0] != types.DriftReplicasDiffer || drift.Severity != types.SeverityHigh {
+		t.Errorf("Kinds = %v (%s), want REPLICAS_DIFFER (HIGH)", drift.Kinds, drift.Severity)
+	}
+	if len(drift.Digests) != 2 || drift.Digests[0].Digest != digestOld || len(drift.Digests[0].Pods) != 2 {
+		t.Errorf("Digests = %+v, want the majority digest first", drift.Digests)
+	}
+	if want := "registry.example.com/app:v1@" + digestOld; drift.PinnedImage != want {
+		t.Errorf("PinnedImage = %q, want %q", drift.PinnedImage, want)
+	}
+}
+
+func TestDetectImageDrift_TagMovedAndLatest(t *testing.T) {
+	pods := []corev1.Pod{
+		runningReplica("web-7c4d-a", "registry.example.com/app", digestOld),
+		runningReplica("web-7c4d-b", "registry.example.com/app", digestOld),
+	}
+
+	drifts := analyzer.DetectImageDrift(pods, map[string]string{"registry.example.com/app": digestNew})
+	if len(drifts) != 1 {
+		t.Fatalf("Drifts = %d, want 1", len(drifts))
+	}
+	drift := drifts[0]
+	if len(drift.Kinds) != 2 || drift.Kinds[0] != types.DriftTagMoved || drift.Kinds[1] != types.DriftLatestTag {
+		t.Errorf("Kinds = %v, want TAG_MOVED and LATEST_TAG", drift.Kinds)
+	}
+	if drift.Severity != types.SeverityMedium {
+		t.Errorf("Severity = %s, want MEDIUM", drift.Severity)
+	}
+	if !mentions(drift.Details, "now resolves to sha256:222222222222") {
+		t.Errorf("Details = %v, want the registry digest", drift.Details)
+	}
+}
+
+func TestDetectImageDrift_Consistent(t *testing.T) {
+	pods := []corev1.Pod{
+		runningReplica("web-7c4d-a", "registry.example.com/app:v1", digestOld),
+		runningReplica("web-7c4d-b", "registry.example.com/app:v1", digestOld),
+		runningReplica("web-7c4d-c", "registry.example.com/app@"+digestNew, digestNew),
+	}
+
+	if drifts := analyzer.DetectImageDrift(pods, map[string]string{"registry.example.com/app:v1": digestOld}); len(drifts) != 0 {
+		t.Errorf("Drifts = %+v, want none for a consistent tag and a digest reference", drifts)
+	}
+}
+
+func TestAnalyzeImageDrift_PinPatch(t *testing.T) {
+	pods := []corev1.Pod{
+		runningReplica("web-7c4d-a", "registry.example.com/app:v1", digestOld),
+		runningReplica("web-7c4d-b", "registry.example.com/app:v1", digestNew),
+	}
+	az, _, _ := newFixAnalyzer(t, revisionReplicaSet("web-7c4d", "3", "registry.example.com/app:v1", 2))
+
+	drifts := az.AnalyzeImageDrift(context.Background(), pods, false)
+	if len(drifts) != 1 || drifts[0].Action == nil {
+		t.Fatalf("Drifts = %+v, want one with a pin action", drifts)
+	}
+	action := drifts[0].Action
+	if action.Target.Kind != "Deployment" || action.Target.Name != "web" {
+		t.Errorf("Target = %+v, want Deployment web", action.Target)
+	}
+	// One replica each: ties go to the lower digest
+	want := fmt.Sprintf(`{"spec":{"template":{"spec":{"containers":[{"image":"registry.example.com/app:v1@%s","name":"app"}]}}}}`, digestOld)
+	if action.Patch != want {
+		t.Errorf("Patch = %s, want %s", action.Patch, want)
+	}
+}
+
+func TestManifestDigest(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/v2/team/app/manifests/v1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digestNew)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	registry := strings.TrimPrefix(server.URL, "https://")
+	ref := types.ImageReference{Registry: registry, Repository: "team/app", Tag: "v1"}
+	// The digest ends up in pin patches: an unverified certificate is refused by default
+	if digest, err := analyzer.ManifestDigest(context.Background(), ref, false); err == nil {
+		t.Errorf("ManifestDigest with a self-signed certificate = %q, want a verification error", digest)
+	}
+	digest, err := analyzer.ManifestDigest(context.Background(), ref, true)
+	if err != nil || digest != digestNew {
+		t.Errorf("ManifestDigest = %q, %v, want %s", digest, err, digestNew)
+	}
+	if _, err := analyzer.ManifestDigest(context.Background(), types.ImageReference{Registry: registry, Repository: "team/app", Tag: "v9"}, true); err == nil {
+		t.Error("ManifestDigest for a missing tag: want an error")
+	}
+}
@@ -0,0 +1,140 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEventNormalization(t *testing.T) {
+	first := time.Date(2025, 12, 18, 10, 0, 0, 0, time.UTC)
+	last := first.Add(20 * time.Minute)
+
+	tests := []struct {
+		name      string
+		event     corev1.Event
+		wantFirst time.Time
+		wantLast  time.Time
+		wantCount int
+	}{
+		{
+			name: "Legacy event with timestamps and count",
+			event: corev1.Event{
+				FirstTimestamp: metav1.NewTime(first),
+				LastTimestamp:  metav1.NewTime(last),
+				Count:          7,
+			},
+			wantFirst: first,
+			wantLast:  last,
+			wantCount: 7,
+		},
+		{
+			name: "Series event with only EventTime",
+			event: corev1.Event{
+				EventTime: metav1.NewMicroTime(first),
+				Series: &corev1.EventSeries{
+					Count:            12,
+					LastObservedTime: metav1.NewMicroTime(last),
+				},
+			},
+			wantFirst: first,
+			wantLast:  last,
+			wantCount: 12,
+		},
+		{
+			name: "Single event with only EventTime",
+			event: corev1.Event{
+				EventTime: metav1.NewMicroTime(first),
+			},
+			wantFirst: first,
+			wantLast:  first,
+			wantCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := k8s.EventFirstSeen(&tt.event); !got.Equal(tt.wantFirst) {
+				t.Errorf("EventFirstSeen() = %v, want %v", got, tt.wantFirst)
+			}
+			if got := k8s.EventLastSeen(&tt.event); !got.Equal(tt.wantLast) {
+				t.Errorf("EventLastSeen() = %v, want %v", got, tt.wantLast)
+			}
+			if got := k8s.EventCount(&tt.event); got != tt.wantCount {
+				t.Errorf("EventCount() = %d, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestGetPodEvents_EventsV1Series(t *testing.T) {
+	first := time.Now().Add(-30 * time.Minute).Truncate(time.Microsecond)
+	last := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+
+	clientset := fake.NewSimpleClientset(
+		&eventsv1.Event{
+			ObjectMeta: metav1.ObjectMeta{Name: "web.2", Namespace: "default"},
+			Regarding:  corev1.ObjectReference{Kind: "Pod", Name: "web", Namespace: "default"},
+			Reason:     "Failed",
+			Note:       "Failed to pull image: manifest unknown",
+			EventTime:  metav1.NewMicroTime(first),
+			Series: &eventsv1.EventSeries{
+				Count:            9,
+				LastObservedTime: metav1.NewMicroTime(last),
+			},
+		},
+		&eventsv1.Event{
+			ObjectMeta: metav1.ObjectMeta{Name: "web.1", Namespace: "default"},
+			Regarding:  corev1.ObjectReference{Kind: "Pod", Name: "web", Namespace: "default"},
+			Reason:     "Pulling",
+			Note:       "Pulling image",
+			EventTime:  metav1.NewMicroTime(first.Add(-time.Minute)),
+		},
+	)
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "events.k8s.io/v1",
+		APIResources: []metav1.APIResource{{Name: "events", Namespaced: true, Kind: "Event"}},
+	}}
+
+	client := &k8s.Client{Clientset: clientset}
+	eventList, err := client.GetPodEvents(context.Background(), "default", "web")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(eventList.Items) != 2 {
+		t.Fatalf("Got %d events, want 2", len(eventList.Items))
+	}
+
+	// Sorted oldest first using EventTime
+	if eventList.Items[0].Reason != "Pulling" {
+		t.Errorf("First event reason = %s, want Pulling", eventList.Items[0].Reason)
+	}
+	if eventList.Items[1].Message != "Failed to pull image: manifest unknown" {
+		t.Errorf("Note was not converted to Message, got %q", eventList.Items[1].Message)
+	}
+
+	failures := k8s.FilterImagePullEvents(eventList.Items)
+	analysis := analyzer.ParseEventsFromK8s(failures)
+	if analysis.FailureCount != 9 {
+		t.Errorf("FailureCount = %d, want 9 (from series)", analysis.FailureCount)
+	}
+	if !analysis.FirstFailureTime.Equal(first) || !analysis.LastFailureTime.Equal(last) {
+		t.Errorf("Failure window = %v - %v, want %v - %v",
+			analysis.FirstFailureTime, analysis.LastFailureTime, first, last)
+	}
+	if analysis.IsTransient {
+		t.Error("Expected persistent failure for 9 failures over 29 minutes")
+	}
+
+	summaries := k8s.ConvertToEventSummary(failures, true)
+	if summaries[0].FirstSeen.IsZero() || summaries[0].Count != 9 {
+		t.Errorf("EventSummary = %+v, want non-zero FirstSeen and Count 9", summaries[0])
+	}
+}
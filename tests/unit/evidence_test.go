@@ -0,0 +1,140 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCollectEvidence_ContainerStatusMessages(t *testing.T) {
+	pod := imagePullPod("default", "web")
+	pod.Status.ContainerStatuses[0].State.Waiting.Message = `Back-off pulling image "registry.example.com/app:v1": manifest unknown`
+	pod.Status.ContainerStatuses[0].LastTerminationState = corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{
+			Reason:  "ErrImagePull",
+			Message: "rpc error: code = NotFound desc = failed to pull and unpack image: not found",
+		},
+	}
+
+	events := []types.EventSummary{
+		{Reason: "Failed", Message: "Failed to pull image: manifest unknown"},
+	}
+
+	evidence := analyzer.CollectEvidence(events, &pod)
+	if len(evidence) != 3 {
+		t.Fatalf("Got %d evidence items, want 3", len(evidence))
+	}
+
+	wantSources := []types.EvidenceSource{
+		types.EvidenceSourceEvent,
+		types.EvidenceSourceContainerStatus,
+		types.EvidenceSourceLastTerminationState,
+	}
+	for i, want := range wantSources {
+		if evidence[i].Source != want {
+			t.Errorf("evidence[%d].Source = %s, want %s", i, evidence[i].Source, want)
+		}
+	}
+	if evidence[1].Container != "app" {
+		t.Errorf("evidence[1].Container = %s, want app", evidence[1].Container)
+	}
+}
+
+func TestDetectRootCauseFromEvidence_ExpiredEvents(t *testing.T) {
+	pod := imagePullPod("default", "web")
+	pod.Status.ContainerStatuses[0].State.Waiting.Message =
+		`failed to pull image "registry.example.com/app:v1": unauthorized: authentication required`
+
+	// No events: the apiserver has already expired them
+	evidence := analyzer.CollectEvidence(nil, &pod)
+	cause, supporting := analyzer.DetectRootCauseFromEvidence(evidence, analyzer.ParseEvents(nil))
+
+	if cause != types.RootCauseAuthFailure {
+		t.Errorf("Root cause = %s, want %s", cause, types.RootCauseAuthFailure)
+	}
+	if len(supporting) != 1 || supporting[0].Source != types.EvidenceSourceContainerStatus {
+		t.Errorf("Supporting evidence = %+v, want one container_status item", supporting)
+	}
+}
+
+func TestDetectRootCauseFromEvidence_WaitingReasons(t *testing.T) {
+	tests := []struct {
+		reason  string
+		message string
+		want    types.RootCause
+	}{
+		{"ErrImageNeverPull", `Container image "app:v1" is not present with pull policy of Never`, types.RootCauseImageNeverPull},
+		{"InvalidImageName", `Failed to apply default image tag "App:V1": couldn't parse image reference`, types.RootCauseInvalidImageName},
+		{"RegistryUnavailable", "", types.RootCauseRegistryUnavailable},
+		{"SignatureValidationFailed", "Source image rejected: A signature was required, but no signature exists", types.RootCauseSignatureValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			pod := imagePullPod("default", "web")
+			pod.Status.ContainerStatuses[0].State.Waiting.Reason = tt.reason
+			pod.Status.ContainerStatuses[0].State.Waiting.Message = tt.message
+
+			if affected := k8s.GetAffectedContainers(&pod); len(affected) != 1 {
+				t.Errorf("Affected containers = %v, want [app]", affected)
+			}
+
+			evidence := analyzer.CollectEvidence(nil, &pod)
+			cause, supporting := analyzer.DetectRootCauseFromEvidence(evidence, analyzer.ParseEvents(nil))
+			if cause != tt.want {
+				t.Errorf("Root cause = %s, want %s", cause, tt.want)
+			}
+			if len(supporting) != 1 || supporting[0].Reason != tt.reason {
+				t.Errorf("Supporting evidence = %+v, want one %s item", supporting, tt.reason)
+			}
+		})
+	}
+}
+
+func TestAnalyzePodObject_NotTransientWhenEventsExpired(t *testing.T) {
+	pod := imagePullPod("default", "web")
+	started := metav1.NewTime(time.Now().Add(-3 * time.Hour))
+	pod.Status.StartTime = &started
+	pod.Status.ContainerStatuses[0].State.Waiting.Message = "rpc error: code = Unknown desc = something odd happened"
+
+	// A single recent event survives; on its own it looks transient
+	clientset := fake.NewSimpleClientset(&corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "web.1", Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "web", Namespace: "default"},
+		Reason:         "BackOff",
+		Message:        "Back-off pulling image",
+		Count:          1,
+		FirstTimestamp: metav1.NewTime(time.Now().Add(-time.Minute)),
+		LastTimestamp:  metav1.NewTime(time.Now().Add(-time.Minute)),
+	})
+
+	logger, err := output.NewAuditLogger(false)
+	if err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+	az := analyzer.NewAnalyzer(&k8s.Client{Clientset: clientset}, logger, 5*time.Second)
+
+	report, err := az.AnalyzePodObject(context.Background(), &pod)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Findings) != 1 {
+		t.Fatalf("Findings = %d, want 1", len(report.Findings))
+	}
+
+	finding := report.Findings[0]
+	if finding.IsTransient {
+		t.Error("Expected persistent failure for a pod waiting for 3h, got transient")
+	}
+	if finding.RootCause == types.RootCauseTransient {
+		t.Errorf("Root cause = %s, want anything but TRANSIENT_FAILURE", finding.RootCause)
+	}
+}
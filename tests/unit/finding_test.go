@@ -1,10 +1,15 @@
 package unit
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
 	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestParseImageReference(t *testing.T) {
@@ -74,28 +79,28 @@ func TestParseImageReference(t *testing.T) {
 		{
 			name:          "Image with digest",
 			containerName: "app",
-			imageRef:      "nginx@sha256:abc123def456",
+			imageRef:      "nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
 			expected: &types.ImageReference{
 				ContainerName: "app",
-				FullReference: "nginx@sha256:abc123def456",
+				FullReference: "nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
 				Registry:      "docker.io",
 				Repository:    "library/nginx",
 				Tag:           "",
-				Digest:        "sha256:abc123def456",
+				Digest:        "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
 				IsDigest:      true,
 			},
 		},
 		{
 			name:          "Full path with digest",
 			containerName: "app",
-			imageRef:      "gcr.io/my-project/my-app@sha256:xyz789",
+			imageRef:      "gcr.io/my-project/my-app@sha256:4c1e997385b8fb4ad4d1d3c7e5af7ff3f882e0a3d8c3ce0a6a6e8d3b8a0c2f5e",
 			expected: &types.ImageReference{
 				ContainerName: "app",
-				FullReference: "gcr.io/my-project/my-app@sha256:xyz789",
+				FullReference: "gcr.io/my-project/my-app@sha256:4c1e997385b8fb4ad4d1d3c7e5af7ff3f882e0a3d8c3ce0a6a6e8d3b8a0c2f5e",
 				Registry:      "gcr.io",
 				Repository:    "my-project/my-app",
 				Tag:           "",
-				Digest:        "sha256:xyz789",
+				Digest:        "sha256:4c1e997385b8fb4ad4d1d3c7e5af7ff3f882e0a3d8c3ce0a6a6e8d3b8a0c2f5e",
 				IsDigest:      true,
 			},
 		},
@@ -127,6 +132,81 @@ func TestParseImageReference(t *testing.T) {
 				IsDigest:      false,
 			},
 		},
+		{
+			name:          "Localhost registry with port",
+			containerName: "app",
+			imageRef:      "localhost:5000/app",
+			expected: &types.ImageReference{
+				ContainerName: "app",
+				FullReference: "localhost:5000/app",
+				Registry:      "localhost:5000",
+				Repository:    "app",
+				Tag:           "latest",
+			},
+		},
+		{
+			name:          "Localhost registry without port",
+			containerName: "app",
+			imageRef:      "localhost/app:dev",
+			expected: &types.ImageReference{
+				ContainerName: "app",
+				FullReference: "localhost/app:dev",
+				Registry:      "localhost",
+				Repository:    "app",
+				Tag:           "dev",
+			},
+		},
+		{
+			name:          "Registry with port and digest",
+			containerName: "app",
+			imageRef:      "registry:5000/ns/app@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
+			expected: &types.ImageReference{
+				ContainerName: "app",
+				FullReference: "registry:5000/ns/app@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
+				Registry:      "registry:5000",
+				Repository:    "ns/app",
+				Digest:        "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
+				IsDigest:      true,
+			},
+		},
+		{
+			name:          "Tag and digest",
+			containerName: "app",
+			imageRef:      "registry.example.com:5000/team/app:v1.2@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
+			expected: &types.ImageReference{
+				ContainerName: "app",
+				FullReference: "registry.example.com:5000/team/app:v1.2@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
+				Registry:      "registry.example.com:5000",
+				Repository:    "team/app",
+				Tag:           "v1.2",
+				Digest:        "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
+				IsDigest:      true,
+			},
+		},
+		{
+			name:          "Legacy Docker Hub domain",
+			containerName: "app",
+			imageRef:      "index.docker.io/nginx:1.25",
+			expected: &types.ImageReference{
+				ContainerName: "app",
+				FullReference: "index.docker.io/nginx:1.25",
+				Registry:      "docker.io",
+				Repository:    "library/nginx",
+				Tag:           "1.25",
+			},
+		},
+		{
+			name:          "Separators in repository",
+			containerName: "app",
+			imageRef:      "ghcr.io/my_org/my__app.v2--beta:1.0_rc-1",
+			expected: &types.ImageReference{
+				ContainerName: "app",
+				FullReference: "ghcr.io/my_org/my__app.v2--beta:1.0_rc-1",
+				Registry:      "ghcr.io",
+				Repository:    "my_org/my__app.v2--beta",
+				Tag:           "1.0_rc-1",
+			},
+		},
 		{
 			name:          "Empty image reference",
 			containerName: "app",
@@ -176,6 +256,66 @@ func TestParseImageReference(t *testing.T) {
 	}
 }
 
+func TestParseImageReference_Invalid(t *testing.T) {
+	tests := []struct {
+		imageRef  string
+		component string
+		position  int
+		reason    string
+	}{
+		{"Nginx:1.25", types.ComponentRepository, 0, "must be lowercase"},
+		{"registry.example.com/Team/app", types.ComponentRepository, 21, "must be lowercase"},
+		{"nginx:1.25 ", types.ComponentReference, 10, "whitespace"},
+		{"https://registry.example.com/app", types.ComponentReference, 5, "URL scheme"},
+		{"nginx:", types.ComponentTag, 5, "is empty"},
+		{"nginx:-rc1", types.ComponentTag, 6, "must start with"},
+		{"nginx:1.25+build", types.ComponentTag, 10, "may only contain"},
+		{"nginx@sha256:abc123", types.ComponentDigest, -1, "64 hex characters"},
+		{"nginx@sha256:" + strings.Repeat("g", 64), types.ComponentDigest, 13, "hexadecimal"},
+		{"team//app", types.ComponentRepository, 5, "empty path component"},
+		{"team/app-", types.ComponentRepository, 8, "separators"},
+		{"team/my...app", types.ComponentRepository, 7, "separators"},
+		{"registry.example.com:port/app", types.ComponentRegistry, 21, "port must be numeric"},
+		{"-registry.example.com/app", types.ComponentRegistry, 0, "must not start or end with '-'"},
+		{strings.Repeat("a", 64), types.ComponentRepository, -1, "hexadecimal string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.imageRef, func(t *testing.T) {
+			_, err := types.ParseImageReference("app", tt.imageRef)
+			var refErr *types.ImageReferenceError
+			if !errors.As(err, &refErr) {
+				t.Fatalf("Error = %v, want *ImageReferenceError", err)
+			}
+			if refErr.Component != tt.component || refErr.Position != tt.position {
+				t.Errorf("Component/Position = %s/%d, want %s/%d (%v)", refErr.Component, refErr.Position, tt.component, tt.position, err)
+			}
+			if !strings.Contains(refErr.Reason, tt.reason) {
+				t.Errorf("Reason = %q, want it to contain %q", refErr.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestGetContainerImages_KeepsInvalidReference(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "app", Image: "registry.example.com/Team/app:v1"}},
+	}}
+
+	refs := k8s.GetContainerImages(pod)
+	if len(refs) != 1 || refs[0].FullReference != "registry.example.com/Team/app:v1" {
+		t.Fatalf("GetContainerImages = %+v, want the invalid reference kept", refs)
+	}
+	if !strings.Contains(refs[0].ParseError, "must be lowercase (character 'T' at position 21)") {
+		t.Errorf("ParseError = %q", refs[0].ParseError)
+	}
+
+	steps := analyzer.GenerateRemediationSteps(types.RootCauseInvalidImageName, &refs[0])
+	if len(steps) < 2 || !strings.Contains(steps[1], "repository \"Team/app\" must be lowercase") {
+		t.Errorf("RemediationSteps = %v, want the parse error second", steps)
+	}
+}
+
 func TestDiagnosticFinding_Validate(t *testing.T) {
 	now := time.Now()
 
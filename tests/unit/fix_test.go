@@ -0,0 +1,239 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newFixAnalyzer returns an analyzer backed by a fake cluster holding objects
+func newFixAnalyzer(t *testing.T, objects ...runtime.Object) (*analyzer.Analyzer, *output.AuditLogger, *fake.Clientset) {
+	t.Helper()
+	clientset := fake.NewSimpleClientset(objects...)
+	logger, err := output.NewAuditLogger(false)
+	if err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+	return analyzer.NewAnalyzer(&k8s.Client{Clientset: clientset}, logger, 5*time.Second), logger, clientset
+}
+
+func TestPlanRemediation_AddImagePullSecretToServiceAccount(t *testing.T) {
+	pod := imagePullPod("default", "web")
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"}}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "regcred", Namespace: "default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(`{"auths":{"https://registry.example.com/v2/":{"auth":"dXNlcjpwYXNz"}}}`),
+		},
+	}
+	az, logger, clientset := newFixAnalyzer(t, &pod, sa, secret)
+
+	findings := []types.DiagnosticFinding{{
+		RootCause:          types.RootCauseAuthFailure,
+		AffectedContainers: []string{"app"},
+		ImageReferences:    []types.ImageReference{{ContainerName: "app", Registry: "registry.example.com", Repository: "app"}},
+	}}
+	actions, err := az.PlanRemediation(context.Background(), &pod, findings, analyzer.FixOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(actions) != 1 || len(findings[0].Actions) != 1 {
+		t.Fatalf("Actions = %+v, want one AddImagePullSecret", actions)
+	}
+
+	action := actions[0]
+	if action.Kind != types.ActionAddImagePullSecret || action.Target.Kind != "ServiceAccount" || action.Target.Name != "default" {
+		t.Errorf("Action = %s on %+v", action.Kind, action.Target)
+	}
+	if action.PatchType != types.PatchTypeJSON || action.Patch != `[{"op":"add","path":"/imagePullSecrets","value":[{"name":"regcred"}]}]` {
+		t.Errorf("Patch (%s) = %s", action.PatchType, action.Patch)
+	}
+
+	result := az.ExecuteAction(context.Background(), action, true)
+	if result.Error != "" || !result.Validated || !result.Applied {
+		t.Fatalf("Result = %+v", result)
+	}
+	if !strings.Contains(result.Diff, "+imagePullSecrets:") || !strings.Contains(result.Diff, "+    - name: regcred") {
+		t.Errorf("Diff does not show the added secret:\n%s", result.Diff)
+	}
+
+	updated, err := clientset.CoreV1().ServiceAccounts("default").Get(context.Background(), "default", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(updated.ImagePullSecrets) != 1 || updated.ImagePullSecrets[0].Name != "regcred" {
+		t.Errorf("ImagePullSecrets = %v, want [regcred]", updated.ImagePullSecrets)
+	}
+
+	// The dry-run and the write are both audited
+	var operations []string
+	for _, entry := range logger.GetAuditEntries() {
+		if entry.ResourceType == "serviceaccounts" {
+			operations = append(operations, entry.Operation)
+		}
+	}
+	for _, want := range []string{"patch (dry-run)", "patch"} {
+		if !containsString(operations, want) {
+			t.Errorf("Audit operations on serviceaccounts = %v, missing %q", operations, want)
+		}
+	}
+}
+
+func TestPlanRemediation_PatchesDeploymentTemplate(t *testing.T) {
+	controller := true
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "registry.example.com/app:v1", ImagePullPolicy: corev1.PullNever}},
+			}},
+		},
+	}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name: "web-5d9c", Namespace: "default",
+		OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", Controller: &controller}},
+	}}
+	pod := imagePullPod("default", "web-5d9c-abcde")
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d9c", Controller: &controller}}
+	az, _, _ := newFixAnalyzer(t, deploy, rs, &pod)
+
+	findings := []types.DiagnosticFinding{{RootCause: types.RootCauseImageNeverPull, AffectedContainers: []string{"app"}}}
+	actions, err := az.PlanRemediation(context.Background(), &pod, findings, analyzer.FixOptions{
+		Images: map[string]string{"app": "registry.example.com/app:v2"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("Actions = %+v, want SetImage and SetImagePullPolicy", actions)
+	}
+
+	want := map[types.RemediationActionKind]string{
+		types.ActionSetImage:           `{"spec":{"template":{"spec":{"containers":[{"image":"registry.example.com/app:v2","name":"app"}]}}}}`,
+		types.ActionSetImagePullPolicy: `{"spec":{"template":{"spec":{"containers":[{"imagePullPolicy":"IfNotPresent","name":"app"}]}}}}`,
+	}
+	for _, action := range actions {
+		if action.Target != (types.ObjectReference{Kind: "Deployment", Namespace: "default", Name: "web"}) {
+			t.Errorf("%s target = %+v, want Deployment default/web", action.Kind, action.Target)
+		}
+		if action.PatchType != types.PatchTypeStrategicMerge || action.Patch != want[action.Kind] {
+			t.Errorf("%s patch (%s) = %s", action.Kind, action.PatchType, action.Patch)
+		}
+	}
+
+	result := az.ExecuteAction(context.Background(), actions[1], false)
+	if result.Error != "" || !result.Validated || result.Applied {
+		t.Fatalf("Result = %+v, want validated and not applied", result)
+	}
+	if !strings.Contains(result.Diff, "-                  imagePullPolicy: Never") ||
+		!strings.Contains(result.Diff, "+                  imagePullPolicy: IfNotPresent") {
+		t.Errorf("Diff does not show the policy change:\n%s", result.Diff)
+	}
+}
+
+func TestPlanRemediation_NormalizesInvalidImageOnBarePod(t *testing.T) {
+	pod := imagePullPod("default", "web")
+	pod.Spec.Containers[0].Image = " Registry.Example.com/Team/App:v1"
+	az, _, _ := newFixAnalyzer(t, &pod)
+
+	findings := []types.DiagnosticFinding{{RootCause: types.RootCauseInvalidImageName, AffectedContainers: []string{"app"}}}
+	actions, err := az.PlanRemediation(context.Background(), &pod, findings, analyzer.FixOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(actions) != 1 {
+		t.Fatalf("Actions = %+v, want one SetImage", actions)
+	}
+	if actions[0].Target.Kind != "Pod" || actions[0].Patch != `{"spec":{"containers":[{"image":"registry.example.com/team/app:v1","name":"app"}]}}` {
+		t.Errorf("Action = %+v", actions[0])
+	}
+
+	// imagePullPolicy cannot change on a running pod: no action for a bare pod
+	findings = []types.DiagnosticFinding{{RootCause: types.RootCauseImageNeverPull, AffectedContainers: []string{"app"}}}
+	if actions, _ := az.PlanRemediation(context.Background(), &pod, findings, analyzer.FixOptions{}); len(actions) != 0 {
+		t.Errorf("Actions = %+v, want none for a bare pod", actions)
+	}
+
+	if _, err := az.PlanRemediation(context.Background(), &pod, nil, analyzer.FixOptions{Images: map[string]string{"missing": "nginx"}}); err == nil {
+		t.Error("Expected an error for --set-image on an unknown container")
+	}
+}
+
+func TestPlanRemediation_SetImageOrderIsStable(t *testing.T) {
+	pod := imagePullPod("default", "web")
+	names := []string{"app", "cache", "proxy", "sidecar", "worker"}
+	images := make(map[string]string, len(names))
+	pod.Spec.Containers = nil
+	for _, name := range names {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: name, Image: "registry.example.com/" + name + ":v1"})
+		images[name] = "registry.example.com/" + name + ":v2"
+	}
+	az, _, _ := newFixAnalyzer(t, &pod)
+
+	// Map iteration order changes between runs; the plan must not
+	for run := 0; run < 10; run++ {
+		actions, err := az.PlanRemediation(context.Background(), &pod, nil, analyzer.FixOptions{Images: images})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(actions) != len(names) {
+			t.Fatalf("Actions = %d, want %d", len(actions), len(names))
+		}
+		for i, action := range actions {
+			if !strings.Contains(action.Patch, `"name":"`+names[i]+`"`) {
+				t.Fatalf("Run %d action %d = %s, want container %s", run, i, action.Patch, names[i])
+			}
+		}
+	}
+}
+
+func TestGetPod_NotFoundKeepsAPIError(t *testing.T) {
+	client := &k8s.Client{Clientset: fake.NewSimpleClientset()}
+	_, err := client.GetPod(context.Background(), "default", "missing")
+	if !k8serrors.IsNotFound(err) {
+		t.Errorf("Error = %v, want one k8serrors.IsNotFound recognizes", err)
+	}
+	if err == nil || err.Error() != "pod 'missing' not found in namespace 'default'" {
+		t.Errorf("Message = %v, want the pod and namespace", err)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	after := "a\nb\nc\nd\nE\nf\ng\nh\ni\nj\nk\n"
+
+	want := `--- old
++++ new
+@@ -2,9 +2,10 @@
+ b
+ c
+ d
+-e
++E
+ f
+ g
+ h
+ i
+ j
++k
+`
+	if got := output.UnifiedDiff(before, after, "old", "new"); got != want {
+		t.Errorf("UnifiedDiff =\n%s\nwant\n%s", got, want)
+	}
+	if got := output.UnifiedDiff(before, before, "old", "new"); got != "" {
+		t.Errorf("UnifiedDiff of equal texts = %q, want empty", got)
+	}
+}
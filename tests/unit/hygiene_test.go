@@ -0,0 +1,126 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// auditedPod returns a pod in a namespace running one container
+func auditedPod(namespace, name, image string, policy corev1.PullPolicy) corev1.Pod {
+	pod := scheduledPod(name, "node-a", false)
+	pod.Namespace = namespace
+	pod.Spec.Containers[0].Image = image
+	pod.Spec.Containers[0].ImagePullPolicy = policy
+	return pod
+}
+
+// ruleFindings indexes findings by workload name and rule
+func ruleFindings(findings []types.DiagnosticFinding) map[string]types.DiagnosticFinding {
+	byRule := make(map[string]types.DiagnosticFinding)
+	for _, f := range findings {
+		name := f.Subject[strings.Index(f.Subject, "/")+1:]
+		byRule[name+"/"+string(f.RootCause)] = f
+	}
+	return byRule
+}
+
+func TestAuditImages_Policy(t *testing.T) {
+	policy, err := analyzer.ParseImagePolicy([]byte(`
+imagePolicy:
+  allowedRegistries: [registry.example.com, index.docker.io/library]
+  digestNamespaces: ["prod-*"]
+  excludeNamespaces: [kube-system]
+  severities:
+    LATEST_TAG: LOW
+`))
+	if err != nil {
+		t.Fatalf("ParseImagePolicy: %v", err)
+	}
+
+	pods := []corev1.Pod{
+		auditedPod("default", "latest", "registry.example.com/app", corev1.PullAlways),
+		auditedPod("default", "foreign", "quay.io/team/app:v1", corev1.PullIfNotPresent),
+		auditedPod("prod-eu", "tagged", "registry.example.com/app:v1", corev1.PullIfNotPresent),
+		auditedPod("default", "hub", "nginx:1.25", corev1.PullIfNotPresent),
+		auditedPod("default", "pinned", "registry.example.com/app@"+digestOld, corev1.PullAlways),
+		auditedPod("kube-system", "system", "quay.io/system/app", corev1.PullNever),
+	}
+	az, _, _ := newFixAnalyzer(t)
+	byRule := ruleFindings(az.AuditImages(pods, policy))
+
+	want := map[string]types.Severity{
+		"latest/LATEST_TAG":                 types.SeverityLow,
+		"foreign/DISALLOWED_REGISTRY":       types.SeverityHigh,
+		"tagged/DIGEST_REQUIRED":            types.SeverityHigh,
+		"hub/DOCKERHUB_WITHOUT_PULL_SECRET": types.SeverityMedium,
+		"pinned/PULL_POLICY_MISMATCH":       types.SeverityMedium,
+	}
+	for key, severity := range want {
+		finding, ok := byRule[key]
+		if !ok {
+			t.Errorf("Missing finding %s", key)
+			continue
+		}
+		if finding.Severity != severity {
+			t.Errorf("%s severity = %s, want %s", key, finding.Severity, severity)
+		}
+	}
+	if len(byRule) != len(want) {
+		t.Errorf("Findings = %v, want only %d", byRule, len(want))
+	}
+	if !strings.Contains(byRule["latest/LATEST_TAG"].Summary, "has no tag") {
+		t.Errorf("Summary = %q, want the tagless wording", byRule["latest/LATEST_TAG"].Summary)
+	}
+	for key, finding := range byRule {
+		if err := finding.Validate(); err != nil {
+			t.Errorf("%s: Validate: %v", key, err)
+		}
+	}
+}
+
+func TestAuditImages_GroupsReplicasAndMirrors(t *testing.T) {
+	pods := []corev1.Pod{
+		runningReplica("web-7c4d-a", "nginx:latest", digestOld),
+		runningReplica("web-7c4d-b", "nginx:latest", digestOld),
+	}
+	for i := range pods {
+		pods[i].Spec.Containers[0].ImagePullPolicy = corev1.PullIfNotPresent
+	}
+	az, _, _ := newFixAnalyzer(t)
+
+	findings := az.AuditImages(pods, nil)
+	byRule := ruleFindings(findings)
+	for _, key := range []string{"web/LATEST_TAG", "web/PULL_POLICY_MISMATCH", "web/DOCKERHUB_WITHOUT_PULL_SECRET"} {
+		finding := byRule[key]
+		if finding.Subject != "Deployment/web" || finding.Scope != types.FindingScopeWorkload || !strings.Contains(finding.Details, "2 pods") {
+			t.Errorf("%s = %+v, want one Deployment finding for both replicas", key, finding)
+		}
+	}
+
+	// Docker Hub images pulled through a mirror need no pull secret
+	mirrors, err := analyzer.ParseMirrors([]byte("mirrors:\n  - registry: docker.io\n    endpoints: [https://mirror.corp.example.com]\n"))
+	if err != nil {
+		t.Fatalf("ParseMirrors: %v", err)
+	}
+	az.SetMirrors(mirrors)
+	if _, ok := ruleFindings(az.AuditImages(pods, nil))["web/DOCKERHUB_WITHOUT_PULL_SECRET"]; ok {
+		t.Error("DOCKERHUB_WITHOUT_PULL_SECRET reported for images pulled through a mirror")
+	}
+}
+
+func TestParseImagePolicy_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown rule":     "imagePolicy:\n  severities:\n    NO_SUCH_RULE: HIGH\n",
+		"invalid severity": "imagePolicy:\n  severities:\n    LATEST_TAG: CRITICAL\n",
+		"invalid pattern":  "imagePolicy:\n  digestNamespaces: [\"prod-[\"]\n",
+	}
+	for name, data := range tests {
+		if _, err := analyzer.ParseImagePolicy([]byte(data)); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+}
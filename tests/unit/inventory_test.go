@@ -0,0 +1,131 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// debugPod returns a failing pod that kubectl debug attached a busybox container to
+func debugPod() corev1.Pod {
+	pod := imagePullPod("default", "web")
+	pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger", Image: "busybox:1.36"},
+	}}
+	return pod
+}
+
+func TestGetContainerImages_EphemeralContainers(t *testing.T) {
+	pod := debugPod()
+
+	// Debug containers are not workload images: analyze, fix and policies skip them
+	if refs := k8s.GetContainerImages(&pod); len(refs) != 1 || refs[0].ContainerName != "app" {
+		t.Errorf("GetContainerImages = %+v, want only the app container", refs)
+	}
+	refs := k8s.GetEphemeralContainerImages(&pod)
+	if len(refs) != 1 || refs[0].ContainerName != "debugger" || refs[0].Repository != "library/busybox" {
+		t.Errorf("GetEphemeralContainerImages = %+v, want the debugger's image", refs)
+	}
+}
+
+func TestAnalyzePodObject_IgnoresEphemeralContainers(t *testing.T) {
+	pod := debugPod()
+	az, _, _ := newFixAnalyzer(t)
+
+	report, err := az.AnalyzePodObject(context.Background(), &pod)
+	if err != nil {
+		t.Fatalf("AnalyzePodObject: %v", err)
+	}
+	if report.Summary.TotalContainers != 1 {
+		t.Errorf("TotalContainers = %d, want 1 without the debug container", report.Summary.TotalContainers)
+	}
+	for _, finding := range report.Findings {
+		for _, ref := range finding.ImageReferences {
+			if ref.ContainerName == "debugger" {
+				t.Errorf("Finding %s references the debug container", string(finding.RootCause))
+			}
+		}
+	}
+
+	policies, err := analyzer.ParseCELPolicies([]byte(`
+celPolicies:
+  - id: CEL_NO_BUSYBOX
+    description: Busybox is not allowed
+    expression: image.repository == 'library/busybox'
+    severity: low
+`))
+	if err != nil {
+		t.Fatalf("ParseCELPolicies: %v", err)
+	}
+	if findings, errs := policies.Evaluate(celInputs(pod)); len(findings) != 0 || len(errs) != 0 {
+		t.Errorf("Policy findings = %+v (%v), want none for the debug container", findings, errs)
+	}
+}
+
+func TestBuildImageInventory(t *testing.T) {
+	web1 := runningReplica("web-7c4d-a", "registry.example.com/app:v1", digestOld)
+	web1.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "regcred"}}
+	web2 := runningReplica("web-7c4d-b", "registry.example.com/app:v1", digestNew)
+	web2.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "regcred"}}
+	web2.Spec.EphemeralContainers = []corev1.EphemeralContainer{{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger", Image: "busybox:1.36"},
+	}}
+	bare := imagePullPod("tools", "runner")
+
+	inventory := analyzer.BuildImageInventory([]corev1.Pod{web1, web2, bare})
+	if len(inventory) != 2 {
+		t.Fatalf("Inventory = %+v, want busybox and app", inventory)
+	}
+	if inventory[0].Repository != "library/busybox" || inventory[0].Pods != 1 {
+		t.Errorf("First entry = %+v, want the ephemeral busybox in 1 pod", inventory[0])
+	}
+
+	app := inventory[1]
+	if app.Registry != "registry.example.com" || app.Repository != "app" || app.Tag != "v1" || app.Pods != 3 {
+		t.Errorf("App = %+v, want registry.example.com/app:v1 in 3 pods", app)
+	}
+	if len(app.ImageIDs) != 2 {
+		t.Errorf("ImageIDs = %v, want both resolved digests", app.ImageIDs)
+	}
+	want := []types.AffectedWorkload{
+		{Namespace: "default", Kind: "Deployment", Name: "web", Pods: 2},
+		{Namespace: "tools", Kind: "Pod", Name: "runner", Pods: 1},
+	}
+	if len(app.Workloads) != 2 || app.Workloads[0] != want[0] || app.Workloads[1] != want[1] {
+		t.Errorf("Workloads = %+v, want %+v", app.Workloads, want)
+	}
+	if len(app.PullSecrets) != 1 || app.PullSecrets[0] != "default/regcred" {
+		t.Errorf("PullSecrets = %v, want default/regcred", app.PullSecrets)
+	}
+}
+
+func TestFormatImageInventory_CSV(t *testing.T) {
+	inventory := analyzer.BuildImageInventory([]corev1.Pod{runningReplica("web-7c4d-a", "registry.example.com/app:v1", digestOld)})
+
+	var buf bytes.Buffer
+	if err := output.FormatImageInventory(inventory, output.FormatTypeCSV, &buf); err != nil {
+		t.Fatalf("FormatImageInventory: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "IMAGE,REGISTRY,REPOSITORY,TAG/DIGEST,IMAGE ID") {
+		t.Fatalf("CSV = %q", buf.String())
+	}
+	want := "registry.example.com/app:v1,registry.example.com,app,v1,registry.example.com/app@" + digestOld + ",Deployment/default/web(1),1,"
+	if lines[1] != want {
+		t.Errorf("Record = %q, want %q", lines[1], want)
+	}
+
+	if _, err := output.ParseInventoryFormat("csv"); err != nil {
+		t.Errorf("ParseInventoryFormat(csv): %v", err)
+	}
+	if _, err := output.ParseFormat("csv"); err == nil {
+		t.Error("ParseFormat(csv): want an error outside tabular listings")
+	}
+}
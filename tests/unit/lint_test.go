@@ -0,0 +1,245 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// lintManifest parses a manifest read from stdin into namespace default
+func lintManifest(t *testing.T, manifest string) *k8s.Manifests {
+	t.Helper()
+	manifests, err := k8s.ParseManifests(strings.NewReader(manifest), "stdin", "default")
+	if err != nil {
+		t.Fatalf("ParseManifests: %v", err)
+	}
+	return manifests
+}
+
+// lintCauses indexes findings by workload name and root cause
+func lintCauses(findings []types.LintFinding) map[string]types.LintFinding {
+	byCause := make(map[string]types.LintFinding)
+	for _, f := range findings {
+		byCause[f.Name+"/"+string(f.RootCause)] = f
+	}
+	return byCause
+}
+
+func TestParseManifests(t *testing.T) {
+	manifests := lintManifest(t, `
+# Source: chart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+        - name: app
+          image: registry.example.com/web:v1
+          ports:
+            - containerPort: 8080
+---
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+  namespace: batch
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+            - name: init
+              image: busybox:1.36
+          containers:
+            - name: report
+              image: registry.example.com/report:v2
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Secret
+    metadata:
+      name: regcred
+    type: kubernetes.io/dockerconfigjson
+  - apiVersion: v1
+    kind: ServiceAccount
+    metadata:
+      name: builder
+    imagePullSecrets:
+      - name: regcred
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: settings
+`)
+
+	if len(manifests.Templates) != 2 {
+		t.Fatalf("Templates = %d, want the Deployment and the CronJob", len(manifests.Templates))
+	}
+	web, report := manifests.Templates[0], manifests.Templates[1]
+	if web.Kind != "Deployment" || web.Namespace != "default" || web.Source != "stdin#1" || web.Spec.Containers[0].Image != "registry.example.com/web:v1" {
+		t.Errorf("Deployment = %+v, want web in default from stdin#1", web)
+	}
+	if report.Kind != "CronJob" || report.Namespace != "batch" || report.Source != "stdin#2" || len(report.Spec.InitContainers) != 1 {
+		t.Errorf("CronJob = %+v, want report in batch from stdin#2, empty documents not counted with its init container", report)
+	}
+	if len(manifests.Secrets) != 1 || len(manifests.ServiceAccounts) != 1 || manifests.ServiceAccounts[0].ImagePullSecrets[0].Name != "regcred" {
+		t.Errorf("Secrets = %d, ServiceAccounts = %+v, want the List items", len(manifests.Secrets), manifests.ServiceAccounts)
+	}
+}
+
+func TestParseManifests_Invalid(t *testing.T) {
+	_, err := k8s.ParseManifests(strings.NewReader("kind: Deployment\nmetadata:\n  name: web\nspec: {}\n"), "deploy.yaml", "default")
+	if err == nil || !strings.Contains(err.Error(), "deploy.yaml#1") {
+		t.Errorf("Error = %v, want one naming the document", err)
+	}
+}
+
+func TestLintManifests_PullSecrets(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "regcred", Namespace: "default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{"auth":"dXNlcjpwYXNz"}}}`),
+		},
+	}
+	sa := &corev1.ServiceAccount{
+		ObjectMeta:       metav1.ObjectMeta{Name: "deployer", Namespace: "default"},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "gone"}},
+	}
+	az, _, _ := newFixAnalyzer(t, secret, sa)
+
+	manifests := lintManifest(t, `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: ok
+spec:
+  imagePullSecrets: [{name: regcred}]
+  containers: [{name: app, image: registry.example.com/app:v1}]
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: missing
+spec:
+  serviceAccountName: deployer
+  containers: [{name: app, image: registry.example.com/app:v1}]
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: bundled
+spec:
+  imagePullSecrets: [{name: bundled-cred}]
+  containers: [{name: app, image: "registry.example.com/App:v1"}]
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: bundled-cred
+type: kubernetes.io/dockerconfigjson
+`)
+
+	byCause := lintCauses(az.LintManifests(context.Background(), manifests, analyzer.LintOptions{}))
+	if finding, ok := byCause["missing/AUTHENTICATION_FAILURE"]; !ok {
+		t.Errorf("Findings = %v, want the missing secret of service account deployer", byCause)
+	} else if !strings.Contains(finding.Message, "gone") {
+		t.Errorf("Message = %q, want the secret name", finding.Message)
+	} else if finding.Severity != types.SeverityMedium {
+		// Public images still pull without the secret
+		t.Errorf("Severity = %s, want MEDIUM without a probe", finding.Severity)
+	}
+	if finding, ok := byCause["bundled/INVALID_IMAGE_NAME"]; !ok || finding.Severity != types.SeverityHigh || finding.Container != "app" {
+		t.Errorf("Findings = %v, want an invalid reference for the upper case repository", byCause)
+	}
+	if len(byCause) != 2 {
+		t.Errorf("Findings = %v, want none for pod ok or the bundled secret", byCause)
+	}
+	// Offline, a secret only in the cluster is reported as not checked
+	byCause = lintCauses(az.LintManifests(context.Background(), manifests, analyzer.LintOptions{Offline: true}))
+	if finding, ok := byCause["ok/AUTHENTICATION_FAILURE"]; !ok || finding.Severity != types.SeverityLow ||
+		finding.Message != "pull secret regcred not defined in the manifests; not checked offline" {
+		t.Errorf("Findings = %v, want regcred reported as not checked offline", byCause)
+	}
+	if _, ok := byCause["bundled/AUTHENTICATION_FAILURE"]; ok {
+		t.Errorf("Findings = %v, want none for the secret in the manifests", byCause)
+	}
+}
+
+func TestLintManifests_Probe(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case strings.HasPrefix(r.URL.Path, "/v2/private/"):
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/team/app/manifests/v1":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	pod := func(name, image string) string {
+		return "apiVersion: v1\nkind: Pod\nmetadata:\n  name: " + name + "\nspec:\n  containers: [{name: app, image: \"" + registry + "/" + image + "\"}]\n---\n"
+	}
+	withSecret := func(name, image string) string {
+		return "apiVersion: v1\nkind: Pod\nmetadata:\n  name: " + name + "\nspec:\n  imagePullSecrets: [{name: gone}]\n  containers: [{name: app, image: \"" + registry + "/" + image + "\"}]\n---\n"
+	}
+	manifests := lintManifest(t, pod("found", "team/app:v1")+pod("typo", "team/app:v9")+pod("private", "private/app:v1")+
+		withSecret("public-gone", "team/app:v1")+withSecret("private-gone", "private/app:v1"))
+
+	az, _, _ := newFixAnalyzer(t)
+	az.SetInsecureRegistries([]string{registry})
+	findings := az.LintManifests(context.Background(), manifests, analyzer.LintOptions{Probe: true})
+	byCause := lintCauses(findings)
+	if finding, ok := byCause["typo/IMAGE_NOT_FOUND"]; !ok || finding.Severity != types.SeverityHigh {
+		t.Errorf("Findings = %v, want IMAGE_NOT_FOUND for the v9 tag", byCause)
+	}
+	if finding, ok := byCause["private/AUTHENTICATION_FAILURE"]; !ok || finding.Severity != types.SeverityHigh {
+		t.Errorf("Findings = %v, want AUTHENTICATION_FAILURE for the private repository", byCause)
+	}
+	// A missing secret only blocks the deploy when the registry requires credentials
+	if finding := byCause["public-gone/AUTHENTICATION_FAILURE"]; finding.Severity != types.SeverityMedium {
+		t.Errorf("Missing secret for a public image = %+v, want MEDIUM", finding)
+	}
+	escalated := false
+	for _, finding := range findings {
+		if finding.Name == "private-gone" && strings.Contains(finding.Message, "gone does not exist") {
+			escalated = finding.Severity == types.SeverityHigh && strings.Contains(finding.Message, registry+" requires credentials")
+		}
+	}
+	if !escalated {
+		t.Errorf("Findings = %+v, want the missing secret of private-gone HIGH, naming the registry", findings)
+	}
+	if len(findings) != 5 {
+		t.Errorf("Findings = %+v, want none for the existing tag", findings)
+	}
+
+	// Offline, credentials cannot be ruled out
+	byCause = lintCauses(az.LintManifests(context.Background(), manifests, analyzer.LintOptions{Probe: true, Offline: true}))
+	if finding := byCause["private/AUTHENTICATION_FAILURE"]; finding.Severity != types.SeverityMedium {
+		t.Errorf("Offline severity = %s, want MEDIUM", finding.Severity)
+	}
+}
@@ -0,0 +1,169 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/types"
+)
+
+const corpMirrors = `
+mirrors:
+  - registry: docker.io
+    endpoints: ["mirror.corp.example.com/v2/dockerhub"]
+  - registry: index.docker.io/library
+    endpoints: ["http://cache.corp.example.com:5000", "mirror.corp.example.com/library"]
+  - registry: quay.io
+    endpoints: ["quay-mirror.corp.example.com"]
+    skipUpstream: true
+  - registry: ghcr.io
+    endpoints: ["mirror.corp.example.com/v2cache/ghcr"]
+`
+
+func TestMirrorConfig_Resolve(t *testing.T) {
+	mirrors, err := analyzer.ParseMirrors([]byte(corpMirrors))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		image string
+		want  []types.RegistryEndpoint
+	}{
+		{"nginx:1.25", []types.RegistryEndpoint{
+			{Host: "cache.corp.example.com:5000", Repository: "nginx", PlainHTTP: true, Mirror: true},
+			{Host: "mirror.corp.example.com", Repository: "library/nginx", Mirror: true},
+			{Host: "docker.io", Repository: "library/nginx"},
+		}},
+		{"bitnami/redis:7", []types.RegistryEndpoint{
+			{Host: "mirror.corp.example.com", Repository: "dockerhub/bitnami/redis", Mirror: true},
+			{Host: "docker.io", Repository: "bitnami/redis"},
+		}},
+		{"quay.io/prometheus/node-exporter:v1.7.0", []types.RegistryEndpoint{
+			{Host: "quay-mirror.corp.example.com", Repository: "prometheus/node-exporter", Mirror: true},
+		}},
+		// Only a v2 path segment is the API path
+		{"ghcr.io/org/app:v1", []types.RegistryEndpoint{
+			{Host: "mirror.corp.example.com", Repository: "v2cache/ghcr/org/app", Mirror: true},
+			{Host: "ghcr.io", Repository: "org/app"},
+		}},
+		{"registry.example.com/app:v1", nil},
+		{"quay.io.example.com/app:v1", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			ref, err := types.ParseImageReference("app", tt.image)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got := mirrors.Resolve(*ref)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Resolve = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("Endpoint %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	// No configuration: images are pulled from their registry
+	var none *analyzer.MirrorConfig
+	if got := none.Resolve(types.ImageReference{Registry: "docker.io", Repository: "library/nginx"}); got != nil {
+		t.Errorf("Resolve without mirrors = %+v, want nil", got)
+	}
+}
+
+func TestParseMirrors_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirrors string
+		want    string
+	}{
+		{"missing registry", "mirrors:\n  - endpoints: [mirror.example.com]\n", "registry is required"},
+		{"no endpoints", "mirrors:\n  - registry: docker.io\n", "at least one endpoint"},
+		{"bad scheme", "mirrors:\n  - registry: docker.io\n    endpoints: ['ftp://mirror.example.com']\n", "unsupported scheme"},
+		{"duplicate registry", "mirrors:\n  - registry: docker.io\n    endpoints: [a.example.com]\n  - registry: index.docker.io\n    endpoints: [b.example.com]\n", "duplicate registry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := analyzer.ParseMirrors([]byte(tt.mirrors))
+			if err == nil {
+				t.Fatalf("Expected error containing %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzePodObject_MirrorRemediation(t *testing.T) {
+	mirrors, err := analyzer.ParseMirrors([]byte(corpMirrors))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	pod := imagePullPod("default", "web")
+	pod.Spec.Containers[0].Image = "bitnami/redis:7"
+	pod.Status.ContainerStatuses[0].State.Waiting.Message =
+		`failed to pull and unpack image "docker.io/bitnami/redis:7": failed to resolve reference "docker.io/bitnami/redis:7": not found`
+
+	az, _, _ := newFixAnalyzer(t)
+	az.SetMirrors(mirrors)
+
+	report, err := az.AnalyzePodObject(context.Background(), &pod)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	finding := report.Findings[0]
+	endpoint := finding.ImageReferences[0].EffectiveEndpoint()
+	if endpoint == nil || endpoint.Host != "mirror.corp.example.com" {
+		t.Fatalf("EffectiveEndpoint = %+v, want mirror.corp.example.com", endpoint)
+	}
+	for _, want := range []string{
+		"pulled through the mirror mirror.corp.example.com",
+		"https://mirror.corp.example.com/v2/dockerhub/bitnami/redis/manifests/7",
+	} {
+		if !mentions(finding.RemediationSteps, want) {
+			t.Errorf("RemediationSteps = %v, want a step with %q", finding.RemediationSteps, want)
+		}
+	}
+}
+
+func TestCheckManifest_Mirror(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/dockerhub/library/nginx/manifests/1.25" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	mirrors, err := analyzer.ParseMirrors([]byte("mirrors:\n  - registry: docker.io\n    endpoints: ['" + server.URL + "/dockerhub']\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ref, err := types.ParseImageReference("app", "nginx:1.25")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ref.PullEndpoints = mirrors.Resolve(*ref)
+
+	exists, err := analyzer.CheckManifest(context.Background(), *ref, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !exists {
+		t.Error("CheckManifest = false, want true from the mirror")
+	}
+}
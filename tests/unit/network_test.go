@@ -0,0 +1,85 @@
+package unit
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/types"
+)
+
+func TestInspectRegistryCertificate_Untrusted(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// An empty pool stands in for a system pool that does not know the test CA
+	result := analyzer.InspectRegistryCertificate(context.Background(), server.Listener.Addr().String(), x509.NewCertPool())
+
+	if result.ErrorMessage != "" {
+		t.Fatalf("Unexpected handshake error: %s", result.ErrorMessage)
+	}
+	if result.Trusted || result.Success {
+		t.Error("Expected certificate from an unknown authority to be untrusted")
+	}
+	if !strings.Contains(result.VerifyError, "unknown authority") {
+		t.Errorf("VerifyError = %q, want unknown authority", result.VerifyError)
+	}
+	if result.Issuer == "" || result.NotAfter.IsZero() || len(result.Chain) == 0 {
+		t.Errorf("Expected issuer, expiry and chain to be reported, got %+v", result)
+	}
+	if !containsString(result.SANs, "127.0.0.1") {
+		t.Errorf("SANs = %v, want 127.0.0.1", result.SANs)
+	}
+}
+
+func TestInspectRegistryCertificate_Trusted(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	result := analyzer.InspectRegistryCertificate(context.Background(), server.Listener.Addr().String(), roots)
+	if !result.Trusted || !result.Success {
+		t.Errorf("Expected trusted certificate, got verify error %q", result.VerifyError)
+	}
+}
+
+func TestInspectRegistryCertificate_PlainHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	result := analyzer.InspectRegistryCertificate(context.Background(), server.Listener.Addr().String(), nil)
+	if !result.PlainHTTP {
+		t.Errorf("Expected plain HTTP endpoint to be detected, got %+v", result)
+	}
+}
+
+func TestDetectRootCause_TLSCertificate(t *testing.T) {
+	messages := []string{
+		`Failed to pull image "registry.local/app:v1": failed to do request: Head "https://registry.local/v2/app/manifests/v1": x509: certificate signed by unknown authority`,
+		`Failed to pull image "registry.local/app:v1": x509: certificate has expired or is not yet valid`,
+		`Failed to pull image "registry.local:5000/app:v1": http: server gave HTTP response to HTTPS client`,
+	}
+
+	for _, message := range messages {
+		evidence := []types.Evidence{{Source: types.EvidenceSourceEvent, Reason: "Failed", Message: message}}
+		cause, _ := analyzer.DetectRootCauseFromEvidence(evidence, analyzer.ParseEvents(nil))
+		if cause != types.RootCauseTLSCertificate {
+			t.Errorf("Root cause for %q = %s, want %s", message, cause, types.RootCauseTLSCertificate)
+		}
+	}
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAnalyzePodObject_NodeDiskPressure(t *testing.T) {
+	pod := imagePullPod("default", "web")
+	pod.Spec.NodeName = "worker-1"
+	pod.Status.ContainerStatuses[0].State.Waiting.Message =
+		`failed to pull and unpack image "registry.example.com/app:v1": failed to extract layer sha256:abc: write /var/lib/containerd/tmp: no space left on device`
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{
+				Type:    corev1.NodeDiskPressure,
+				Status:  corev1.ConditionTrue,
+				Message: "kubelet has disk pressure",
+			}},
+			Capacity: corev1.ResourceList{
+				corev1.ResourceEphemeralStorage: resource.MustParse("20Gi"),
+			},
+		},
+	}
+
+	logger, err := output.NewAuditLogger(false)
+	if err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+	az := analyzer.NewAnalyzer(&k8s.Client{Clientset: fake.NewSimpleClientset(node)}, logger, 5*time.Second)
+
+	report, err := az.AnalyzePodObject(context.Background(), &pod)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	finding := report.Findings[0]
+	if finding.RootCause != types.RootCauseNodeDiskPressure {
+		t.Fatalf("Root cause = %s, want %s", finding.RootCause, types.RootCauseNodeDiskPressure)
+	}
+
+	diag := finding.NodeDiagnostics
+	if diag == nil || !diag.DiskPressure {
+		t.Fatalf("NodeDiagnostics = %+v, want DiskPressure true", diag)
+	}
+	if diag.EphemeralStorageCapacity != "20Gi" {
+		t.Errorf("EphemeralStorageCapacity = %s, want 20Gi", diag.EphemeralStorageCapacity)
+	}
+	// The fake clientset cannot proxy to the kubelet, so defaults apply
+	if diag.ImageGCHighThresholdPercent != 85 || diag.ThresholdsSource != "kubelet defaults" {
+		t.Errorf("Image GC high = %d from %q, want 85 from kubelet defaults",
+			diag.ImageGCHighThresholdPercent, diag.ThresholdsSource)
+	}
+}
+
+func TestParseKubeletConfigz(t *testing.T) {
+	raw := []byte(`{"kubeletconfig":{"imageGCHighThresholdPercent":70,"imageGCLowThresholdPercent":60,"evictionHard":{"imagefs.available":"15%","nodefs.available":"10%"}}}`)
+
+	config, err := k8s.ParseKubeletConfigz(raw)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.ImageGCHighThresholdPercent == nil || *config.ImageGCHighThresholdPercent != 70 {
+		t.Errorf("ImageGCHighThresholdPercent = %v, want 70", config.ImageGCHighThresholdPercent)
+	}
+	if config.ImageGCLowThresholdPercent == nil || *config.ImageGCLowThresholdPercent != 60 {
+		t.Errorf("ImageGCLowThresholdPercent = %v, want 60", config.ImageGCLowThresholdPercent)
+	}
+	if config.EvictionHard["imagefs.available"] != "15%" {
+		t.Errorf("imagefs.available = %s, want 15%%", config.EvictionHard["imagefs.available"])
+	}
+}
@@ -0,0 +1,116 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// scheduledPod returns a pod on a node, failing to pull its image or running it
+func scheduledPod(name, node string, failing bool) corev1.Pod {
+	pod := imagePullPod("default", name)
+	pod.Spec.NodeName = node
+	if !failing {
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+		pod.Status.ContainerStatuses[0].ImageID = "registry.example.com/app@sha256:1111"
+	}
+	return pod
+}
+
+// zonedNode returns a node in a zone and node pool
+func zonedNode(name, zone, pool string, conditions ...corev1.NodeCondition) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+			"topology.kubernetes.io/zone":   zone,
+			"cloud.google.com/gke-nodepool": pool,
+		}},
+		Status: corev1.NodeStatus{Conditions: conditions},
+	}
+}
+
+func TestCorrelateNodeFailures_ConcentratedOnNode(t *testing.T) {
+	var pods []corev1.Pod
+	for i := 0; i < 3; i++ {
+		pods = append(pods, scheduledPod(fmt.Sprintf("bad-%d", i), "node-a", true))
+	}
+	for i := 0; i < 4; i++ {
+		pods = append(pods, scheduledPod(fmt.Sprintf("good-b-%d", i), "node-b", false))
+		pods = append(pods, scheduledPod(fmt.Sprintf("good-c-%d", i), "node-c", false))
+	}
+	nodes := []corev1.Node{
+		zonedNode("node-a", "zone-1", "default-pool",
+			corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			corev1.NodeCondition{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue, Message: "ephemeral storage low"}),
+		zonedNode("node-b", "zone-1", "default-pool"),
+		zonedNode("node-c", "zone-2", "default-pool"),
+	}
+
+	findings := analyzer.CorrelateNodeFailures(pods, nodes)
+	if len(findings) != 1 {
+		t.Fatalf("Findings = %d, want 1 for node-a", len(findings))
+	}
+
+	finding := findings[0]
+	if finding.RootCause != types.RootCauseNodeSpecific || finding.Scope != types.FindingScopeNode || finding.Subject != "Node/node-a" {
+		t.Errorf("Finding = %s %s %s, want NODE_SPECIFIC_FAILURE node Node/node-a", finding.RootCause, finding.Scope, finding.Subject)
+	}
+	if err := finding.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	// %s on a RootCause prints its description; the summary leads with the ID
+	if want := "NODE_SPECIFIC_FAILURE: Image pulls fail on specific nodes but succeed elsewhere"; finding.Summary != want {
+		t.Errorf("Summary = %q, want %q", finding.Summary, want)
+	}
+
+	corr := finding.NodeCorrelation
+	if len(corr.FailedPods) != 3 || corr.HealthyElsewhere != 8 || corr.FailureRate != 1 || corr.ElsewhereFailureRate != 0 {
+		t.Errorf("Correlation = %+v, want 3 failing pods, 8 healthy elsewhere", corr)
+	}
+	if corr.Zone != "zone-1" || corr.NodePool != "default-pool" {
+		t.Errorf("Zone/pool = %s/%s, want zone-1/default-pool", corr.Zone, corr.NodePool)
+	}
+	if len(corr.Images) != 1 || corr.Images[0] != "registry.example.com/app:v1" {
+		t.Errorf("Images = %v", corr.Images)
+	}
+	if len(corr.Groups) != 2 || corr.Groups[0].FailedPods != 3 || corr.Groups[0].HealthyPods != 4 {
+		t.Errorf("Groups = %+v, want zone-1 with 3 failing and 4 pulled", corr.Groups)
+	}
+	if !mentions(finding.RemediationSteps, "DiskPressure=True: ephemeral storage low") {
+		t.Errorf("RemediationSteps = %v, want the unhealthy condition", finding.RemediationSteps)
+	}
+	if !mentions(finding.RemediationSteps, "kubectl cordon node-a") {
+		t.Errorf("RemediationSteps = %v, want a cordon step", finding.RemediationSteps)
+	}
+}
+
+func TestCorrelateNodeFailures_SpreadAcrossNodes(t *testing.T) {
+	// Failures on every node point at the image or registry, not at a node
+	var pods []corev1.Pod
+	for _, node := range []string{"node-a", "node-b", "node-c"} {
+		pods = append(pods,
+			scheduledPod(node+"-bad-1", node, true),
+			scheduledPod(node+"-bad-2", node, true),
+			scheduledPod(node+"-good", node, false))
+	}
+
+	if findings := analyzer.CorrelateNodeFailures(pods, nil); len(findings) != 0 {
+		t.Errorf("Findings = %+v, want none when failures are spread evenly", findings)
+	}
+}
+
+func TestCorrelateNodeFailures_NoHealthyBaseline(t *testing.T) {
+	// An image no node has pulled may simply not exist
+	pods := []corev1.Pod{
+		scheduledPod("bad-1", "node-a", true),
+		scheduledPod("bad-2", "node-a", true),
+	}
+
+	if findings := analyzer.CorrelateNodeFailures(pods, nil); len(findings) != 0 {
+		t.Errorf("Findings = %+v, want none without healthy pods elsewhere", findings)
+	}
+}
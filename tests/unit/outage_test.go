@@ -0,0 +1,123 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// failedPull returns the scan result of a pod of a Deployment failing to pull an image
+func failedPull(namespace, deployment string, i int, image string, cause types.RootCause, firstFailure time.Time) analyzer.PodResult {
+	controller := true
+	pod := imagePullPod(namespace, fmt.Sprintf("%s-5d8f7-%d", deployment, i))
+	pod.Labels = map[string]string{"pod-template-hash": "5d8f7"}
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: deployment + "-5d8f7", Controller: &controller}}
+	pod.Spec.NodeName = "node-a"
+
+	ref, _ := types.ParseImageReference("app", image)
+	last := firstFailure.Add(10 * time.Minute)
+	return analyzer.PodResult{
+		Pod: &pod,
+		Report: &types.AnalysisReport{Findings: []types.DiagnosticFinding{{
+			RootCause:        cause,
+			PodName:          pod.Name,
+			PodNamespace:     namespace,
+			ImageReferences:  []types.ImageReference{*ref},
+			FailureCount:     5,
+			FirstFailureTime: &firstFailure,
+			LastFailureTime:  &last,
+		}}},
+	}
+}
+
+func TestDetectRegistryOutages(t *testing.T) {
+	since := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	results := []analyzer.PodResult{
+		failedPull("shop", "web", 1, "quay.io/team/web:v1", types.RootCauseNetworkIssue, since.Add(2*time.Minute)),
+		failedPull("shop", "web", 2, "quay.io/team/web:v1", types.RootCauseNetworkIssue, since),
+		failedPull("billing", "api", 1, "quay.io/team/api:v3", types.RootCauseRegistryUnavailable, since.Add(time.Minute)),
+		failedPull("billing", "api", 2, "quay.io/team/api:v3", types.RootCauseNetworkIssue, since.Add(3*time.Minute)),
+		failedPull("shop", "cart", 1, "registry.example.com/cart:v9", types.RootCauseImageNotFound, since),
+	}
+
+	findings := analyzer.DetectRegistryOutages(results)
+	if len(findings) != 1 {
+		t.Fatalf("Findings = %d, want 1 for quay.io", len(findings))
+	}
+
+	finding := findings[0]
+	if finding.Scope != types.FindingScopeCluster || finding.Subject != "Registry/quay.io" {
+		t.Errorf("Scope/Subject = %s %s, want cluster Registry/quay.io", finding.Scope, finding.Subject)
+	}
+	if want := "REGISTRY_UNAVAILABLE: Registry quay.io unavailable since 2024-03-01T09:00:00Z"; finding.Summary != want {
+		t.Errorf("Summary = %q, want %q", finding.Summary, want)
+	}
+	if err := finding.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	outage := finding.RegistryOutage
+	if outage.AffectedPods != 4 || outage.ShareOfFailures != 0.8 {
+		t.Errorf("Outage = %d pods (%.2f), want 4 (0.80)", outage.AffectedPods, outage.ShareOfFailures)
+	}
+	if outage.RootCauses[types.RootCauseNetworkIssue] != 3 || outage.RootCauses[types.RootCauseRegistryUnavailable] != 1 {
+		t.Errorf("RootCauses = %v", outage.RootCauses)
+	}
+	want := []types.AffectedWorkload{
+		{Namespace: "billing", Kind: "Deployment", Name: "api", Pods: 2},
+		{Namespace: "shop", Kind: "Deployment", Name: "web", Pods: 2},
+	}
+	if len(outage.Workloads) != len(want) {
+		t.Fatalf("Workloads = %+v, want %+v", outage.Workloads, want)
+	}
+	for i := range want {
+		if outage.Workloads[i] != want[i] {
+			t.Errorf("Workload %d = %+v, want %+v", i, outage.Workloads[i], want[i])
+		}
+	}
+	if !mentions(finding.RemediationSteps, "kubectl debug node/node-a") {
+		t.Errorf("RemediationSteps = %v, want a check from a node", finding.RemediationSteps)
+	}
+}
+
+func TestDetectRegistryOutages_AttributesMirror(t *testing.T) {
+	since := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	var results []analyzer.PodResult
+	for i := 0; i < 3; i++ {
+		result := failedPull("shop", "web", i, "nginx:1.25", types.RootCauseNetworkIssue, since)
+		ref := &result.Report.Findings[0].ImageReferences[0]
+		ref.PullEndpoints = []types.RegistryEndpoint{
+			{Host: "mirror.corp.example.com", Repository: "library/nginx", Mirror: true},
+			{Host: "docker.io", Repository: "library/nginx"},
+		}
+		results = append(results, result)
+	}
+
+	findings := analyzer.DetectRegistryOutages(results)
+	if len(findings) != 1 {
+		t.Fatalf("Findings = %d, want 1", len(findings))
+	}
+	if outage := findings[0].RegistryOutage; outage.Registry != "mirror.corp.example.com" || outage.MirrorOf != "docker.io" {
+		t.Errorf("Outage = %s (mirror of %s), want mirror.corp.example.com (mirror of docker.io)", outage.Registry, outage.MirrorOf)
+	}
+}
+
+func TestDetectRegistryOutages_UnrelatedFailures(t *testing.T) {
+	since := time.Now().Add(-time.Hour)
+	results := []analyzer.PodResult{
+		failedPull("shop", "web", 1, "quay.io/team/web:v1", types.RootCauseNetworkIssue, since),
+		failedPull("shop", "web", 2, "quay.io/team/web:v1", types.RootCauseNetworkIssue, since),
+		failedPull("shop", "cart", 1, "registry.example.com/cart:v9", types.RootCauseImageNotFound, since),
+		failedPull("shop", "cart", 2, "registry.example.com/cart:v9", types.RootCauseImageNotFound, since),
+		{Pod: &corev1.Pod{}, Err: fmt.Errorf("timeout")},
+	}
+
+	if findings := analyzer.DetectRegistryOutages(results); len(findings) != 0 {
+		t.Errorf("Findings = %+v, want none below the outage thresholds", findings)
+	}
+}
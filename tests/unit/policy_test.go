@@ -0,0 +1,140 @@
+package unit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const kyvernoDenial = `Error creating: admission webhook "validate.kyverno.svc-fail" denied the request: 
+
+resource Pod/default/web-abc-x7k2p was blocked due to the following policies 
+
+restrict-image-registries:
+  validate-registries: 'validation error: Unknown image registry. rule validate-registries failed at path /spec/containers/0/image/'`
+
+func TestParsePolicyRejection(t *testing.T) {
+	tests := []struct {
+		name           string
+		message        string
+		wantController string
+		wantPolicies   []string
+		wantSignature  bool
+	}{
+		{
+			name:           "Kyverno validate policy",
+			message:        kyvernoDenial,
+			wantController: types.PolicyControllerKyverno,
+			wantPolicies:   []string{"restrict-image-registries"},
+		},
+		{
+			name: "Kyverno image verification",
+			message: "Error creating: admission webhook \"mutate.kyverno.svc-fail\" denied the request: \n\n" +
+				"resource Pod/default/web was blocked due to the following policies\n\n" +
+				"check-image:\n  verify-signature: 'failed to verify image registry.example.com/app:v1: .attestors[0].entries[0].keys: no matching signatures'",
+			wantController: types.PolicyControllerKyverno,
+			wantPolicies:   []string{"check-image"},
+			wantSignature:  true,
+		},
+		{
+			name:           "Gatekeeper constraint",
+			message:        `Error creating: admission webhook "validation.gatekeeper.sh" denied the request: [allowed-repos] container <app> has an invalid image repo <docker.io/app:v1>, allowed repos are ["registry.example.com/"]`,
+			wantController: types.PolicyControllerGatekeeper,
+			wantPolicies:   []string{"allowed-repos"},
+		},
+		{
+			name:           "Sigstore policy-controller",
+			message:        "Error creating: admission webhook \"policy.sigstore.dev\" denied the request: validation failed: failed policy: signed-images: spec.containers[0].image\nregistry.example.com/app@sha256:abc signature key validation failed for authority authority-0: no matching signatures",
+			wantController: types.PolicyControllerSigstore,
+			wantPolicies:   []string{"signed-images"},
+			wantSignature:  true,
+		},
+		{
+			name:           "ValidatingAdmissionPolicy",
+			message:        `Error creating: pods "web-1" is forbidden: ValidatingAdmissionPolicy 'trusted-registries.example.com' with binding 'trusted-registries-binding' denied request: failed expression`,
+			wantController: types.PolicyControllerValidatingPolicy,
+			wantPolicies:   []string{"trusted-registries.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejection := analyzer.ParsePolicyRejection(tt.message)
+			if rejection == nil {
+				t.Fatal("Expected a policy rejection, got nil")
+			}
+			if rejection.Controller != tt.wantController {
+				t.Errorf("Controller = %s, want %s", rejection.Controller, tt.wantController)
+			}
+			if !reflect.DeepEqual(rejection.Policies, tt.wantPolicies) {
+				t.Errorf("Policies = %v, want %v", rejection.Policies, tt.wantPolicies)
+			}
+			if rejection.Signature != tt.wantSignature {
+				t.Errorf("Signature = %v, want %v", rejection.Signature, tt.wantSignature)
+			}
+		})
+	}
+
+	if analyzer.ParsePolicyRejection(`Error creating: pods "web-1" is forbidden: exceeded quota: compute`) != nil {
+		t.Error("Quota errors must not be reported as policy rejections")
+	}
+}
+
+func TestAnalyzeAdmissionRejections(t *testing.T) {
+	now := time.Now()
+	clientset := fake.NewSimpleClientset(
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "web-abc.1", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "ReplicaSet", Name: "web-abc", Namespace: "default"},
+			Reason:         "FailedCreate",
+			Message:        kyvernoDenial,
+			Count:          14,
+			FirstTimestamp: metav1.NewTime(now.Add(-30 * time.Minute)),
+			LastTimestamp:  metav1.NewTime(now.Add(-time.Minute)),
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "api-xyz.1", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "ReplicaSet", Name: "api-xyz", Namespace: "default"},
+			Reason:         "FailedCreate",
+			Message:        `Error creating: pods "api-xyz-1" is forbidden: exceeded quota: compute`,
+			Count:          3,
+		},
+	)
+
+	logger, err := output.NewAuditLogger(false)
+	if err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+	az := analyzer.NewAnalyzer(&k8s.Client{Clientset: clientset}, logger, 5*time.Second)
+
+	findings, err := az.AnalyzeAdmissionRejections(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("Findings = %d, want 1 (quota errors are not policy rejections)", len(findings))
+	}
+
+	finding := findings[0]
+	if finding.RootCause != types.RootCausePolicyRejection || finding.Scope != types.FindingScopeWorkload {
+		t.Errorf("Finding = %s/%s, want POLICY_REJECTION/workload", finding.RootCause, finding.Scope)
+	}
+	if finding.Subject != "ReplicaSet/web-abc" {
+		t.Errorf("Subject = %s, want ReplicaSet/web-abc", finding.Subject)
+	}
+	if finding.FailureCount != 14 {
+		t.Errorf("FailureCount = %d, want 14", finding.FailureCount)
+	}
+	if err := finding.Validate(); err != nil {
+		t.Errorf("Workload finding should validate without a pod: %v", err)
+	}
+}
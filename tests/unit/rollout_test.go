@@ -0,0 +1,165 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// revisionReplicaSet returns a ReplicaSet of Deployment web at a revision
+func revisionReplicaSet(name, revision, image string, ready int32) *appsv1.ReplicaSet {
+	controller := true
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			Annotations:     map[string]string{k8s.RevisionAnnotation: revision},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", Controller: &controller}},
+		},
+		Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: image}},
+		}}},
+		Status: appsv1.ReplicaSetStatus{ReadyReplicas: ready},
+	}
+}
+
+func TestAnalyzePodObject_RolloutCorrelation(t *testing.T) {
+	controller := true
+	pod := imagePullPod("default", "web-7c4d-xyz")
+	pod.Spec.Containers[0].Image = "registry.example.com/app:v2"
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7c4d", Controller: &controller}}
+	pod.Status.ContainerStatuses[0].State.Waiting.Message =
+		`failed to pull and unpack image "registry.example.com/app:v2": failed to resolve reference "registry.example.com/app:v2": manifest unknown`
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status: corev1.NodeStatus{Images: []corev1.ContainerImage{
+			{Names: []string{"registry.example.com/app@sha256:1111", "registry.example.com/app:v1"}},
+		}},
+	}
+
+	az, _, _ := newFixAnalyzer(t,
+		revisionReplicaSet("web-5b8f", "1", "registry.example.com/app:v1", 2),
+		revisionReplicaSet("web-6a1e", "2", "registry.example.com/app:v2", 0),
+		revisionReplicaSet("web-7c4d", "3", "registry.example.com/app:v2", 0),
+		node,
+	)
+
+	report, err := az.AnalyzePodObject(context.Background(), &pod)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	finding := report.Findings[0]
+	if finding.RootCause != types.RootCauseImageNotFound {
+		t.Fatalf("Root cause = %s, want %s", finding.RootCause, types.RootCauseImageNotFound)
+	}
+	rollout := finding.Rollout
+	if rollout == nil {
+		t.Fatal("Expected a rollout correlation")
+	}
+	if rollout.Deployment != "web" || rollout.Revision != 3 || rollout.IntroducedRevision != 2 || rollout.PreviousRevision != 1 {
+		t.Errorf("Rollout = %+v, want web revision 3 introduced in 2, previous 1", rollout)
+	}
+
+	if len(rollout.ImageChanges) != 1 {
+		t.Fatalf("ImageChanges = %+v, want one", rollout.ImageChanges)
+	}
+	change := rollout.ImageChanges[0]
+	if change.PreviousImage != "registry.example.com/app:v1" || change.CurrentImage != "registry.example.com/app:v2" {
+		t.Errorf("Change = %s -> %s", change.PreviousImage, change.CurrentImage)
+	}
+	// Ready pods show the image is cached, not that a node without it can pull it
+	if change.Pullable != nil || !strings.Contains(change.PullableReason, "cached: 2 ready pods of revision 1") {
+		t.Errorf("Pullable = %v (%s), want unknown, with the ready pods of revision 1 as cached", change.Pullable, change.PullableReason)
+	}
+	if len(change.CachedOnNodes) != 1 || change.CachedOnNodes[0] != "node-a" {
+		t.Errorf("CachedOnNodes = %v, want [node-a]", change.CachedOnNodes)
+	}
+
+	want := "kubectl rollout undo deployment/web --to-revision=1 -n default"
+	if !mentions(finding.RemediationSteps, want) {
+		t.Errorf("RemediationSteps = %v, want a step with %q", finding.RemediationSteps, want)
+	}
+}
+
+func TestAnalyzePodObject_NoRolloutWithoutImageChange(t *testing.T) {
+	controller := true
+	pod := imagePullPod("default", "web-7c4d-xyz")
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7c4d", Controller: &controller}}
+	pod.Status.ContainerStatuses[0].State.Waiting.Message = "manifest unknown"
+
+	az, _, _ := newFixAnalyzer(t,
+		revisionReplicaSet("web-5b8f", "1", "registry.example.com/app:v1", 0),
+		revisionReplicaSet("web-7c4d", "2", "registry.example.com/app:v1", 0),
+	)
+
+	report, err := az.AnalyzePodObject(context.Background(), &pod)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Findings[0].Rollout != nil {
+		t.Errorf("Rollout = %+v, want none when no revision changed the image", report.Findings[0].Rollout)
+	}
+}
+
+func TestCheckManifest(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") == "repository:private/app:pull" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token":"anonymous"}`))
+		case r.Header.Get("Authorization") != "Bearer anonymous":
+			repo, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test",scope="repository:`+repo+`:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/team/app/manifests/v1":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	registry := strings.TrimPrefix(server.URL, "https://")
+	tests := []struct {
+		repository string
+		tag        string
+		want       bool
+		wantErr    bool
+	}{
+		{"team/app", "v1", true, false},
+		{"team/app", "v9", false, false},
+		{"private/app", "v1", false, true},
+	}
+
+	// The answer decides admission: an unverified certificate is refused by default
+	if _, err := analyzer.CheckManifest(context.Background(), types.ImageReference{Registry: registry, Repository: "team/app", Tag: "v1"}, false); err == nil {
+		t.Error("CheckManifest with a self-signed certificate: want a verification error")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repository+":"+tt.tag, func(t *testing.T) {
+			got, err := analyzer.CheckManifest(context.Background(), types.ImageReference{Registry: registry, Repository: tt.repository, Tag: tt.tag}, true)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CheckManifest = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -42,6 +42,41 @@ func TestRootCause_String(t *testing.T) {
 			cause:    types.RootCauseManifestError,
 			expected: "Image manifest is invalid or corrupted",
 		},
+		{
+			name:     "POLICY_REJECTION",
+			cause:    types.RootCausePolicyRejection,
+			expected: "Image blocked by an admission or image policy",
+		},
+		{
+			name:     "NODE_DISK_PRESSURE",
+			cause:    types.RootCauseNodeDiskPressure,
+			expected: "Node ran out of disk space while pulling the image",
+		},
+		{
+			name:     "TLS_CERTIFICATE_ERROR",
+			cause:    types.RootCauseTLSCertificate,
+			expected: "Registry TLS certificate is not trusted or invalid",
+		},
+		{
+			name:     "IMAGE_NEVER_PULL",
+			cause:    types.RootCauseImageNeverPull,
+			expected: "Image not present on node and pull policy is Never",
+		},
+		{
+			name:     "INVALID_IMAGE_NAME",
+			cause:    types.RootCauseInvalidImageName,
+			expected: "Image reference is invalid",
+		},
+		{
+			name:     "REGISTRY_UNAVAILABLE",
+			cause:    types.RootCauseRegistryUnavailable,
+			expected: "Registry is unavailable",
+		},
+		{
+			name:     "SIGNATURE_VALIDATION_FAILED",
+			cause:    types.RootCauseSignatureValidation,
+			expected: "Image signature validation failed",
+		},
 		{
 			name:     "TRANSIENT_FAILURE",
 			cause:    types.RootCauseTransient,
@@ -105,6 +140,41 @@ func TestRootCause_Severity(t *testing.T) {
 			cause:    types.RootCauseManifestError,
 			expected: types.SeverityMedium,
 		},
+		{
+			name:     "POLICY_REJECTION is HIGH severity",
+			cause:    types.RootCausePolicyRejection,
+			expected: types.SeverityHigh,
+		},
+		{
+			name:     "NODE_DISK_PRESSURE is HIGH severity",
+			cause:    types.RootCauseNodeDiskPressure,
+			expected: types.SeverityHigh,
+		},
+		{
+			name:     "TLS_CERTIFICATE_ERROR is HIGH severity",
+			cause:    types.RootCauseTLSCertificate,
+			expected: types.SeverityHigh,
+		},
+		{
+			name:     "IMAGE_NEVER_PULL is HIGH severity",
+			cause:    types.RootCauseImageNeverPull,
+			expected: types.SeverityHigh,
+		},
+		{
+			name:     "INVALID_IMAGE_NAME is HIGH severity",
+			cause:    types.RootCauseInvalidImageName,
+			expected: types.SeverityHigh,
+		},
+		{
+			name:     "SIGNATURE_VALIDATION_FAILED is HIGH severity",
+			cause:    types.RootCauseSignatureValidation,
+			expected: types.SeverityHigh,
+		},
+		{
+			name:     "REGISTRY_UNAVAILABLE is MEDIUM severity",
+			cause:    types.RootCauseRegistryUnavailable,
+			expected: types.SeverityMedium,
+		},
 		{
 			name:     "TRANSIENT_FAILURE is LOW severity",
 			cause:    types.RootCauseTransient,
@@ -179,6 +249,14 @@ func TestAllRootCauseConstants(t *testing.T) {
 		types.RootCauseRateLimit,
 		types.RootCausePermissionDenied,
 		types.RootCauseManifestError,
+		types.RootCauseTLSCertificate,
+		types.RootCauseNodeDiskPressure,
+		types.RootCausePolicyRejection,
+		types.RootCauseNodeSpecific,
+		types.RootCauseImageNeverPull,
+		types.RootCauseInvalidImageName,
+		types.RootCauseRegistryUnavailable,
+		types.RootCauseSignatureValidation,
 		types.RootCauseTransient,
 		types.RootCauseUnknown,
 	}
@@ -0,0 +1,139 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const harborRules = `
+rules:
+  - id: HARBOR_PROXY_AUTH
+    description: Corporate proxy rejected the Harbor credentials
+    severity: high
+    priority: 10
+    match:
+      - contains: "407 Proxy Authentication Required"
+      - regex: 'harbor\.corp\.example\.com.*unauthorized'
+        weight: 0.8
+    remediation:
+      - "Follow the runbook: https://runbooks.corp.example.com/harbor-proxy-auth"
+      - "Renew the robot account for {{.Repository}} on {{.Registry}}"
+  - id: RATE_LIMIT_EXCEEDED
+    match:
+      - contains: "quota of pulls exhausted"
+`
+
+func TestParseRules_CustomRootCause(t *testing.T) {
+	rules, err := analyzer.ParseRules([]byte(harborRules))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	evidence := eventEvidence(
+		`Failed to pull image "harbor.corp.example.com/team/app:v1": unexpected status: 407 Proxy Authentication Required, unauthorized`)
+
+	detection := analyzer.ScoreRootCausesWithRules(evidence, &analyzer.EventAnalysis{}, rules)
+	if detection.RootCause != "HARBOR_PROXY_AUTH" {
+		t.Fatalf("Root cause = %s, want HARBOR_PROXY_AUTH (trace: %v)", detection.RootCause, detection.Trace)
+	}
+
+	// Without the rules the built-in table falls back to the generic match
+	if got := analyzer.ScoreRootCauses(evidence, &analyzer.EventAnalysis{}).RootCause; got != types.RootCauseAuthFailure {
+		t.Errorf("Built-in root cause = %s, want %s", got, types.RootCauseAuthFailure)
+	}
+}
+
+func TestParseRules_ExtendsBuiltinRootCause(t *testing.T) {
+	rules, err := analyzer.ParseRules([]byte(harborRules))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	evidence := eventEvidence("registry error: quota of pulls exhausted for this project")
+	if got := analyzer.ScoreRootCausesWithRules(evidence, &analyzer.EventAnalysis{}, rules).RootCause; got != types.RootCauseRateLimit {
+		t.Errorf("Root cause = %s, want %s", got, types.RootCauseRateLimit)
+	}
+}
+
+func TestParseRules_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		want  string
+	}{
+		{"lower case id", "rules:\n  - id: harbor\n    description: x\n    match: [{contains: x}]\n", "upper case"},
+		{"missing description", "rules:\n  - id: HARBOR\n    match: [{contains: x}]\n", "description is required"},
+		{"no matchers", "rules:\n  - id: HARBOR\n    description: x\n", "at least one matcher"},
+		{"both matchers", "rules:\n  - id: HARBOR\n    description: x\n    match: [{contains: x, regex: y}]\n", "exactly one of"},
+		{"bad regex", "rules:\n  - id: HARBOR\n    description: x\n    match: [{regex: '(unclosed'}]\n", "matcher 1"},
+		{"bad severity", "rules:\n  - id: HARBOR\n    description: x\n    severity: urgent\n    match: [{contains: x}]\n", "severity"},
+		{"unknown template field", "rules:\n  - id: HARBOR\n    description: x\n    match: [{contains: x}]\n    remediation: ['{{.Namespace}}']\n", "remediation step 1"},
+		{"reserved id", "rules:\n  - id: UNKNOWN\n    match: [{contains: x}]\n", "decided by the analyzer"},
+		{"built-in id", "rules:\n  - id: LATEST_TAG\n    description: x\n    match: [{contains: x}]\n", "built-in root cause"},
+		{"duplicate id", "rules:\n  - id: HARBOR\n    description: x\n    match: [{contains: x}]\n  - id: HARBOR\n    description: y\n    match: [{contains: y}]\n", "duplicate id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := analyzer.ParseRules([]byte(tt.rules))
+			if err == nil {
+				t.Fatalf("Expected error containing %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzePodObject_RuleRemediation(t *testing.T) {
+	rules, err := analyzer.ParseRules([]byte(harborRules))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	pod := imagePullPod("default", "web")
+	pod.Spec.Containers[0].Image = "harbor.corp.example.com/team/app:v1"
+	pod.Status.ContainerStatuses[0].State.Waiting.Message =
+		`failed to pull and unpack image "harbor.corp.example.com/team/app:v1": 407 Proxy Authentication Required`
+
+	logger, err := output.NewAuditLogger(false)
+	if err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+	az := analyzer.NewAnalyzer(&k8s.Client{Clientset: fake.NewSimpleClientset()}, logger, 5*time.Second)
+	az.SetRules(rules)
+
+	report, err := az.AnalyzePodObject(context.Background(), &pod)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	finding := report.Findings[0]
+	if finding.RootCause != "HARBOR_PROXY_AUTH" || finding.Severity != types.SeverityHigh {
+		t.Fatalf("Finding = %s/%s, want HARBOR_PROXY_AUTH/HIGH", finding.RootCause, finding.Severity)
+	}
+	if finding.Summary != "HARBOR_PROXY_AUTH: Corporate proxy rejected the Harbor credentials" {
+		t.Errorf("Summary = %q", finding.Summary)
+	}
+	want := []string{
+		"Follow the runbook: https://runbooks.corp.example.com/harbor-proxy-auth",
+		"Renew the robot account for team/app on harbor.corp.example.com",
+	}
+	if len(finding.RemediationSteps) != len(want) {
+		t.Fatalf("RemediationSteps = %v, want %v", finding.RemediationSteps, want)
+	}
+	for i := range want {
+		if finding.RemediationSteps[i] != want[i] {
+			t.Errorf("RemediationSteps[%d] = %q, want %q", i, finding.RemediationSteps[i], want[i])
+		}
+	}
+}
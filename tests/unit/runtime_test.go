@@ -0,0 +1,97 @@
+package unit
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// runtimeCorpusEntry is a real-world pull error with its expected classification
+type runtimeCorpusEntry struct {
+	Message  string          `yaml:"message"`
+	Expected types.RootCause `yaml:"expected"`
+}
+
+func TestScoreRootCauses_RuntimeCorpus(t *testing.T) {
+	data, err := os.ReadFile("testdata/runtime_messages.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read corpus: %v", err)
+	}
+	var corpus map[types.ContainerRuntime][]runtimeCorpusEntry
+	if err := yaml.Unmarshal(data, &corpus); err != nil {
+		t.Fatalf("Failed to parse corpus: %v", err)
+	}
+
+	for _, runtime := range []types.ContainerRuntime{types.RuntimeContainerd, types.RuntimeCRIO, types.RuntimeDocker} {
+		if len(corpus[runtime]) == 0 {
+			t.Errorf("Corpus has no messages for %s", runtime)
+		}
+		var rules *analyzer.RuleSet
+		rules = rules.ForRuntime(runtime)
+
+		for i, entry := range corpus[runtime] {
+			detection := analyzer.ScoreRootCausesWithRules(eventEvidence(entry.Message), &analyzer.EventAnalysis{}, rules)
+			if detection.RootCause != entry.Expected {
+				t.Errorf("%s[%d]: root cause = %s, want %s\n  message: %s\n  candidates: %v",
+					runtime, i, string(detection.RootCause), string(entry.Expected), entry.Message, detection.Candidates)
+			}
+		}
+	}
+}
+
+func TestParseContainerRuntime(t *testing.T) {
+	tests := []struct {
+		version string
+		want    types.ContainerRuntime
+	}{
+		{"containerd://1.7.13", types.RuntimeContainerd},
+		{"cri-o://1.28.1", types.RuntimeCRIO},
+		{"docker://24.0.7", types.RuntimeDocker},
+		{"remote://1.0", types.RuntimeUnknown},
+		{"", types.RuntimeUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := k8s.ParseContainerRuntime(tt.version); got != tt.want {
+			t.Errorf("ParseContainerRuntime(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateRuntimeRemediationSteps_PullCommand(t *testing.T) {
+	img := &types.ImageReference{ContainerName: "app", FullReference: "registry.example.com/team/app:v1", Registry: "registry.example.com"}
+
+	tests := []struct {
+		runtime types.ContainerRuntime
+		want    string
+	}{
+		{types.RuntimeContainerd, "ctr -n k8s.io images pull registry.example.com/team/app:v1"},
+		{types.RuntimeCRIO, "podman pull registry.example.com/team/app:v1"},
+		{types.RuntimeDocker, "docker pull registry.example.com/team/app:v1"},
+	}
+
+	for _, tt := range tests {
+		steps := analyzer.GenerateRuntimeRemediationSteps(types.RootCauseImageNotFound, img, tt.runtime)
+		if !mentions(steps, tt.want) {
+			t.Errorf("%s: steps %v do not mention %q", tt.runtime, steps, tt.want)
+		}
+		if tt.runtime != types.RuntimeDocker && mentions(steps, "docker pull") {
+			t.Errorf("%s: steps %v suggest docker pull", tt.runtime, steps)
+		}
+	}
+}
+
+// mentions reports whether any step contains the given text
+func mentions(steps []string, text string) bool {
+	for _, step := range steps {
+		if strings.Contains(step, text) {
+			return true
+		}
+	}
+	return false
+}
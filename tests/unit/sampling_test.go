@@ -0,0 +1,119 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/analyzer"
+	"github.com/aboigues/k8t/pkg/k8s"
+	"github.com/aboigues/k8t/pkg/output"
+	"github.com/aboigues/k8t/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGroupPodsForSampling(t *testing.T) {
+	pods := []corev1.Pod{
+		ownedPod("web-abc-3", "web-abc", "registry.example.com/web:v2", "ImagePullBackOff"),
+		ownedPod("web-abc-1", "web-abc", "registry.example.com/web:v2", "ImagePullBackOff"),
+		ownedPod("web-abc-2", "web-abc", "registry.example.com/web:v2", "ErrImagePull"),
+		ownedPod("api-xyz-1", "api-xyz", "registry.example.com/api:v1", "ImagePullBackOff"),
+		imagePullPod("default", "standalone-1"),
+		imagePullPod("default", "standalone-2"),
+	}
+
+	groups := analyzer.GroupPodsForSampling(pods)
+
+	// api-xyz, standalone-1, standalone-2, web-abc (ImagePullBackOff), web-abc (ErrImagePull)
+	if len(groups) != 5 {
+		t.Fatalf("Got %d groups, want 5", len(groups))
+	}
+
+	var webGroup *analyzer.PodGroup
+	for i := range groups {
+		if groups[i].Representative.Name == "web-abc-1" {
+			webGroup = &groups[i]
+		}
+	}
+	if webGroup == nil {
+		t.Fatal("Expected web-abc-1 to represent its group")
+	}
+	if len(webGroup.Members) != 1 || webGroup.Members[0].Name != "web-abc-3" {
+		t.Errorf("web-abc-1 group members = %v, want [web-abc-3]", podNames(webGroup.Members))
+	}
+
+	for _, group := range groups {
+		if group.Representative.Name == "standalone-1" && len(group.Members) != 0 {
+			t.Errorf("Pods without a controller must not be grouped, got members %v", podNames(group.Members))
+		}
+	}
+}
+
+func TestAnalyzePods_SamplingProjectsFindings(t *testing.T) {
+	pods := []corev1.Pod{
+		ownedPod("web-abc-2", "web-abc", "registry.example.com/web:v2", "ImagePullBackOff"),
+		ownedPod("web-abc-1", "web-abc", "registry.example.com/web:v2", "ImagePullBackOff"),
+		ownedPod("web-abc-3", "web-abc", "registry.example.com/web:v2", "ImagePullBackOff"),
+	}
+
+	clientset := fake.NewSimpleClientset()
+	logger, err := output.NewAuditLogger(false)
+	if err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+	az := analyzer.NewAnalyzer(&k8s.Client{Clientset: clientset}, logger, 5*time.Second)
+
+	results, err := az.AnalyzePods(context.Background(), pods, analyzer.ScanOptions{Sample: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	report := analyzer.MergeReports(types.TargetTypeNamespace, "default", "default", results)
+	if report.Summary.DirectlyAnalyzedPods != 1 || report.Summary.InferredPods != 2 {
+		t.Errorf("Directly analyzed = %d, inferred = %d, want 1 and 2",
+			report.Summary.DirectlyAnalyzedPods, report.Summary.InferredPods)
+	}
+	if len(report.Findings) != 3 {
+		t.Fatalf("Findings = %d, want 3", len(report.Findings))
+	}
+
+	wantNames := []string{"web-abc-1", "web-abc-2", "web-abc-3"}
+	for i, finding := range report.Findings {
+		if finding.PodName != wantNames[i] {
+			t.Errorf("Findings[%d].PodName = %s, want %s", i, finding.PodName, wantNames[i])
+		}
+		wantInferred := i > 0
+		if finding.Inferred != wantInferred {
+			t.Errorf("Findings[%d].Inferred = %v, want %v", i, finding.Inferred, wantInferred)
+		}
+		if wantInferred && finding.InferredFrom != "default/web-abc-1" {
+			t.Errorf("Findings[%d].InferredFrom = %s, want default/web-abc-1", i, finding.InferredFrom)
+		}
+	}
+}
+
+// ownedPod builds an ImagePullBackOff-style pod controlled by the named ReplicaSet
+func ownedPod(name, replicaSet, image, reason string) corev1.Pod {
+	controller := true
+	pod := imagePullPod("default", name)
+	pod.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "apps/v1",
+		Kind:       "ReplicaSet",
+		Name:       replicaSet,
+		Controller: &controller,
+	}}
+	pod.Spec.Containers[0].Image = image
+	pod.Status.ContainerStatuses[0].Image = image
+	pod.Status.ContainerStatuses[0].State.Waiting.Reason = reason
+	return pod
+}
+
+func podNames(pods []*corev1.Pod) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
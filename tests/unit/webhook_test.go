@@ -0,0 +1,275 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aboigues/k8t/pkg/webhook"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// manifestRegistry serves team/app:v1 and counts manifest requests
+// Requests for team/slow block until release is closed.
+func manifestRegistry(t *testing.T, release chan struct{}) (string, *int32) {
+	t.Helper()
+	var hits int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/v2/team/slow/manifests/v1":
+			<-release
+			w.WriteHeader(http.StatusOK)
+		case "/v2/team/app/manifests/v1":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "https://"), &hits
+}
+
+// webDeployment returns Deployment web running one image
+func webDeployment(image string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: image}},
+		}}},
+	}
+}
+
+// admissionRequest wraps objects in an admission request
+func admissionRequest(t *testing.T, op admissionv1.Operation, obj, old runtime.Object) *admissionv1.AdmissionRequest {
+	t.Helper()
+	req := &admissionv1.AdmissionRequest{UID: "3f2a", Operation: op, Namespace: "default"}
+	for _, o := range []struct {
+		obj runtime.Object
+		raw *runtime.RawExtension
+	}{{obj, &req.Object}, {old, &req.OldObject}} {
+		if o.obj == nil {
+			continue
+		}
+		raw, err := json.Marshal(o.obj)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		o.raw.Raw = raw
+	}
+	return req
+}
+
+// newWebhook builds a handler trusting the self-signed certificate of registry
+func newWebhook(t *testing.T, cfg webhook.Config, registry string, objects ...runtime.Object) *webhook.Handler {
+	t.Helper()
+	az, logger, _ := newFixAnalyzer(t, objects...)
+	az.SetInsecureRegistries([]string{registry})
+	return webhook.NewHandler(az, logger, cfg)
+}
+
+func TestWebhook_AdmissionReviewRoundTrip(t *testing.T) {
+	registry, _ := manifestRegistry(t, nil)
+	server := httptest.NewServer(newWebhook(t, webhook.Config{Mode: webhook.ModeDeny, Timeout: 5 * time.Second, CacheTTL: time.Minute, Probe: true}, registry))
+	defer server.Close()
+
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  admissionRequest(t, admissionv1.Create, webDeployment(registry+"/team/app:v9"), nil),
+	}
+	body, _ := json.Marshal(review)
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var got admissionv1.AdmissionReview
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got.APIVersion != "admission.k8s.io/v1" || got.Kind != "AdmissionReview" || got.Response == nil || got.Response.UID != "3f2a" {
+		t.Fatalf("Review = %+v, want an admission.k8s.io/v1 response for UID 3f2a", got)
+	}
+	if got.Response.Allowed || got.Response.Result == nil || !strings.Contains(got.Response.Result.Message, "IMAGE_NOT_FOUND") {
+		t.Errorf("Response = %+v, want a denial for the missing tag", got.Response)
+	}
+	if got.Response.Result.Code != http.StatusForbidden {
+		t.Errorf("Code = %d, want 403", got.Response.Result.Code)
+	}
+
+	resp, err = http.Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Status = %d, want 400 for a review without request", resp.StatusCode)
+	}
+}
+
+func TestWebhook_WarnMode(t *testing.T) {
+	h := newWebhook(t, webhook.Config{Mode: webhook.ModeWarn, Timeout: 5 * time.Second, CacheTTL: time.Minute}, "")
+
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "debug-"},
+		Spec: corev1.PodSpec{
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "regcred"}},
+			Containers:       []corev1.Container{{Name: "app", Image: "registry.example.com/App:v1"}},
+		},
+	}
+	resp := h.Review(context.Background(), admissionRequest(t, admissionv1.Create, pod, nil))
+	if !resp.Allowed {
+		t.Fatalf("Response = %+v, want the pod admitted in warn mode", resp)
+	}
+	warnings := strings.Join(resp.Warnings, "\n")
+	if !strings.Contains(warnings, "container app: INVALID_IMAGE_NAME") || !strings.Contains(warnings, "imagePullSecret regcred does not exist") {
+		t.Errorf("Warnings = %q, want the invalid reference and the missing secret", resp.Warnings)
+	}
+}
+
+func TestWebhook_CachesFindings(t *testing.T) {
+	registry, hits := manifestRegistry(t, nil)
+	h := newWebhook(t, webhook.Config{Mode: webhook.ModeDeny, Timeout: 5 * time.Second, CacheTTL: time.Minute, Probe: true}, registry)
+
+	for i := 0; i < 3; i++ {
+		resp := h.Review(context.Background(), admissionRequest(t, admissionv1.Create, webDeployment(registry+"/team/app:v1"), nil))
+		if !resp.Allowed || len(resp.Warnings) != 0 {
+			t.Fatalf("Response = %+v, want the existing image admitted", resp)
+		}
+	}
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("Registry requests = %d, want 1 with the cache", n)
+	}
+}
+
+func TestWebhook_PullSecretFindingsNotCached(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	az, logger, clientset := newFixAnalyzer(t)
+	az.SetInsecureRegistries([]string{registry})
+	h := webhook.NewHandler(az, logger, webhook.Config{Mode: webhook.ModeDeny, Timeout: 5 * time.Second, CacheTTL: time.Minute, Probe: true})
+	deployment := webDeployment(registry + "/private/app:v1")
+	deployment.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "regcred"}}
+
+	resp := h.Review(context.Background(), admissionRequest(t, admissionv1.Create, deployment, nil))
+	if resp.Allowed || !strings.Contains(resp.Result.Message, "imagePullSecret regcred does not exist") {
+		t.Fatalf("Response = %+v, want a denial for the missing secret of a private registry", resp)
+	}
+
+	// Creating the secret must admit the next request, not the one after the cache TTL
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "regcred", Namespace: "default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(`{"auths":{"` + registry + `":{"auth":"dXNlcjpwYXNz"}}}`),
+		},
+	}
+	if _, err := clientset.CoreV1().Secrets("default").Create(context.Background(), secret, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Create secret: %v", err)
+	}
+	resp = h.Review(context.Background(), admissionRequest(t, admissionv1.Create, deployment, nil))
+	if !resp.Allowed || len(resp.Warnings) != 0 {
+		t.Errorf("Response = %+v, want admitted once the secret exists", resp)
+	}
+}
+
+func TestWebhook_MissingTagNotCached(t *testing.T) {
+	var pushed int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/team/app/manifests/v2" && atomic.LoadInt32(&pushed) == 1 {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+	h := newWebhook(t, webhook.Config{Mode: webhook.ModeDeny, Timeout: 5 * time.Second, CacheTTL: time.Minute, Probe: true}, registry)
+
+	resp := h.Review(context.Background(), admissionRequest(t, admissionv1.Create, webDeployment(registry+"/team/app:v2"), nil))
+	if resp.Allowed {
+		t.Fatalf("Response = %+v, want a denial before the tag is pushed", resp)
+	}
+
+	// Pushing the tag must admit the next request, not the one after the cache TTL
+	atomic.StoreInt32(&pushed, 1)
+	resp = h.Review(context.Background(), admissionRequest(t, admissionv1.Create, webDeployment(registry+"/team/app:v2"), nil))
+	if !resp.Allowed || len(resp.Warnings) != 0 {
+		t.Errorf("Response = %+v, want admitted once the tag is pushed", resp)
+	}
+}
+
+func TestWebhook_FailsOpenOnTimeout(t *testing.T) {
+	release := make(chan struct{})
+	registry, _ := manifestRegistry(t, release)
+	defer close(release)
+	h := newWebhook(t, webhook.Config{Mode: webhook.ModeDeny, Timeout: 50 * time.Millisecond, CacheTTL: time.Minute, Probe: true}, registry)
+
+	start := time.Now()
+	resp := h.Review(context.Background(), admissionRequest(t, admissionv1.Create, webDeployment(registry+"/team/slow:v1"), nil))
+	if !resp.Allowed || len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "not checked") {
+		t.Errorf("Response = %+v, want admitted with a warning", resp)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Review took %v, want it bounded by the timeout", elapsed)
+	}
+}
+
+func TestWebhook_SkipsUnchangedAndControlledPods(t *testing.T) {
+	registry, hits := manifestRegistry(t, nil)
+	h := newWebhook(t, webhook.Config{Mode: webhook.ModeDeny, Timeout: 5 * time.Second, CacheTTL: time.Minute, Probe: true}, registry)
+
+	// Scaling a Deployment whose tag was deleted must not be blocked
+	scaled := webDeployment(registry + "/team/app:gone")
+	replicas := int32(5)
+	scaled.Spec.Replicas = &replicas
+	resp := h.Review(context.Background(), admissionRequest(t, admissionv1.Update, scaled, webDeployment(registry+"/team/app:gone")))
+	if !resp.Allowed {
+		t.Errorf("Response = %+v, want an update keeping the image admitted", resp)
+	}
+
+	controller := true
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web-7c4d-a", OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-7c4d", UID: "1", Controller: &controller},
+		}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: registry + "/team/app:gone"}}},
+	}
+	resp = h.Review(context.Background(), admissionRequest(t, admissionv1.Create, pod, nil))
+	if !resp.Allowed {
+		t.Errorf("Response = %+v, want a controller's pod admitted", resp)
+	}
+	if n := atomic.LoadInt32(hits); n != 0 {
+		t.Errorf("Registry requests = %d, want none", n)
+	}
+
+	// Changing the image is checked
+	resp = h.Review(context.Background(), admissionRequest(t, admissionv1.Update, webDeployment(registry+"/team/app:v2"), scaled))
+	if resp.Allowed {
+		t.Errorf("Response = %+v, want the new missing tag denied", resp)
+	}
+}